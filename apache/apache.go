@@ -0,0 +1,130 @@
+// Package apache serves the repositories via Apache and mod_dav_svn.
+package apache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/armadillica/svn-manager/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// Apache is the backend that serves the repositories via Apache and mod_dav_svn.
+type Apache struct {
+	*backend.DelayedReloader
+	template *template.Template
+}
+
+var _ backend.Backend = (*Apache)(nil)
+
+func apachectl(subcmd string) (string, error) {
+	deadline := time.Now().Add(10 * time.Second)
+	ctx, cancelFunc := context.WithDeadline(context.Background(), deadline)
+	defer cancelFunc()
+
+	cmd := exec.CommandContext(ctx, "sudo", "--non-interactive", "apache2ctl", subcmd)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// Check that we can run 'sudo apache2ctl configtest' successfully.
+func testApachectl() {
+	log.Info("testing Apache configuration")
+	output, err := apachectl("configtest")
+	if err != nil {
+		out := strings.TrimSpace(output)
+		log.WithField("output", out).WithError(err).Fatal("error running sudo apache2ctl configtest")
+	}
+}
+
+// Create creates a new Apache backend, which renders the Location directives with the given
+// template. Apache is gracefully restarted restartDelay after a config file in configDir
+// changed, once 'apache2ctl configtest' accepts the configuration.
+func Create(restartDelay time.Duration, configDir string, tmpl *template.Template) *Apache {
+	testApachectl()
+	return &Apache{
+		DelayedReloader: backend.CreateDelayedReloader("Apache", restartDelay, configDir,
+			func() (string, error) { return apachectl("configtest") },
+			func() (string, error) { return apachectl("graceful") },
+		),
+		template: tmpl,
+	}
+}
+
+// LoadTemplate loads the template for the Location directives. The template is rendered for
+// an example repository at every access level, with and without read-only users, a group
+// file and authz rules, so that mistakes are found before any config file is written.
+func LoadTemplate(filename string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(filename)).ParseFiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	example := backend.Repo{
+		RepoID:         "example",
+		ProjectID:      "59eefa9cf488554678cae036",
+		Creator:        "Example Creator <example@example.com>",
+		CreatedOn:      time.Now().UTC(),
+		RepoPath:       "/svn/ex/example",
+		Htpasswd:       "/svn/ex/example/htpasswd",
+		Usernames:      []string{"example", "reviewer"},
+		ReadWriteUsers: []string{"example", "reviewer"},
+	}
+	withReadOnlyUser := example
+	withReadOnlyUser.ReadOnlyUsers = []string{"reviewer"}
+	withReadOnlyUser.ReadWriteUsers = []string{"example"}
+	withGroupFile := withReadOnlyUser
+	withGroupFile.Htpasswd = "/svn/users/htpasswd"
+	withGroupFile.GroupFile = "/svn/ex/example/members"
+	withAuthz := withGroupFile
+	withAuthz.AuthzFile = "/svn/ex/example/authz"
+	withAuthz.AuthzRules = []backend.AuthzRule{
+		{Path: "/", Access: map[string]string{"example": "rw", "reviewer": "r"}},
+	}
+
+	for _, repo := range []backend.Repo{example, withReadOnlyUser, withGroupFile, withAuthz} {
+		for _, access := range []string{backend.AccessReadWrite, backend.AccessReadOnly, backend.AccessNone} {
+			repo.Access = access
+			if _, err := RenderConfig(tmpl, repo); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tmpl, nil
+}
+
+// ConfigFilename returns the filename of the repository's Apache location directive.
+func ConfigFilename(repoID string) string {
+	return fmt.Sprintf("svn-%s.conf", repoID)
+}
+
+// RenderConfig returns the Apache location directive for the repository.
+func RenderConfig(tmpl *template.Template, repo backend.Repo) ([]byte, error) {
+	switch repo.Access {
+	case backend.AccessReadWrite, backend.AccessReadOnly, backend.AccessNone:
+	default:
+		return nil, fmt.Errorf("unknown access level %q", repo.Access)
+	}
+
+	var conf bytes.Buffer
+	if err := tmpl.Execute(&conf, repo); err != nil {
+		return nil, err
+	}
+	return conf.Bytes(), nil
+}
+
+// ConfigFilename returns the filename of the repository's Apache location directive.
+func (a *Apache) ConfigFilename(repoID string) string {
+	return ConfigFilename(repoID)
+}
+
+// RenderConfig returns the Apache location directive for the repository.
+func (a *Apache) RenderConfig(repo backend.Repo) ([]byte, error) {
+	return RenderConfig(a.template, repo)
+}
@@ -0,0 +1,136 @@
+package apache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/armadillica/svn-manager/backend"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+// The template shipped with SVN Manager.
+const defaultTemplateFile = "../apache_templates/location.conf.tmpl"
+
+type ApacheTestSuite struct {
+	tmpl *template.Template
+}
+
+var _ = check.Suite(&ApacheTestSuite{})
+
+func (s *ApacheTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.tmpl, err = LoadTemplate(defaultTemplateFile)
+	if err != nil {
+		c.Fatalf("unable to load %s: %s", defaultTemplateFile, err)
+	}
+}
+
+func (s *ApacheTestSuite) TestRenderConfig(c *check.C) {
+	repo := backend.Repo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		RepoPath:  "/svn/12/1234",
+		Htpasswd:  "/svn/12/1234/htpasswd",
+		Access:    backend.AccessReadWrite,
+	}
+	conf, err := RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Equal(c, `# Location directive for project "59eefa9cf488554678cae036"
+<Location /repo/1234>
+    DAV svn
+    SVNPath /svn/12/1234
+    AuthType Basic
+    AuthName "Blender Cloud SVN repository \"1234\""
+    AuthUserFile /svn/12/1234/htpasswd
+    Require valid-user
+</Location>
+`, string(conf))
+
+	repo.AuthzFile = "/svn/12/1234/authz"
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "    AuthUserFile /svn/12/1234/htpasswd\n    AuthzSVNAccessFile /svn/12/1234/authz\n")
+
+	repo.Usernames = []string{"alice", "bob", "reviewer"}
+	repo.ReadOnlyUsers = []string{"reviewer"}
+	repo.ReadWriteUsers = []string{"alice", "bob"}
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), `    <Limit GET PROPFIND OPTIONS REPORT>
+        Require valid-user
+    </Limit>
+    <LimitExcept GET PROPFIND OPTIONS REPORT>
+        Require user alice bob
+    </LimitExcept>
+`)
+
+	repo.ReadOnlyUsers = []string{"alice", "bob", "reviewer"}
+	repo.ReadWriteUsers = nil
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "    <LimitExcept GET PROPFIND OPTIONS REPORT>\n        Require all denied\n")
+
+	repo.Htpasswd = "/svn/users/htpasswd"
+	repo.GroupFile = "/svn/12/1234/members"
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "    AuthUserFile /svn/users/htpasswd\n    AuthGroupFile /svn/12/1234/members\n")
+	assert.Contains(c, string(conf), "    <Limit GET PROPFIND OPTIONS REPORT>\n        Require group 1234\n")
+	assert.NotContains(c, string(conf), "valid-user")
+
+	repo.ReadOnlyUsers = nil
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "    Require group 1234\n</Location>\n")
+
+	repo.Access = backend.AccessReadOnly
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "<LimitExcept GET PROPFIND OPTIONS REPORT>")
+
+	repo.Access = backend.AccessNone
+	conf, err = RenderConfig(s.tmpl, repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "    Require all denied\n")
+	assert.NotContains(c, string(conf), "valid-user")
+
+	repo.Access = "write-only"
+	_, err = RenderConfig(s.tmpl, repo)
+	assert.NotNil(c, err)
+}
+
+func (s *ApacheTestSuite) TestLoadTemplate(c *check.C) {
+	dir, err := ioutil.TempDir("", "apache-template")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(contents string) string {
+		filename := filepath.Join(dir, "location.conf.tmpl")
+		if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+			c.Fatalf("unable to write %s: %s", filename, err)
+		}
+		return filename
+	}
+
+	tmpl, err := LoadTemplate(write("<Location /svn/{{.ProjectID}}/{{.RepoID}}>\n</Location>\n"))
+	assert.Nil(c, err)
+	conf, err := RenderConfig(tmpl, backend.Repo{RepoID: "1234", ProjectID: "abc", Access: backend.AccessNone})
+	assert.Nil(c, err)
+	assert.Equal(c, "<Location /svn/abc/1234>\n</Location>\n", string(conf))
+
+	// Syntax errors and unknown variables are found when loading.
+	_, err = LoadTemplate(write("<Location /repo/{{.RepoID}>\n"))
+	assert.NotNil(c, err)
+	_, err = LoadTemplate(write("<Location /repo/{{.RepositoryID}}>\n"))
+	assert.NotNil(c, err)
+	// Also in branches that only apply to repositories with authz rules.
+	_, err = LoadTemplate(write("<Location /repo/{{.RepoID}}>\n{{if .AuthzFile}}{{.AuthzPath}}{{end}}\n"))
+	assert.NotNil(c, err)
+	_, err = LoadTemplate(filepath.Join(dir, "nonexistent.tmpl"))
+	assert.NotNil(c, err)
+}
@@ -0,0 +1,79 @@
+// Package backend abstracts the server that makes the repositories accessible to users,
+// like Apache or svnserve.
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// Access levels of a repository as a whole.
+const (
+	AccessReadWrite = "read-write"
+	AccessReadOnly  = "read-only"
+	AccessNone      = "none"
+)
+
+// Statuses of a repository's config file.
+const (
+	StatusPending = "pending" // written, waiting for the backend to be reloaded.
+	StatusActive  = "active"  // in use by the backend.
+	StatusFailed  = "failed"  // refused by the backend, or the backend could not be reloaded.
+)
+
+// Repo contains what a backend needs to know to serve a repository.
+type Repo struct {
+	RepoID    string
+	ProjectID string
+	Creator   string
+	CreatedOn time.Time
+	RepoPath  string   // directory of the SVN repository.
+	Htpasswd  string   // file with the bcrypted passwords of the users with access.
+	Usernames []string // users with access, sorted.
+	Access    string   // one of the Access constants.
+
+	// Apache AuthGroupFile with a group named after RepoID, listing the users with access.
+	// When set, Htpasswd is shared by all repositories and contains other users too.
+	GroupFile string
+
+	// Usernames split by whether they can commit, sorted. ReadOnlyUsers is empty when
+	// every user can commit.
+	ReadOnlyUsers  []string
+	ReadWriteUsers []string
+
+	AuthzFile  string      // svn authz file with the path-based rules; empty when there are none.
+	AuthzRules []AuthzRule // the same rules, for backends that cannot use the authz file.
+}
+
+// AuthzRule determines the access of users to a path in the repository, and everything below it.
+// It always applies to users in Repo.Usernames; groups and "*" have been expanded, and
+// users in Repo.ReadOnlyUsers never get "rw".
+// Access levels of the repository as a whole still apply.
+type AuthzRule struct {
+	Path   string            // like "/trunk/release".
+	Access map[string]string // username to "rw", "r", or "" for no access.
+}
+
+// Backend renders the per-repository config files, and reloads the server when they change.
+// The config files are stored by SVNMan, in a subdirectory named after the first two
+// characters of the repository ID.
+type Backend interface {
+	Reloader
+	// ConfigFilename returns the filename of the repository's config file, without directory.
+	ConfigFilename(repoID string) string
+	// RenderConfig returns the contents of the repository's config file.
+	RenderConfig(repo Repo) ([]byte, error)
+}
+
+// Reloader describes the interface for delayed-reloading of the backend.
+type Reloader interface {
+	// QueueReload queues a reload for a change to the repository's config file.
+	// The file is empty when the config was removed.
+	QueueReload(repoID, configFile string)
+	Flush()
+	PerformReload()
+	// ConfigStatus returns the status of the repository's config, and why it failed.
+	ConfigStatus(repoID string) (status, reason string)
+	// WaitForReload waits until the repository's config is no longer pending, or the context is done.
+	WaitForReload(ctx context.Context, repoID string) (status, reason string)
+}
@@ -0,0 +1,461 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Filesystem-friendly timestamp format for config files that were moved aside.
+const rejectedTimestampFormat = "2006-01-02T15-04-05Z07-00"
+
+// errConfigChanged indicates that a config file was written again while it was being tested.
+var errConfigChanged = errors.New("config file was changed while testing")
+
+// CommandFunc runs a command for the backend, returning its output.
+type CommandFunc func() (string, error)
+
+// DelayedReloader implements Reloader for backends that can test their configuration
+// before reloading it.
+type DelayedReloader struct {
+	name        string // of the backend, for logging and error messages.
+	reloadDelay time.Duration
+	configDir   string
+	test        CommandFunc
+	reload      CommandFunc
+
+	// Held while testing and reloading, which can take a while, so that reload attempts
+	// don't overlap. QueueReload and ConfigStatus don't need it.
+	reloadMutex sync.Mutex
+	generation  int // number of reload attempts.
+	// Contents of the config files as they were when the configuration last passed the test,
+	// or when we started; used for rolling back refused config files.
+	accepted map[string][]byte
+
+	mutex  sync.Mutex
+	queued bool
+	timer  *time.Timer
+	// Repository IDs whose config file was removed. Their accepted contents no longer apply
+	// once a new config file is written.
+	removed map[string]bool
+	// Config files whose accepted contents no longer apply, to be forgotten before the next
+	// reload attempt.
+	forget map[string]bool
+
+	statusMutex sync.Mutex
+	// Repository IDs mapped to their config file, for the config files written since the last
+	// reload attempt started. The file is empty for removed config files. Only modified while
+	// holding both mutex and statusMutex.
+	pending   map[string]string
+	reloading map[string]string   // like pending, for the reload attempt in progress.
+	rollbacks map[string]Rollback // repository ID to its most recent rollback.
+	failures  map[string]string   // repository ID to the reason the backend could not be reloaded.
+	reloaded  chan struct{}       // closed and replaced after every reload attempt.
+}
+
+// Rollback describes a config file that was rolled back because the backend refused it.
+type Rollback struct {
+	RepoID       string
+	ConfigFile   string
+	MovedTo      string // where the refused config file was saved.
+	Restored     bool   // whether the previous contents were put back, rather than the file removed.
+	Reason       string // output of the configuration test.
+	RolledBackOn time.Time
+}
+
+// CreateDelayedReloader creates a new DelayedReloader. Before reloading, the test command
+// is run; config files that break the configuration are rolled back to their contents at
+// the last successful test, or removed when they are new. The refused config files are
+// saved into the "rejected" subdirectory of configDir.
+func CreateDelayedReloader(name string, reloadDelay time.Duration, configDir string,
+	test, reload CommandFunc) *DelayedReloader {
+	dr := &DelayedReloader{
+		name:        name,
+		reloadDelay: reloadDelay,
+		configDir:   configDir,
+		test:        test,
+		reload:      reload,
+		accepted:    map[string][]byte{},
+		removed:     map[string]bool{},
+		forget:      map[string]bool{},
+		pending:     map[string]string{},
+		reloading:   map[string]string{},
+		rollbacks:   map[string]Rollback{},
+		failures:    map[string]string{},
+		reloaded:    make(chan struct{}),
+	}
+
+	// The config files that are there now are in use, or at least were not written by us.
+	found, err := filepath.Glob(filepath.Join(configDir, "*", "*"))
+	if err != nil {
+		log.WithField("backend", name).WithError(err).Error("unable to find existing config files")
+	}
+	for _, configFile := range found {
+		if stat, err := os.Stat(configFile); err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+		contents, err := readConfig(configFile)
+		if err != nil {
+			log.WithField("config_file", configFile).WithError(err).Error("unable to read config file")
+			continue
+		}
+		dr.accepted[configFile] = contents
+	}
+	return dr
+}
+
+// QueueReload queues a reload that'll take place in a few seconds.
+// Any call to QueueReload during that time is a no-op. This prevents the backend
+// from being reloaded too often.
+func (dr *DelayedReloader) QueueReload(repoID, configFile string) {
+	dr.mutex.Lock()
+	defer dr.mutex.Unlock()
+
+	// The new config file will be tested, so earlier problems no longer apply.
+	dr.statusMutex.Lock()
+	dr.pending[repoID] = configFile
+	delete(dr.rollbacks, repoID)
+	delete(dr.failures, repoID)
+	dr.statusMutex.Unlock()
+
+	// A removed config file must never come back when its successor is refused.
+	if configFile == "" {
+		dr.removed[repoID] = true
+	} else if dr.removed[repoID] {
+		dr.forget[configFile] = true
+		delete(dr.removed, repoID)
+	}
+
+	logger := log.WithField("backend", dr.name)
+	if dr.queued {
+		logger.Debug("reload already queued")
+		return
+	}
+
+	logger.WithField("delay", dr.reloadDelay).Info("queueing reload")
+	dr.timer = time.AfterFunc(dr.reloadDelay, dr.PerformReload)
+	dr.queued = true
+}
+
+// PerformReload performs an immediate reload. When the configuration is
+// invalid, the config files written since the last reload that cause this are rolled back.
+// Config files written while this is running are left for the next reload.
+func (dr *DelayedReloader) PerformReload() {
+	dr.reloadMutex.Lock()
+	defer dr.reloadMutex.Unlock()
+
+	dr.generation++
+	logger := log.WithFields(log.Fields{
+		"backend":    dr.name,
+		"generation": dr.generation,
+	})
+	logger.Info("performing reload")
+
+	dr.mutex.Lock()
+	if dr.timer != nil {
+		dr.timer.Stop()
+		dr.timer = nil
+	}
+	dr.queued = false
+	for configFile := range dr.forget {
+		delete(dr.accepted, configFile)
+	}
+	dr.forget = map[string]bool{}
+	dr.statusMutex.Lock()
+	pending := dr.pending
+	dr.pending = map[string]string{}
+	dr.reloading = pending
+	dr.statusMutex.Unlock()
+	dr.mutex.Unlock()
+
+	rolledBack, reason := dr.ensureValidConfig(logger, pending)
+	if reason == "" {
+		output, err := dr.reload()
+		if err != nil {
+			logger.WithField("output", output).WithError(err).Error("error reloading")
+			reason = "unable to reload " + dr.name + ": " + strings.TrimSpace(output)
+		} else {
+			logger.Info("reloaded")
+		}
+	}
+
+	dr.statusMutex.Lock()
+	defer dr.statusMutex.Unlock()
+	dr.reloading = map[string]string{}
+	if reason == "" {
+		// The backend loaded every config file that's in place, including the ones that
+		// previously failed for reasons of their own.
+		dr.failures = map[string]string{}
+	}
+	for repoID := range pending {
+		if _, requeued := dr.pending[repoID]; requeued {
+			// Written again in the meantime, so this outcome no longer applies.
+			continue
+		}
+		if rollback, ok := rolledBack[repoID]; ok {
+			dr.rollbacks[repoID] = rollback
+		} else if reason != "" {
+			dr.failures[repoID] = reason
+		}
+	}
+	close(dr.reloaded)
+	dr.reloaded = make(chan struct{})
+}
+
+// ensureValidConfig runs the test command, and rolls back the pending config files that make
+// it fail. Returns the rolled back repositories, and the reason why the backend cannot be
+// reloaded, which is empty when it is safe to reload.
+func (dr *DelayedReloader) ensureValidConfig(logger *log.Entry, pending map[string]string) (map[string]Rollback, string) {
+	output, err := dr.test()
+	if err == nil {
+		dr.accept(pending)
+		return nil, ""
+	}
+	reason := strings.TrimSpace(output)
+	logger.WithField("output", reason).WithError(err).Error("configuration is invalid, rolling back changed config files")
+
+	// Sorted, so that the outcome doesn't depend on map order.
+	repoIDs := make([]string, 0, len(pending))
+	for repoID := range pending {
+		repoIDs = append(repoIDs, repoID)
+	}
+	sort.Strings(repoIDs)
+
+	type change struct {
+		repoID     string
+		configFile string
+		contents   []byte
+	}
+	changes := []change{}
+	for _, repoID := range repoIDs {
+		configFile := pending[repoID]
+		if configFile == "" {
+			continue
+		}
+		contents, err := readConfig(configFile)
+		if err != nil {
+			logger.WithField("config_file", configFile).WithError(err).Error("unable to read config file")
+			continue
+		}
+		if contents == nil {
+			continue
+		}
+		changes = append(changes, change{repoID, configFile, contents})
+	}
+
+	invalid := dr.name + " configuration is invalid: "
+	if len(changes) == 0 {
+		logger.Error("no changed config files to roll back, not reloading")
+		return nil, invalid + reason
+	}
+
+	// First find out whether our config files are the problem at all.
+	rolledBackAll := []change{}
+	for _, ch := range changes {
+		if err := swapConfig(ch.configFile, ch.contents, dr.accepted[ch.configFile]); err != nil {
+			logger.WithField("config_file", ch.configFile).WithError(err).Error("unable to roll back config file")
+			continue
+		}
+		rolledBackAll = append(rolledBackAll, ch)
+	}
+	output, err = dr.test()
+	if err != nil {
+		// Our config files weren't the problem, so put them back and leave it to a human.
+		logger.WithField("output", strings.TrimSpace(output)).WithError(err).
+			Error("configuration still invalid after rollback, not reloading")
+		for _, ch := range rolledBackAll {
+			if err := swapConfig(ch.configFile, dr.accepted[ch.configFile], ch.contents); err != nil {
+				logger.WithField("config_file", ch.configFile).WithError(err).
+					Error("unable to restore config file after rollback")
+			}
+		}
+		return nil, invalid + strings.TrimSpace(output)
+	}
+
+	// Put the changed config files back one at a time, keeping those the backend accepts.
+	now := time.Now().UTC()
+	rolledBack := map[string]Rollback{}
+	lastTestFailed := false
+	for _, ch := range rolledBackAll {
+		fileLogger := logger.WithFields(log.Fields{
+			"repo_id":     ch.repoID,
+			"config_file": ch.configFile,
+		})
+		previous := dr.accepted[ch.configFile]
+		if err := swapConfig(ch.configFile, previous, ch.contents); err != nil {
+			fileLogger.WithError(err).Warning("unable to test config file on its own")
+			continue
+		}
+		output, err := dr.test()
+		lastTestFailed = err != nil
+		if err == nil {
+			dr.accepted[ch.configFile] = ch.contents
+			continue
+		}
+		if err := swapConfig(ch.configFile, ch.contents, previous); err != nil {
+			// This leaves the configuration invalid, but there is nothing better to do.
+			fileLogger.WithError(err).Error("unable to roll back config file")
+			return rolledBack, invalid + strings.TrimSpace(output)
+		}
+
+		rollback := Rollback{
+			RepoID:       ch.repoID,
+			ConfigFile:   ch.configFile,
+			Restored:     previous != nil,
+			Reason:       strings.TrimSpace(output),
+			RolledBackOn: now,
+		}
+		rollback.MovedTo = filepath.Join(dr.configDir, "rejected", filepath.Base(filepath.Dir(ch.configFile)),
+			filepath.Base(ch.configFile)+"-"+now.Format(rejectedTimestampFormat))
+		if err := os.MkdirAll(filepath.Dir(rollback.MovedTo), 0750); err != nil {
+			fileLogger.WithError(err).Error("unable to create directory for rejected config files")
+		} else if err := ioutil.WriteFile(rollback.MovedTo, ch.contents, 0640); err != nil {
+			fileLogger.WithError(err).Error("unable to save rejected config file")
+		}
+		rolledBack[ch.repoID] = rollback
+
+		fileLogger.WithFields(log.Fields{
+			"moved_to": rollback.MovedTo,
+			"restored": rollback.Restored,
+			"output":   rollback.Reason,
+		}).Error("config of repository was rolled back")
+	}
+
+	// Backends may reload what they tested last, so that has to be the final configuration.
+	if lastTestFailed {
+		output, err = dr.test()
+		if err != nil {
+			logger.WithField("output", strings.TrimSpace(output)).WithError(err).
+				Error("configuration invalid after rollback, not reloading")
+			return rolledBack, invalid + strings.TrimSpace(output)
+		}
+	}
+	return rolledBack, ""
+}
+
+// accept remembers the contents of the pending config files, as the backend accepted them.
+func (dr *DelayedReloader) accept(pending map[string]string) {
+	for _, configFile := range pending {
+		if configFile == "" {
+			continue
+		}
+		contents, err := readConfig(configFile)
+		if err != nil {
+			log.WithField("config_file", configFile).WithError(err).Error("unable to read config file")
+			continue
+		}
+		if contents == nil {
+			delete(dr.accepted, configFile)
+		} else {
+			dr.accepted[configFile] = contents
+		}
+	}
+}
+
+// readConfig returns the contents of the config file, or nil when it doesn't exist.
+func readConfig(configFile string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		contents = []byte{}
+	}
+	return contents, nil
+}
+
+// swapConfig replaces the contents of the config file, but only when it still has the
+// expected contents, as SVNMan may have written it again in the meantime. Nil contents
+// mean that the file doesn't exist.
+func swapConfig(configFile string, expected, contents []byte) error {
+	current, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+	if (current == nil) != (expected == nil) || !bytes.Equal(current, expected) {
+		return errConfigChanged
+	}
+
+	if contents == nil {
+		return os.Remove(configFile)
+	}
+	tempfile := configFile + ".new"
+	if err := ioutil.WriteFile(tempfile, contents, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempfile, configFile)
+}
+
+// configStatus returns the status of the repository's config. The caller must hold statusMutex.
+func (dr *DelayedReloader) configStatus(repoID string) (status, reason string) {
+	if _, ok := dr.pending[repoID]; ok {
+		return StatusPending, ""
+	}
+	if _, ok := dr.reloading[repoID]; ok {
+		return StatusPending, ""
+	}
+	if rollback, ok := dr.rollbacks[repoID]; ok {
+		return StatusFailed, rollback.Reason
+	}
+	if reason, ok := dr.failures[repoID]; ok {
+		return StatusFailed, reason
+	}
+	return StatusActive, ""
+}
+
+// ConfigStatus returns the status of the repository's config, and why it failed.
+// Config files that haven't changed since SVN Manager started are assumed to be active.
+func (dr *DelayedReloader) ConfigStatus(repoID string) (status, reason string) {
+	dr.statusMutex.Lock()
+	defer dr.statusMutex.Unlock()
+	return dr.configStatus(repoID)
+}
+
+// WaitForReload waits until the repository's config is no longer pending, or the context is done.
+func (dr *DelayedReloader) WaitForReload(ctx context.Context, repoID string) (status, reason string) {
+	for {
+		dr.statusMutex.Lock()
+		status, reason = dr.configStatus(repoID)
+		reloaded := dr.reloaded
+		dr.statusMutex.Unlock()
+
+		if status != StatusPending {
+			return status, reason
+		}
+		select {
+		case <-ctx.Done():
+			return status, reason
+		case <-reloaded:
+		}
+	}
+}
+
+// Flush performs a scheduled reload immediately and then returns. A reload that is already
+// in progress is waited for.
+func (dr *DelayedReloader) Flush() {
+	dr.mutex.Lock()
+
+	if !dr.queued {
+		dr.mutex.Unlock()
+		log.WithField("backend", dr.name).Debug("reload not queued")
+		// PerformReload only clears queued once it holds reloadMutex.
+		dr.reloadMutex.Lock()
+		dr.reloadMutex.Unlock()
+		return
+	}
+
+	log.WithField("backend", dr.name).Info("flushing reload")
+	dr.mutex.Unlock()
+	dr.PerformReload()
+}
@@ -0,0 +1,243 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type ReloaderTestSuite struct {
+	configDir string
+	calls     []string
+	reloader  *DelayedReloader
+}
+
+var _ = check.Suite(&ReloaderTestSuite{})
+
+func (s *ReloaderTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.configDir, err = ioutil.TempDir("", "backend")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	s.calls = []string{}
+	s.reloader = CreateDelayedReloader("Test", time.Hour, s.configDir, s.fakeTest, s.fakeReload)
+}
+
+func (s *ReloaderTestSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.configDir)
+}
+
+// fakeTest refuses the configuration when any config file contains "BROKEN".
+func (s *ReloaderTestSuite) fakeTest() (string, error) {
+	s.calls = append(s.calls, "test")
+	found, _ := filepath.Glob(filepath.Join(s.configDir, "*", "*.conf"))
+	for _, filename := range found {
+		contents, _ := ioutil.ReadFile(filename)
+		if strings.Contains(string(contents), "BROKEN") {
+			return "Syntax error in " + filename, errors.New("exit status 1")
+		}
+	}
+	return "Syntax OK", nil
+}
+
+func (s *ReloaderTestSuite) fakeReload() (string, error) {
+	s.calls = append(s.calls, "reload")
+	return "", nil
+}
+
+func (s *ReloaderTestSuite) writeConfig(c *check.C, repoID, contents string) string {
+	filename := filepath.Join(s.configDir, repoID[:2], "svn-"+repoID+".conf")
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		c.Fatalf("unable to create directory: %s", err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		c.Fatalf("unable to write %s: %s", filename, err)
+	}
+	return filename
+}
+
+func (s *ReloaderTestSuite) TestReloadValidConfig(c *check.C) {
+	s.reloader.QueueReload("1234", s.writeConfig(c, "1234", "fine"))
+	status, _ := s.reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusPending, status)
+	s.reloader.Flush()
+
+	assert.Equal(c, []string{"test", "reload"}, s.calls)
+	status, reason := s.reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusActive, status)
+	assert.Equal(c, "", reason)
+}
+
+func (s *ReloaderTestSuite) TestRollbackBrokenConfig(c *check.C) {
+	goodFile := s.writeConfig(c, "1234", "fine")
+	brokenFile := s.writeConfig(c, "5678", "BROKEN")
+	s.reloader.QueueReload("1234", goodFile)
+	s.reloader.QueueReload("5678", brokenFile)
+	s.reloader.Flush()
+
+	// After rolling back both, they are tested one at a time, and then the final configuration.
+	assert.Equal(c, []string{"test", "test", "test", "test", "test", "reload"}, s.calls)
+	status, _ := s.reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusActive, status)
+	_, err := os.Stat(goodFile)
+	assert.Nil(c, err, "accepted config file should have been kept")
+
+	status, reason := s.reloader.ConfigStatus("5678")
+	assert.Equal(c, StatusFailed, status)
+	assert.Contains(c, reason, "Syntax error in "+brokenFile)
+	rollback, ok := s.reloader.rollbacks["5678"]
+	if assert.True(c, ok, "repo 5678 should have been rolled back") {
+		assert.False(c, rollback.Restored)
+		_, err = os.Stat(brokenFile)
+		assert.True(c, os.IsNotExist(err), "new config file should have been removed")
+		contents, err := ioutil.ReadFile(rollback.MovedTo)
+		assert.Nil(c, err, "refused config file should have been saved")
+		assert.Equal(c, "BROKEN", string(contents))
+		assert.True(c, strings.HasPrefix(rollback.MovedTo, filepath.Join(s.configDir, "rejected")))
+	}
+
+	// Writing a good config clears the rollback.
+	s.calls = []string{}
+	s.reloader.QueueReload("5678", s.writeConfig(c, "5678", "fine"))
+	s.reloader.Flush()
+	assert.Equal(c, []string{"test", "reload"}, s.calls)
+	status, _ = s.reloader.ConfigStatus("5678")
+	assert.Equal(c, StatusActive, status)
+}
+
+func (s *ReloaderTestSuite) TestRollbackToPreviousConfig(c *check.C) {
+	// Config files that exist at startup are considered to be in use.
+	existingFile := s.writeConfig(c, "abcd", "existing")
+	s.reloader = CreateDelayedReloader("Test", time.Hour, s.configDir, s.fakeTest, s.fakeReload)
+
+	// The previous contents are those of the last successful test, not of the last write.
+	acceptedFile := s.writeConfig(c, "1234", "accepted")
+	s.reloader.QueueReload("1234", acceptedFile)
+	s.reloader.Flush()
+	s.reloader.QueueReload("1234", s.writeConfig(c, "1234", "intermediate"))
+	s.reloader.QueueReload("1234", s.writeConfig(c, "1234", "BROKEN"))
+	s.reloader.QueueReload("abcd", s.writeConfig(c, "abcd", "BROKEN"))
+	s.reloader.Flush()
+
+	for repoID, previous := range map[string]string{"1234": "accepted", "abcd": "existing"} {
+		status, _ := s.reloader.ConfigStatus(repoID)
+		assert.Equal(c, StatusFailed, status)
+		assert.True(c, s.reloader.rollbacks[repoID].Restored)
+		contents, err := ioutil.ReadFile(s.reloader.rollbacks[repoID].ConfigFile)
+		assert.Nil(c, err)
+		assert.Equal(c, previous, string(contents))
+	}
+	assert.Equal(c, existingFile, s.reloader.rollbacks["abcd"].ConfigFile)
+
+	// A removed config file is never restored.
+	assert.Nil(c, os.Remove(acceptedFile))
+	s.reloader.QueueReload("1234", "")
+	s.reloader.Flush()
+	s.reloader.QueueReload("1234", s.writeConfig(c, "1234", "BROKEN"))
+	s.reloader.Flush()
+	assert.False(c, s.reloader.rollbacks["1234"].Restored)
+	_, err := os.Stat(acceptedFile)
+	assert.True(c, os.IsNotExist(err), "config file of removed repository should not have been restored")
+}
+
+func (s *ReloaderTestSuite) TestNoReloadWhenOthersBroken(c *check.C) {
+	// This file was not written via QueueReload, so it's not ours to roll back.
+	s.writeConfig(c, "abcd", "BROKEN")
+	goodFile := s.writeConfig(c, "1234", "fine")
+	s.reloader.QueueReload("1234", goodFile)
+	s.reloader.Flush()
+
+	assert.Equal(c, []string{"test", "test"}, s.calls)
+	status, reason := s.reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusFailed, status)
+	assert.Contains(c, reason, "Test configuration is invalid")
+	_, err := os.Stat(goodFile)
+	assert.Nil(c, err, "config file should have been put back")
+
+	// Once the other problem has been fixed, any reload activates the config.
+	s.writeConfig(c, "abcd", "fine")
+	s.reloader.QueueReload("abcd", "")
+	s.reloader.Flush()
+	status, _ = s.reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusActive, status)
+}
+
+func (s *ReloaderTestSuite) TestWaitForReload(c *check.C) {
+	s.reloader.QueueReload("1234", s.writeConfig(c, "1234", "fine"))
+
+	// Without a reload, waiting should time out.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	status, _ := s.reloader.WaitForReload(ctx, "1234")
+	assert.Equal(c, StatusPending, status)
+
+	done := make(chan string)
+	go func() {
+		status, _ := s.reloader.WaitForReload(context.Background(), "1234")
+		done <- status
+	}()
+	s.reloader.Flush()
+
+	select {
+	case status := <-done:
+		assert.Equal(c, StatusActive, status)
+	case <-time.After(5 * time.Second):
+		c.Fatal("WaitForReload did not return after reload")
+	}
+}
+
+func (s *ReloaderTestSuite) TestQueueReloadDuringReload(c *check.C) {
+	testing := make(chan struct{})
+	release := make(chan struct{})
+	slowTest := func() (string, error) {
+		select {
+		case testing <- struct{}{}:
+			<-release
+		default:
+		}
+		return "Syntax OK", nil
+	}
+	reloader := CreateDelayedReloader("Test", time.Hour, s.configDir, slowTest, s.fakeReload)
+
+	reloader.QueueReload("1234", s.writeConfig(c, "1234", "fine"))
+	done := make(chan struct{})
+	go func() {
+		reloader.Flush()
+		close(done)
+	}()
+	<-testing
+
+	// Queueing and asking for the status must not wait for the configuration test.
+	queued := make(chan struct{})
+	go func() {
+		reloader.QueueReload("5678", s.writeConfig(c, "5678", "fine"))
+		close(queued)
+	}()
+	select {
+	case <-queued:
+	case <-time.After(5 * time.Second):
+		c.Fatal("QueueReload blocked while the configuration was being tested")
+	}
+	status, _ := reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusPending, status)
+
+	close(release)
+	<-done
+	status, _ = reloader.ConfigStatus("1234")
+	assert.Equal(c, StatusActive, status)
+	// Written after the reload started, so it waits for the next one.
+	status, _ = reloader.ConfigStatus("5678")
+	assert.Equal(c, StatusPending, status)
+
+	reloader.Flush()
+	status, _ = reloader.ConfigStatus("5678")
+	assert.Equal(c, StatusActive, status)
+}
@@ -0,0 +1,245 @@
+// Package backups periodically updates the hotcopy backup of every repository.
+package backups
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+// Job types of the backup jobs.
+const (
+	JobType        = "backup"
+	RestoreJobType = "restore-backup"
+)
+
+const (
+	// How often we check which repositories are due for a backup. The first check is done
+	// right after Go() is called.
+	checkInterval = time.Minute
+	// How often the repositories are listed, to find new and deleted ones.
+	listInterval = time.Hour
+	// Maximum number of unfinished backup jobs, so that backups leave room in the job
+	// queue and workers for other jobs.
+	maxPending = 2
+	// How long to wait before retrying a failed backup. This doubles with every consecutive
+	// failure, up to the backup interval.
+	retryDelay = 10 * time.Minute
+)
+
+// JobFunc returns a job function that backs up the repository, and stores the
+// svnman.BackupState as the job result. The job ID is added to logFields.
+func JobFunc(svn svnman.Manager, repoID string, logFields log.Fields) jobs.Func {
+	return func(ctx context.Context, run *jobs.Run) error {
+		logFields["job_id"] = run.ID
+		state, err := svn.BackupRepo(ctx, repoID, logFields)
+		if err != nil {
+			return err
+		}
+		return run.SetResult(state)
+	}
+}
+
+// RestoreJobFunc returns a job function that restores the repository from its backup.
+// The job ID is added to logFields.
+func RestoreJobFunc(svn svnman.Manager, repoID string, logFields log.Fields) jobs.Func {
+	return func(ctx context.Context, run *jobs.Run) error {
+		logFields["job_id"] = run.ID
+		return svn.RestoreBackup(ctx, repoID, logFields)
+	}
+}
+
+// Scheduler submits a backup job for every repository whose backup is older than the interval.
+type Scheduler struct {
+	svn      svnman.Manager
+	jobs     *jobs.Manager
+	interval time.Duration
+
+	repoIDs  []string             // as of the last time the repositories were listed.
+	listedOn time.Time            // zero until the repositories were listed successfully.
+	due      map[string]time.Time // repository ID to when its next backup is due, when known.
+	failures map[string]int       // repository ID to its number of consecutive failed backups.
+	pending  map[string]string    // repository ID to ID of its unfinished backup job.
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Create returns a Scheduler that backs up every repository once per interval.
+// Call Go() to actually start it.
+func Create(svn svnman.Manager, jobManager *jobs.Manager, interval time.Duration) *Scheduler {
+	log.WithField("interval", interval).Info("creating backup scheduler")
+
+	return &Scheduler{
+		svn:      svn,
+		jobs:     jobManager,
+		interval: interval,
+		due:      map[string]time.Time{},
+		failures: map[string]int{},
+		pending:  map[string]string{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Go starts the scheduler in a background goroutine.
+func (s *Scheduler) Go() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			s.run(time.Now())
+
+			select {
+			case <-s.done:
+				log.Info("backup scheduler stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the scheduler. Backup jobs that were already submitted are
+// handled by the job manager.
+func (s *Scheduler) Close() {
+	log.Info("shutting down backup scheduler")
+	close(s.done)
+	s.wg.Wait()
+}
+
+// checkPending returns true when a backup job for the repository is still unfinished.
+// Otherwise it schedules the next backup of the repository, based on how the job ended.
+func (s *Scheduler) checkPending(repoID string, now time.Time) bool {
+	jobID, ok := s.pending[repoID]
+	if !ok {
+		return false
+	}
+	job, err := s.jobs.Get(jobID)
+	switch {
+	case err != nil:
+		// Forgotten by the job manager, so look at the backup itself again.
+		delete(s.due, repoID)
+	case !job.Status.Finished():
+		return true
+	case job.Status == jobs.StatusCompleted:
+		s.due[repoID] = now.Add(s.interval)
+		delete(s.failures, repoID)
+	default:
+		s.failures[repoID]++
+		delay := retryDelay
+		for idx := 1; idx < s.failures[repoID] && delay < s.interval; idx++ {
+			delay *= 2
+		}
+		if delay > s.interval {
+			delay = s.interval
+		}
+		s.due[repoID] = now.Add(delay)
+		log.WithFields(log.Fields{
+			"repo_id":     repoID,
+			"failures":    s.failures[repoID],
+			"retry_after": delay,
+		}).Warning("backup scheduler backing off after failed backup")
+	}
+	delete(s.pending, repoID)
+	return false
+}
+
+// listRepos refreshes the list of repositories, when it's older than listInterval.
+func (s *Scheduler) listRepos(now time.Time) {
+	if !s.listedOn.IsZero() && now.Sub(s.listedOn) < listInterval {
+		return
+	}
+	list, err := s.svn.ListRepos(svnman.ListRepos{})
+	if err != nil {
+		log.WithError(err).Error("backup scheduler unable to list repositories")
+		return
+	}
+
+	exists := map[string]bool{}
+	s.repoIDs = make([]string, len(list.Repos))
+	for idx, repo := range list.Repos {
+		s.repoIDs[idx] = repo.RepoID
+		exists[repo.RepoID] = true
+	}
+	for repoID := range s.due {
+		if !exists[repoID] {
+			delete(s.due, repoID)
+			delete(s.failures, repoID)
+		}
+	}
+	s.listedOn = now
+}
+
+// dueTime returns when the next backup of the repository is due, looking at its backup
+// when that isn't known yet.
+func (s *Scheduler) dueTime(repoID string, now time.Time) time.Time {
+	if due, ok := s.due[repoID]; ok {
+		return due
+	}
+
+	state, err := s.svn.GetBackupState(repoID)
+	switch {
+	case err == svnman.ErrNoBackup:
+		s.due[repoID] = now
+	case err != nil:
+		log.WithField("repo_id", repoID).WithError(err).Error("backup scheduler unable to get backup state")
+		s.due[repoID] = now.Add(retryDelay)
+	default:
+		s.due[repoID] = state.BackedUpOn.Add(s.interval)
+	}
+	return s.due[repoID]
+}
+
+// run submits backup jobs for the repositories whose backup is due. It returns the number
+// of submitted jobs.
+func (s *Scheduler) run(now time.Time) int {
+	s.listRepos(now)
+
+	submitted := 0
+	pendingCount := 0
+	for repoID := range s.pending {
+		if s.checkPending(repoID, now) {
+			pendingCount++
+		}
+	}
+	for _, repoID := range s.repoIDs {
+		if pendingCount >= maxPending {
+			// The others will be backed up at one of the next checks.
+			break
+		}
+		if _, ok := s.pending[repoID]; ok {
+			continue
+		}
+		if s.dueTime(repoID, now).After(now) {
+			continue
+		}
+
+		logFields := log.Fields{
+			"repo_id": repoID,
+			"in":      "backup scheduler",
+		}
+		job, err := s.jobs.Submit(JobType, repoID, "", JobFunc(s.svn, repoID, logFields))
+		if err != nil {
+			// Most likely the queue is full; we'll try again at the next check.
+			log.WithField("repo_id", repoID).WithError(err).Warning("backup scheduler unable to submit job")
+			break
+		}
+		s.pending[repoID] = job.ID
+		submitted++
+		pendingCount++
+	}
+
+	log.WithFields(log.Fields{
+		"repositories": len(s.repoIDs),
+		"submitted":    submitted,
+	}).Debug("backup scheduler finished check")
+	return submitted
+}
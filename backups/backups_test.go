@@ -0,0 +1,175 @@
+package backups
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type BackupsTestSuite struct {
+	now       time.Time
+	jobsDir   string
+	jobs      *jobs.Manager
+	mockCtrl  *gomock.Controller
+	mockSVN   *svnman.MockManager
+	scheduler *Scheduler
+}
+
+var _ = check.Suite(&BackupsTestSuite{})
+
+func (s *BackupsTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.now = time.Date(2017, 12, 1, 12, 0, 0, 0, time.UTC)
+	s.jobsDir, err = ioutil.TempDir("", "backups-jobs")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	s.jobs, err = jobs.Create(s.jobsDir, 1)
+	if err != nil {
+		c.Fatalf("unable to create job manager: %s", err)
+	}
+	s.mockCtrl = gomock.NewController(c)
+	s.mockSVN = svnman.NewMockManager(s.mockCtrl)
+	s.scheduler = Create(s.mockSVN, s.jobs, 24*time.Hour)
+}
+
+func (s *BackupsTestSuite) TearDownTest(c *check.C) {
+	s.jobs.Close()
+	s.mockCtrl.Finish()
+	os.RemoveAll(s.jobsDir)
+}
+
+// waitForJob waits until the job has finished, and returns its final state.
+func (s *BackupsTestSuite) waitForJob(c *check.C, jobID string) jobs.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := s.jobs.Get(jobID)
+		if err != nil {
+			c.Fatalf("unable to get job %s: %s", jobID, err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("job %s did not finish in time", jobID)
+	return jobs.Job{}
+}
+
+func (s *BackupsTestSuite) expectList(repoIDs ...string) {
+	list := svnman.RepoList{}
+	for _, repoID := range repoIDs {
+		list.Repos = append(list.Repos, svnman.RepoSummary{RepoID: repoID})
+	}
+	s.mockSVN.EXPECT().ListRepos(svnman.ListRepos{}).Times(1).Return(list, nil)
+}
+
+func (s *BackupsTestSuite) TestRunBacksUpDueRepos(c *check.C) {
+	s.expectList("never", "old", "recent")
+	s.mockSVN.EXPECT().GetBackupState("never").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	s.mockSVN.EXPECT().GetBackupState("old").Times(1).
+		Return(svnman.BackupState{BackedUpOn: s.now.Add(-25 * time.Hour)}, nil)
+	s.mockSVN.EXPECT().GetBackupState("recent").Times(1).
+		Return(svnman.BackupState{BackedUpOn: s.now.Add(-1 * time.Hour)}, nil)
+	s.mockSVN.EXPECT().BackupRepo(gomock.Any(), "never", gomock.Any()).Times(1).
+		Return(svnman.BackupState{HeadRevision: 3}, nil)
+	s.mockSVN.EXPECT().BackupRepo(gomock.Any(), "old", gomock.Any()).Times(1).
+		Return(svnman.BackupState{}, svnman.ErrBackup)
+
+	assert.Equal(c, 2, s.scheduler.run(s.now))
+
+	job := s.waitForJob(c, s.scheduler.pending["never"])
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+	assert.Equal(c, JobType, job.Type)
+	assert.JSONEq(c, `{"backed_up_on": "0001-01-01T00:00:00Z", "head_revision": 3, "disk_size": 0}`,
+		string(job.Result))
+
+	job = s.waitForJob(c, s.scheduler.pending["old"])
+	assert.Equal(c, jobs.StatusFailed, job.Status)
+}
+
+func (s *BackupsTestSuite) TestRunSkipsPendingRepos(c *check.C) {
+	block := make(chan struct{})
+	s.expectList("repo")
+	s.mockSVN.EXPECT().GetBackupState("repo").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	s.mockSVN.EXPECT().BackupRepo(gomock.Any(), "repo", gomock.Any()).Times(1).DoAndReturn(
+		func(ctx context.Context, repoID string, logFields log.Fields) (svnman.BackupState, error) {
+			<-block
+			return svnman.BackupState{}, nil
+		})
+	assert.Equal(c, 1, s.scheduler.run(s.now))
+
+	// The backup job is still running, so nothing should be submitted.
+	assert.Equal(c, 0, s.scheduler.run(s.now.Add(time.Minute)))
+
+	close(block)
+	s.waitForJob(c, s.scheduler.pending["repo"])
+}
+
+func (s *BackupsTestSuite) TestRunLimitsPendingJobs(c *check.C) {
+	block := make(chan struct{})
+	s.expectList("repo1", "repo2", "repo3")
+	s.mockSVN.EXPECT().GetBackupState(gomock.Any()).Times(2).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	s.mockSVN.EXPECT().BackupRepo(gomock.Any(), gomock.Any(), gomock.Any()).Times(3).DoAndReturn(
+		func(ctx context.Context, repoID string, logFields log.Fields) (svnman.BackupState, error) {
+			<-block
+			return svnman.BackupState{}, nil
+		})
+	assert.Equal(c, 2, s.scheduler.run(s.now))
+
+	// Nothing should be submitted while the first two backups are unfinished.
+	assert.Equal(c, 0, s.scheduler.run(s.now))
+
+	close(block)
+	s.waitForJob(c, s.scheduler.pending["repo1"])
+	s.waitForJob(c, s.scheduler.pending["repo2"])
+
+	// The finished backups are not due again, so their state isn't needed.
+	s.mockSVN.EXPECT().GetBackupState("repo3").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	assert.Equal(c, 1, s.scheduler.run(s.now))
+	s.waitForJob(c, s.scheduler.pending["repo3"])
+}
+
+func (s *BackupsTestSuite) TestRunBacksOffFailedRepos(c *check.C) {
+	s.expectList("repo")
+	s.mockSVN.EXPECT().GetBackupState("repo").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	s.mockSVN.EXPECT().BackupRepo(gomock.Any(), "repo", gomock.Any()).Times(3).
+		Return(svnman.BackupState{}, svnman.ErrBackup)
+
+	now := s.now
+	assert.Equal(c, 1, s.scheduler.run(now))
+	s.waitForJob(c, s.scheduler.pending["repo"])
+
+	// The first retry is after retryDelay, the next one after twice that.
+	for _, delay := range []time.Duration{retryDelay, 2 * retryDelay} {
+		assert.Equal(c, 0, s.scheduler.run(now.Add(time.Minute)))
+		now = now.Add(time.Minute + delay)
+		assert.Equal(c, 1, s.scheduler.run(now))
+		s.waitForJob(c, s.scheduler.pending["repo"])
+	}
+	assert.Equal(c, 0, s.scheduler.run(now.Add(3*retryDelay)))
+	assert.Equal(c, 3, s.scheduler.failures["repo"])
+}
+
+func (s *BackupsTestSuite) TestRunListsReposPeriodically(c *check.C) {
+	s.expectList("repo")
+	s.mockSVN.EXPECT().GetBackupState("repo").Times(1).Return(svnman.BackupState{BackedUpOn: s.now}, nil)
+	assert.Equal(c, 0, s.scheduler.run(s.now))
+	assert.Equal(c, 0, s.scheduler.run(s.now.Add(time.Minute)))
+
+	// New repositories are found, and deleted ones forgotten.
+	s.expectList("new")
+	s.mockSVN.EXPECT().GetBackupState("new").Times(1).Return(svnman.BackupState{BackedUpOn: s.now}, nil)
+	assert.Equal(c, 0, s.scheduler.run(s.now.Add(listInterval)))
+	assert.Equal(c, []string{"new"}, s.scheduler.repoIDs)
+	_, known := s.scheduler.due["repo"]
+	assert.False(c, known, "deleted repository should be forgotten")
+}
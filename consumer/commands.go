@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/armadillica/svn-manager/jsonschemas"
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrValidationFailed indicates that a message did not pass JSON validation. Specifics are logged.
+	ErrValidationFailed = errors.New("JSON validation failed")
+	// ErrRepoIDMismatch indicates that the repository ID in the command differs from the one in its payload.
+	ErrRepoIDMismatch = errors.New("repository ID of command and payload differ")
+)
+
+// Command is the message format for commands sent to SVN Manager via AMQP.
+// The payload is the same document as sent to the corresponding HTTP endpoint.
+type Command struct {
+	Command string          `json:"command"` // "create_repo", "delete_repo" or "modify_access"
+	RepoID  string          `json:"repo_id"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// process decodes and validates the message, and dispatches it to SVNMan.
+func (c *Consumer) process(body []byte, logFields log.Fields) error {
+	cmd := Command{}
+	if err := decodeJSON(body, &cmd, "amqp_command", logFields); err != nil {
+		return err
+	}
+	logFields["command"] = cmd.Command
+	logFields["repo_id"] = cmd.RepoID
+	logger := log.WithFields(logFields)
+
+	switch cmd.Command {
+	case "create_repo":
+		repoInfo := svnman.CreateRepo{}
+		if err := decodeJSON(cmd.Payload, &repoInfo, "create_repo", logFields); err != nil {
+			return err
+		}
+		if !strings.EqualFold(repoInfo.RepoID, cmd.RepoID) {
+			logger.WithField("payload_repo_id", repoInfo.RepoID).Warning("repository ID mismatch")
+			return ErrRepoIDMismatch
+		}
+		repoInfo.Normalise()
+		logger.Info("repository creation requested")
+		return c.svn.CreateRepo(repoInfo, logFields)
+
+	case "delete_repo":
+		logger.Info("repository deletion requested")
+		return c.svn.DeleteRepo(cmd.RepoID, logFields)
+
+	case "modify_access":
+		mods := svnman.ModifyAccess{}
+		if err := decodeJSON(cmd.Payload, &mods, "modify_access", logFields); err != nil {
+			return err
+		}
+		logger.Info("going to modify access on repository")
+		return c.svn.ModifyAccess(cmd.RepoID, mods, logFields)
+	}
+
+	// The JSON schema should have prevented this.
+	logger.Error("unknown command")
+	return ErrValidationFailed
+}
+
+// decodeJSON decodes JSON from a message, and validates it against the given schema.
+func decodeJSON(body []byte, document interface{}, schemaName string, logFields log.Fields) error {
+	logger := log.WithFields(logFields).WithField("schema", schemaName)
+
+	if err := json.Unmarshal(body, document); err != nil {
+		logger.WithError(err).Warning("unable to decode JSON")
+		return ErrValidationFailed
+	}
+
+	result, err := jsonschemas.Validate(schemaName, document)
+	if err != nil {
+		logger.WithError(err).Warning("received JSON that was unvalidatable")
+		return ErrValidationFailed
+	}
+	if !result.Valid() {
+		verrors := result.Errors()
+		errors := make([]string, len(verrors))
+		for idx, verr := range verrors {
+			errors[idx] = verr.String()
+		}
+		logger.WithField(log.ErrorKey, strings.Join(errors, "|")).Warning("received invalid JSON")
+		return ErrValidationFailed
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package consumer receives repository management commands from RabbitMQ.
+package consumer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+)
+
+const consumerTag = "svn-manager"
+
+const (
+	// How often a command is tried before it is sent to the dead-letter queue.
+	maxAttempts = 5
+	// How long to wait before trying a command again; this doubles with every attempt.
+	retryDelay = time.Second
+)
+
+// Errors caused by the command itself, which trying again won't solve.
+var permanentErrors = map[error]bool{
+	ErrValidationFailed:        true,
+	ErrRepoIDMismatch:          true,
+	svnman.ErrInvalidRepoID:    true,
+	svnman.ErrAlreadyExists:    true,
+	svnman.ErrNotFound:         true,
+	svnman.ErrNoPassword:       true,
+	svnman.ErrPasswordConflict: true,
+}
+
+// Consumer consumes command messages from a queue and dispatches them to SVNMan.
+// Commands that fail for other reasons than the command itself are tried again a few
+// times. Messages that cannot be handled are rejected, which sends them to the dead-letter
+// queue.
+type Consumer struct {
+	channel    *amqp.Channel
+	svn        svnman.Manager
+	queueName  string
+	deliveries <-chan amqp.Delivery
+	retryDelay time.Duration
+	closing    chan struct{} // closed when Close() is called.
+	wg         sync.WaitGroup
+}
+
+// Create declares the command and dead-letter queues, and returns a Consumer for them.
+// Call Go() to actually start consuming.
+func Create(conn *amqp.Connection, svn svnman.Manager, queueName string) (*Consumer, error) {
+	logger := log.WithField("queue", queueName)
+
+	channel, err := conn.Channel()
+	if err != nil {
+		logger.WithError(err).Error("unable to open AMQP channel")
+		return nil, err
+	}
+
+	if err := declareQueues(channel, queueName); err != nil {
+		logger.WithError(err).Error("unable to declare AMQP queues")
+		channel.Close()
+		return nil, err
+	}
+
+	// Handle one command at a time; repository operations are not cheap.
+	if err := channel.Qos(1, 0, false); err != nil {
+		logger.WithError(err).Error("unable to set AMQP QoS")
+		channel.Close()
+		return nil, err
+	}
+
+	deliveries, err := channel.Consume(queueName, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		logger.WithError(err).Error("unable to consume from AMQP queue")
+		channel.Close()
+		return nil, err
+	}
+
+	logger.Info("consuming commands from AMQP")
+	return &Consumer{
+		channel:    channel,
+		svn:        svn,
+		queueName:  queueName,
+		deliveries: deliveries,
+		retryDelay: retryDelay,
+		closing:    make(chan struct{}),
+	}, nil
+}
+
+// declareQueues declares the command queue, and the dead-letter exchange & queue it uses.
+func declareQueues(channel *amqp.Channel, queueName string) error {
+	deadExchange := queueName + ".dead-letter"
+	deadQueue := queueName + ".dead"
+
+	if err := channel.ExchangeDeclare(deadExchange, "fanout", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := channel.QueueDeclare(deadQueue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := channel.QueueBind(deadQueue, "", deadExchange, false, nil); err != nil {
+		return err
+	}
+
+	args := amqp.Table{"x-dead-letter-exchange": deadExchange}
+	_, err := channel.QueueDeclare(queueName, true, false, false, false, args)
+	return err
+}
+
+// Go starts handling deliveries in a background goroutine.
+func (c *Consumer) Go() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for delivery := range c.deliveries {
+			c.handle(delivery)
+		}
+		log.WithField("queue", c.queueName).Info("stopped consuming commands from AMQP")
+	}()
+}
+
+// Close stops consuming, waits for the current message to be handled, and closes the channel.
+func (c *Consumer) Close() {
+	logger := log.WithField("queue", c.queueName)
+	logger.Info("shutting down AMQP consumer")
+
+	if err := c.channel.Cancel(consumerTag, false); err != nil {
+		logger.WithError(err).Warning("unable to cancel AMQP consumer")
+	}
+	close(c.closing)
+	c.wg.Wait()
+
+	if err := c.channel.Close(); err != nil {
+		logger.WithError(err).Warning("unable to close AMQP channel")
+	}
+}
+
+// handle processes a single delivery, and acknowledges or rejects it.
+func (c *Consumer) handle(delivery amqp.Delivery) {
+	logFields := log.Fields{
+		"queue":        c.queueName,
+		"delivery_tag": delivery.DeliveryTag,
+		"message_id":   delivery.MessageId,
+	}
+	logger := log.WithFields(logFields)
+
+	err := c.process(delivery.Body, logFields)
+	for attempt := 1; err != nil && !permanentErrors[err] && attempt < maxAttempts; attempt++ {
+		delay := c.retryDelay << uint(attempt-1)
+		logger.WithError(err).WithField("retry_after", delay).Warning("unable to handle command, trying again")
+		select {
+		case <-c.closing:
+			// Leave it to whoever consumes from the queue next.
+			logger.Info("shutting down, requeueing command")
+			if err := delivery.Nack(false, true); err != nil {
+				logger.WithError(err).Error("unable to requeue AMQP message")
+			}
+			return
+		case <-time.After(delay):
+		}
+		err = c.process(delivery.Body, logFields)
+	}
+	if err != nil {
+		logger.WithError(err).Warning("unable to handle command, sending to dead-letter queue")
+		if err := delivery.Nack(false, false); err != nil {
+			logger.WithError(err).Error("unable to reject AMQP message")
+		}
+		return
+	}
+
+	if err := delivery.Ack(false); err != nil {
+		logger.WithError(err).Error("unable to acknowledge AMQP message")
+	}
+}
@@ -0,0 +1,173 @@
+package consumer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type mockAcknowledger struct {
+	acked    []uint64
+	nacked   []uint64
+	requeued []uint64
+	rejected []uint64
+}
+
+func (ma *mockAcknowledger) Ack(tag uint64, multiple bool) error {
+	ma.acked = append(ma.acked, tag)
+	return nil
+}
+func (ma *mockAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	if requeue {
+		ma.requeued = append(ma.requeued, tag)
+	} else {
+		ma.nacked = append(ma.nacked, tag)
+	}
+	return nil
+}
+func (ma *mockAcknowledger) Reject(tag uint64, requeue bool) error {
+	ma.rejected = append(ma.rejected, tag)
+	return nil
+}
+
+type ConsumerTestSuite struct {
+	ack      *mockAcknowledger
+	consumer *Consumer
+}
+
+var _ = check.Suite(&ConsumerTestSuite{})
+
+func (s *ConsumerTestSuite) SetUpTest(c *check.C) {
+	s.ack = &mockAcknowledger{}
+	s.consumer = &Consumer{queueName: "unittest", closing: make(chan struct{})}
+}
+
+func (s *ConsumerTestSuite) mockSVN(c *check.C) (*gomock.Controller, *svnman.MockManager) {
+	mockCtrl := gomock.NewController(c)
+	mockSVN := svnman.NewMockManager(mockCtrl)
+	s.consumer.svn = mockSVN
+	return mockCtrl, mockSVN
+}
+
+func (s *ConsumerTestSuite) deliver(tag uint64, body string) {
+	s.consumer.handle(amqp.Delivery{
+		Acknowledger: s.ack,
+		DeliveryTag:  tag,
+		Body:         []byte(body),
+	})
+}
+
+func (s *ConsumerTestSuite) TestCreateRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().CreateRepo(svnman.CreateRepo{
+		RepoID:    "uppercase",
+		ProjectID: "8afae1eb1d171833df73416b",
+		Creator:   "creator <email@example.com>",
+	}, gomock.Any()).Times(1)
+
+	s.deliver(1, `{"command": "create_repo", "repo_id": "UPPERCASE", "payload": {
+		"repo_id": "UPPERCASE",
+		"project_id": "8afae1eb1d171833df73416b",
+		"creator": "creator <email@example.com>"
+	}}`)
+	assert.Equal(c, []uint64{1}, s.ack.acked)
+	assert.Empty(c, s.ack.nacked)
+}
+
+func (s *ConsumerTestSuite) TestCreateRepoMismatch(c *check.C) {
+	mockCtrl, _ := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	s.deliver(1, `{"command": "create_repo", "repo_id": "some-repo", "payload": {
+		"repo_id": "other-repo",
+		"project_id": "8afae1eb1d171833df73416b",
+		"creator": "creator <email@example.com>"
+	}}`)
+	assert.Empty(c, s.ack.acked)
+	assert.Equal(c, []uint64{1}, s.ack.nacked)
+}
+
+func (s *ConsumerTestSuite) TestDeleteRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().DeleteRepo("1234", gomock.Any()).Times(1).Return(nil)
+	mockSVN.EXPECT().DeleteRepo("12345", gomock.Any()).Times(maxAttempts).Return(errors.New("something unexpected"))
+
+	s.deliver(1, `{"command": "delete_repo", "repo_id": "1234"}`)
+	s.deliver(2, `{"command": "delete_repo", "repo_id": "12345"}`)
+	assert.Equal(c, []uint64{1}, s.ack.acked)
+	assert.Equal(c, []uint64{2}, s.ack.nacked)
+}
+
+func (s *ConsumerTestSuite) TestRetryTransientErrors(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	gomock.InOrder(
+		mockSVN.EXPECT().DeleteRepo("1234", gomock.Any()).Times(2).Return(errors.New("disk busy")),
+		mockSVN.EXPECT().DeleteRepo("1234", gomock.Any()).Times(1).Return(nil),
+	)
+	s.deliver(1, `{"command": "delete_repo", "repo_id": "1234"}`)
+	assert.Equal(c, []uint64{1}, s.ack.acked)
+	assert.Empty(c, s.ack.nacked)
+
+	// Errors caused by the command itself are not tried again.
+	mockSVN.EXPECT().ModifyAccess("1234", gomock.Any(), gomock.Any()).Times(1).Return(svnman.ErrNoPassword)
+	s.deliver(2, `{"command": "modify_access", "repo_id": "1234", "payload": {"grant": [{"username": "someone"}]}}`)
+	assert.Equal(c, []uint64{2}, s.ack.nacked)
+}
+
+func (s *ConsumerTestSuite) TestRequeueWhenClosing(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	s.consumer.retryDelay = time.Hour
+	close(s.consumer.closing)
+	mockSVN.EXPECT().DeleteRepo("1234", gomock.Any()).Times(1).Return(errors.New("disk busy"))
+	s.deliver(1, `{"command": "delete_repo", "repo_id": "1234"}`)
+	assert.Empty(c, s.ack.acked)
+	assert.Empty(c, s.ack.nacked)
+	assert.Equal(c, []uint64{1}, s.ack.requeued)
+}
+
+func (s *ConsumerTestSuite) TestModifyAccess(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().ModifyAccess("1234", svnman.ModifyAccess{
+		Grant: []svnman.ModifyAccessGrantEntry{
+			svnman.ModifyAccessGrantEntry{Username: "someone", Password: "$2y$10$abcdef"},
+		},
+		Revoke: []string{"another"},
+	}, gomock.Any()).Times(1)
+
+	s.deliver(1, `{"command": "modify_access", "repo_id": "1234", "payload": {
+		"grant": [{"username": "someone", "password": "$2y$10$abcdef"}],
+		"revoke": ["another"]
+	}}`)
+	assert.Equal(c, []uint64{1}, s.ack.acked)
+	assert.Empty(c, s.ack.nacked)
+}
+
+func (s *ConsumerTestSuite) TestInvalidMessages(c *check.C) {
+	mockCtrl, _ := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	s.deliver(1, `this is not JSON`)
+	s.deliver(2, `{"command": "format_harddisk", "repo_id": "1234"}`)
+	s.deliver(3, `{"command": "delete_repo", "repo_id": "../../etc"}`)
+	s.deliver(4, `{"command": "delete_repo"}`)
+	s.deliver(5, `{"command": "create_repo", "repo_id": "1234"}`)
+	s.deliver(6, `{"command": "modify_access", "repo_id": "1234", "payload": {"revoke": ["in valid"]}}`)
+
+	assert.Empty(c, s.ack.acked)
+	assert.Equal(c, []uint64{1, 2, 3, 4, 5, 6}, s.ack.nacked)
+}
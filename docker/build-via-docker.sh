@@ -60,7 +60,7 @@ fi
 mkdir $PREFIX
 
 echo "Assembling files into $PREFIX/"
-rsync ../ui ../json_schemas $PREFIX -a --delete-after
+rsync ../ui ../json_schemas ../svn_hooks ../apache_templates $PREFIX -a --delete-after
 cp ../{README.md,LICENSE.txt,CHANGELOG.md} $PREFIX/
 
 if [ -z "$TARGET" -o "$TARGET" = "linux" ]; then
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+)
+
+// AMQPPublisher publishes events as JSON to a RabbitMQ topic exchange.
+type AMQPPublisher struct {
+	mutex    sync.Mutex
+	channel  *amqp.Channel
+	exchange string
+}
+
+// CreateAMQPPublisher declares the topic exchange, and returns a publisher for it.
+func CreateAMQPPublisher(conn *amqp.Connection, exchange string) (*AMQPPublisher, error) {
+	logger := log.WithField("exchange", exchange)
+
+	channel, err := conn.Channel()
+	if err != nil {
+		logger.WithError(err).Error("unable to open AMQP channel")
+		return nil, err
+	}
+	if err := channel.ExchangeDeclare(exchange, "topic", true, false, false, false, nil); err != nil {
+		logger.WithError(err).Error("unable to declare AMQP exchange")
+		channel.Close()
+		return nil, err
+	}
+
+	logger.Info("publishing events to AMQP")
+	return &AMQPPublisher{
+		channel:  channel,
+		exchange: exchange,
+	}, nil
+}
+
+// Publish sends the payload as JSON to the exchange.
+func (ap *AMQPPublisher) Publish(routingKey string, payload interface{}) {
+	logger := log.WithFields(log.Fields{
+		"exchange":    ap.exchange,
+		"routing_key": routingKey,
+	})
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.WithError(err).Error("unable to encode event as JSON")
+		return
+	}
+
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+
+	err = ap.channel.Publish(ap.exchange, routingKey, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now().UTC(),
+		Body:         body,
+	})
+	if err != nil {
+		logger.WithError(err).Error("unable to publish event")
+		return
+	}
+	logger.Debug("event published")
+}
+
+// Close closes the AMQP channel.
+func (ap *AMQPPublisher) Close() {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+
+	if err := ap.channel.Close(); err != nil {
+		log.WithField("exchange", ap.exchange).WithError(err).Warning("unable to close AMQP channel")
+	}
+}
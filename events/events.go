@@ -0,0 +1,51 @@
+// Package events publishes repository lifecycle events, so that others don't have to poll for them.
+package events
+
+import "time"
+
+// Routing keys of the events we publish.
+const (
+	RepoCreated        = "repo.created"
+	RepoDeleted        = "repo.deleted"
+	RepoRestored       = "repo.restored"
+	RepoPurged         = "repo.purged"
+	RepoBackupRestored = "repo.backup_restored"
+	RepoAccessChanged  = "repo.access_changed"
+	RepoAuthzChanged   = "repo.authz_changed"
+	RepoCommit         = "repo.commit"
+)
+
+// Publisher describes the interface for publishing events.
+// Publishing is best-effort; errors are logged and not returned.
+type Publisher interface {
+	Publish(routingKey string, payload interface{})
+}
+
+// RepoEvent is published whenever an operation on a repository succeeds or fails.
+type RepoEvent struct {
+	RepoID    string    `json:"repo_id"`
+	ProjectID string    `json:"project_id,omitempty"`
+	Creator   string    `json:"creator,omitempty"`
+	Granted   []string  `json:"granted,omitempty"` // list of usernames
+	Revoked   []string  `json:"revoked,omitempty"` // list of usernames
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CommitEvent is published for every commit in a repository.
+type CommitEvent struct {
+	RepoID       string        `json:"repo_id"`
+	ProjectID    string        `json:"project_id,omitempty"`
+	Revision     int           `json:"revision"`
+	Author       string        `json:"author"`
+	Date         time.Time     `json:"date"`
+	LogMessage   string        `json:"log_message"`
+	ChangedPaths []ChangedPath `json:"changed_paths"`
+}
+
+// ChangedPath describes a single path changed in a commit.
+type ChangedPath struct {
+	Action string `json:"action"` // as reported by 'svnlook changed', like "A", "U" or "_U".
+	Path   string `json:"path"`
+}
@@ -0,0 +1,45 @@
+package events
+
+import "sync"
+
+// NoopPublisher discards all events.
+type NoopPublisher struct{}
+
+// Publish does nothing.
+func (np NoopPublisher) Publish(routingKey string, payload interface{}) {}
+
+// PublishedEvent is an event as stored by the MemoryPublisher.
+type PublishedEvent struct {
+	RoutingKey string
+	Payload    interface{}
+}
+
+// MemoryPublisher keeps all events in memory, for testing purposes.
+type MemoryPublisher struct {
+	mutex  sync.Mutex
+	events []PublishedEvent
+}
+
+// Publish stores the event.
+func (mp *MemoryPublisher) Publish(routingKey string, payload interface{}) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+	mp.events = append(mp.events, PublishedEvent{routingKey, payload})
+}
+
+// Events returns the events published so far.
+func (mp *MemoryPublisher) Events() []PublishedEvent {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	events := make([]PublishedEvent, len(mp.events))
+	copy(events, mp.events)
+	return events
+}
+
+// Reset forgets all events published so far.
+func (mp *MemoryPublisher) Reset() {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+	mp.events = nil
+}
@@ -3,6 +3,7 @@ package httphandler
 import (
 	"net/http"
 
+	"github.com/armadillica/svn-manager/jobs"
 	"github.com/armadillica/svn-manager/svnman"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -10,26 +11,54 @@ import (
 
 // APIHandler serves HTTP requests and forwards connections to the SVN Man.
 type APIHandler struct {
-	svn svnman.Manager
-	r   *mux.Router // the router we're attached to
+	svn         svnman.Manager
+	jobs        *jobs.Manager
+	maxDumpSize int64       // of uploaded dumps, in bytes after decompression.
+	r           *mux.Router // the router we're attached to
 }
 
-// CreateAPIHandler creates a new HTTP request handler that's bound to the given SVN Man.
-func CreateAPIHandler(svn svnman.Manager) *APIHandler {
-	return &APIHandler{svn, nil}
+// CreateAPIHandler creates a new HTTP request handler that's bound to the given SVN Man,
+// and that runs long operations via the given job manager. Uploaded dumps are refused when
+// they are larger than maxDumpSize bytes.
+func CreateAPIHandler(svn svnman.Manager, jobManager *jobs.Manager, maxDumpSize int64) *APIHandler {
+	return &APIHandler{
+		svn:         svn,
+		jobs:        jobManager,
+		maxDumpSize: maxDumpSize,
+	}
 }
 
 // AddRoutes adds the web endpoints to the router.
 func (h *APIHandler) AddRoutes(r *mux.Router) {
 	h.r = r
 	r.HandleFunc("/repo", h.createRepo).Methods("POST")
+	r.HandleFunc("/repo", h.listRepos).Methods("GET")
 	r.HandleFunc("/repo/{repo-id}", h.getRepo).Methods("GET").Name("get-repo")
 	r.HandleFunc("/repo/{repo-id}", h.deleteRepo).Methods("DELETE")
+	r.HandleFunc("/repo/{repo-id}/load", h.loadRepo).Methods("POST")
+	r.HandleFunc("/repo/{repo-id}/dump", h.dumpRepo).Methods("GET")
+	r.HandleFunc("/repo/{repo-id}/dump", h.submitDumpJob).Methods("POST")
+	r.HandleFunc("/repo/{repo-id}/verify", h.submitVerifyJob).Methods("POST")
+	r.HandleFunc("/repo/{repo-id}/pack", h.submitPackJob).Methods("POST")
+	r.HandleFunc("/repo/{repo-id}/backup", h.getBackup).Methods("GET")
+	r.HandleFunc("/repo/{repo-id}/backup", h.submitBackupJob).Methods("POST")
+	r.HandleFunc("/repo/{repo-id}/backup/restore", h.submitRestoreBackupJob).Methods("POST")
 	r.HandleFunc("/repo/{repo-id}/block", h.blockUnblockRepo).Methods("POST")
 	r.HandleFunc("/repo/{repo-id}/access", h.modifyAccess).Methods("POST")
+	r.HandleFunc("/repo/{repo-id}/authz", h.getAuthz).Methods("GET")
+	r.HandleFunc("/repo/{repo-id}/authz", h.setAuthz).Methods("PUT")
+	r.HandleFunc("/repo/{repo-id}/commits", h.notifyCommit).Methods("POST")
 	r.HandleFunc("/repo/{repo-id}/hooks", h.reportRepoHooks).Methods("GET")
 	r.HandleFunc("/repo/{repo-id}/hooks", h.modifyHooks).Methods("POST")
+	r.HandleFunc("/users/{username}/repos", h.getUserRepos).Methods("GET")
+	r.HandleFunc("/users/{username}/password", h.setPassword).Methods("POST")
+	r.HandleFunc("/jobs/{job-id}", h.getJob).Methods("GET").Name("get-job")
+	r.HandleFunc("/jobs/{job-id}/output", h.getJobOutput).Methods("GET")
 	r.HandleFunc("/hooks", h.listAvailableHooks).Methods("GET")
+	r.HandleFunc("/attic", h.listAttic).Methods("GET")
+	r.HandleFunc("/attic/{repo-id}", h.listAttic).Methods("GET")
+	r.HandleFunc("/attic/{repo-id}/{timestamp}", h.purgeAtticEntry).Methods("DELETE")
+	r.HandleFunc("/attic/{repo-id}/{timestamp}/restore", h.restoreRepo).Methods("POST")
 }
 
 func logFieldsForRequest(r *http.Request) (log.Fields, *log.Entry) {
@@ -60,24 +89,44 @@ func getRepoID(w http.ResponseWriter, r *http.Request, logFields log.Fields) str
 	return repoID
 }
 
-func (h *APIHandler) notImplemented(w http.ResponseWriter, r *http.Request) {
-	_, logger := logFieldsForRequest(r)
-	logger.Warning("handler for this URL not implemented")
-	w.WriteHeader(http.StatusNotImplemented)
-}
+// Returns the username from the request, or "" when there was no (valid) one.
+func getUsername(w http.ResponseWriter, r *http.Request, logFields log.Fields) string {
+	username, ok := mux.Vars(r)["username"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		log.WithFields(logFields).Warning("no username given")
+		return ""
+	}
+	logFields["username"] = username
 
-func (h *APIHandler) blockUnblockRepo(w http.ResponseWriter, r *http.Request) {
-	h.notImplemented(w, r)
+	if !ValidUsername(username) {
+		w.WriteHeader(http.StatusBadRequest)
+		log.WithFields(logFields).Warning("invalid username given")
+		return ""
+	}
+	return username
 }
 
-func (h *APIHandler) listAvailableHooks(w http.ResponseWriter, r *http.Request) {
-	h.notImplemented(w, r)
-}
+// Returns the attic timestamp from the request, or "" when there was no (valid) one.
+func getAtticTimestamp(w http.ResponseWriter, r *http.Request, logFields log.Fields) string {
+	timestamp, ok := mux.Vars(r)["timestamp"]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		log.WithFields(logFields).Warning("no timestamp given")
+		return ""
+	}
+	logFields["timestamp"] = timestamp
 
-func (h *APIHandler) reportRepoHooks(w http.ResponseWriter, r *http.Request) {
-	h.notImplemented(w, r)
+	if !ValidAtticTimestamp(timestamp) {
+		w.WriteHeader(http.StatusBadRequest)
+		log.WithFields(logFields).Warning("invalid timestamp given")
+		return ""
+	}
+	return timestamp
 }
 
-func (h *APIHandler) modifyHooks(w http.ResponseWriter, r *http.Request) {
-	h.notImplemented(w, r)
+func (h *APIHandler) notImplemented(w http.ResponseWriter, r *http.Request) {
+	_, logger := logFieldsForRequest(r)
+	logger.Warning("handler for this URL not implemented")
+	w.WriteHeader(http.StatusNotImplemented)
 }
@@ -1,6 +1,11 @@
 package httphandler
 
 import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/armadillica/svn-manager/jobs"
 	"github.com/armadillica/svn-manager/svnman"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
@@ -8,19 +13,49 @@ import (
 )
 
 type HTTPHandlerTestSuite struct {
-	api   *APIHandler
-	route *mux.Router
+	api     *APIHandler
+	route   *mux.Router
+	jobsDir string
 }
 
 var _ = check.Suite(&HTTPHandlerTestSuite{})
 
 func (s *HTTPHandlerTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.jobsDir, err = ioutil.TempDir("", "jobs")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	jobManager, err := jobs.Create(s.jobsDir, 1)
+	if err != nil {
+		c.Fatalf("unable to create job manager: %s", err)
+	}
+
 	s.route = mux.NewRouter()
-	s.api = CreateAPIHandler(nil)
+	s.api = CreateAPIHandler(nil, jobManager, 1024)
 	s.api.AddRoutes(s.route.PathPrefix("/unittests").Subrouter())
 }
 
 func (s *HTTPHandlerTestSuite) TearDownTest(c *check.C) {
+	s.api.jobs.Close()
+	os.RemoveAll(s.jobsDir)
+}
+
+// waitForJob waits until the job has finished, and returns its final state.
+func (s *HTTPHandlerTestSuite) waitForJob(c *check.C, jobID string) jobs.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := s.api.jobs.Get(jobID)
+		if err != nil {
+			c.Fatalf("unable to get job %s: %s", jobID, err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("job %s did not finish in time", jobID)
+	return jobs.Job{}
 }
 
 func (s *HTTPHandlerTestSuite) mockSVN(c *check.C) (*gomock.Controller, *svnman.MockManager) {
@@ -0,0 +1,121 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/gorilla/mux"
+)
+
+// AtticList is sent as JSON response to GET /api/attic requests.
+type AtticList struct {
+	Attic []svnman.AtticEntry `json:"attic"`
+}
+
+func (h *APIHandler) listAttic(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+
+	// The repository ID is optional; without it, the entire attic is listed.
+	repoID := ""
+	if _, ok := mux.Vars(r)["repo-id"]; ok {
+		repoID = getRepoID(w, r, logFields)
+		if repoID == "" {
+			return
+		}
+		logger = logger.WithFields(logFields)
+	}
+
+	entries, err := h.svn.ListAttic(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to list attic")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to list attic: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(AtticList{entries}); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+func (h *APIHandler) restoreRepo(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	timestamp := getAtticTimestamp(w, r, logFields)
+	if timestamp == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+	logger.Info("repository restoration requested")
+
+	err := h.svn.RestoreRepo(repoID, timestamp, logFields)
+	switch err {
+	case nil:
+	case svnman.ErrAlreadyExists:
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "repository %q already exists", repoID)
+		return
+	case svnman.ErrNotInAttic:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "repository %q deleted at %s not found in attic", repoID, timestamp)
+		return
+	default:
+		logger.WithError(err).Error("unable to restore repository")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to restore repository: %s", err)
+		return
+	}
+
+	route, err := h.r.Get("get-repo").URL("repo-id", repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to find URL for repository")
+	} else {
+		w.Header().Set("Location", route.String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	reply := repoCreationResult{RepoID: repoID}
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(reply); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		return
+	}
+}
+
+func (h *APIHandler) purgeAtticEntry(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	timestamp := getAtticTimestamp(w, r, logFields)
+	if timestamp == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+	logger.Info("permanent removal from attic requested")
+
+	err := h.svn.PurgeAtticEntry(repoID, timestamp, logFields)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case svnman.ErrNotInAttic:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "repository %q deleted at %s not found in attic", repoID, timestamp)
+	default:
+		logger.WithError(err).Error("unable to purge repository from attic")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to purge repository from attic: %s", err)
+	}
+}
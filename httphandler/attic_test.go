@@ -0,0 +1,121 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) restoreRepo(c *check.C, repoID, timestamp string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/unittests/attic/"+repoID+"/"+timestamp+"/restore", nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestRestoreRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().RestoreRepo("1234", "2017-11-14T16-30-04Z-00", gomock.Any()).Times(1)
+	mockSVN.EXPECT().RestoreRepo("1234", "2017-11-14T16-30-04+01-00", gomock.Any()).Times(1).Return(svnman.ErrAlreadyExists)
+	mockSVN.EXPECT().RestoreRepo("12345", "2017-11-14T16-30-04Z-00", gomock.Any()).Times(1).Return(svnman.ErrNotInAttic)
+	mockSVN.EXPECT().RestoreRepo("123456", "2017-11-14T16-30-04Z-00", gomock.Any()).Times(1).Return(errors.New("something unexpected"))
+
+	resp := repoCreationResult{}
+	respRec := s.restoreRepo(c, "1234", "2017-11-14T16-30-04Z-00")
+	parseJSON(c, respRec, http.StatusCreated, &resp)
+	assert.Equal(c, "1234", resp.RepoID)
+	assert.Equal(c, "/unittests/repo/1234", respRec.Header().Get("Location"))
+
+	respRec = s.restoreRepo(c, "1234", "2017-11-14T16-30-04+01-00")
+	assert.Equal(c, http.StatusConflict, respRec.Code)
+
+	respRec = s.restoreRepo(c, "12345", "2017-11-14T16-30-04Z-00")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	respRec = s.restoreRepo(c, "123456", "2017-11-14T16-30-04Z-00")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestRestoreRepoBadTimestamp(c *check.C) {
+	mockCtrl, _ := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	respRec := s.restoreRepo(c, "1234", "yesterday")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	respRec = s.restoreRepo(c, "1234", "2017-11-14T16:30:04Z")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestListAttic(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	createdOn := time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC)
+	entries := []svnman.AtticEntry{
+		svnman.AtticEntry{
+			RepoID:    "1234",
+			Timestamp: "2017-11-14T16-30-04Z-00",
+			DeletedOn: time.Date(2017, 11, 14, 16, 30, 4, 0, time.UTC),
+			ProjectID: "59eefa9cf488554678cae036",
+			Creator:   "me",
+			CreatedOn: &createdOn,
+			DiskSize:  47,
+		},
+	}
+	mockSVN.EXPECT().ListAttic("").Times(1).Return(entries, nil)
+	mockSVN.EXPECT().ListAttic("1234").Times(1).Return(entries, nil)
+	mockSVN.EXPECT().ListAttic("12345").Times(1).Return(nil, errors.New("something unexpected"))
+
+	resp := AtticList{}
+	respRec := s.get(c, "/unittests/attic")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, entries, resp.Attic)
+
+	resp = AtticList{}
+	respRec = s.get(c, "/unittests/attic/1234")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, entries, resp.Attic)
+
+	respRec = s.get(c, "/unittests/attic/12345")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+
+	respRec = s.get(c, "/unittests/attic/ab$cd")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) purgeAtticEntry(c *check.C, repoID, timestamp string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("DELETE", "/unittests/attic/"+repoID+"/"+timestamp, nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestPurgeAtticEntry(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().PurgeAtticEntry("1234", "2017-11-14T16-30-04Z-00", gomock.Any()).Times(1)
+	mockSVN.EXPECT().PurgeAtticEntry("12345", "2017-11-14T16-30-04Z-00", gomock.Any()).Times(1).Return(svnman.ErrNotInAttic)
+	mockSVN.EXPECT().PurgeAtticEntry("123456", "2017-11-14T16-30-04Z-00", gomock.Any()).Times(1).Return(svnman.ErrPurge)
+
+	respRec := s.purgeAtticEntry(c, "1234", "2017-11-14T16-30-04Z-00")
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+
+	respRec = s.purgeAtticEntry(c, "12345", "2017-11-14T16-30-04Z-00")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	respRec = s.purgeAtticEntry(c, "123456", "2017-11-14T16-30-04Z-00")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+
+	respRec = s.purgeAtticEntry(c, "1234", "yesterday")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
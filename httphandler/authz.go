@@ -0,0 +1,72 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+func (h *APIHandler) getAuthz(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithField("repo_id", repoID)
+
+	authz, err := h.svn.GetAuthz(repoID)
+	if err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	} else if err != nil {
+		logger.WithError(err).Error("unable to get authorization rules of repository")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to get authorization rules: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(authz); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+func (h *APIHandler) setAuthz(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithField("repo_id", repoID)
+
+	authz := svnman.Authz{}
+	if err := decodeJSON(w, r, &authz, "authz", logFields); err != nil {
+		return
+	}
+
+	logger.Info("going to replace authorization rules of repository")
+	err := h.svn.SetAuthz(repoID, authz, logFields)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case svnman.ErrNotFound:
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+	case svnman.ErrInvalidAuthz:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "unable to set authorization rules: %s", err)
+	default:
+		logger.WithError(err).Error("unable to set authorization rules")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to set authorization rules: %s", err)
+	}
+}
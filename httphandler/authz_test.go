@@ -0,0 +1,80 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) setAuthz(c *check.C, repoID string, payload svnman.Authz) *httptest.ResponseRecorder {
+	body, err := json.Marshal(payload)
+	assert.Nil(c, err, "marshalling failed")
+
+	req, _ := http.NewRequest("PUT", "/unittests/repo/"+repoID+"/authz", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestGetAuthz(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	authz := svnman.Authz{
+		Groups: map[string][]string{"leads": {"alice"}},
+		Rules: []svnman.AuthzRule{
+			{Path: "/trunk/release", Access: map[string]string{"*": "r", "@leads": "rw"}},
+		},
+	}
+	mockSVN.EXPECT().GetAuthz("1234").Times(1).Return(authz, nil)
+	mockSVN.EXPECT().GetAuthz("12345").Times(1).Return(svnman.Authz{}, svnman.ErrNotFound)
+
+	resp := svnman.Authz{}
+	parseJSON(c, s.get(c, "/unittests/repo/1234/authz"), http.StatusOK, &resp)
+	assert.Equal(c, authz, resp)
+
+	respRec := s.get(c, "/unittests/repo/12345/authz")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestSetAuthz(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	authz := svnman.Authz{
+		Groups: map[string][]string{"leads": {"alice"}},
+		Rules: []svnman.AuthzRule{
+			{Path: "/trunk/release", Access: map[string]string{"*": "r", "@leads": "rw"}},
+		},
+	}
+	mockSVN.EXPECT().SetAuthz("1234", authz, gomock.Any()).Times(1)
+	respRec := s.setAuthz(c, "1234", authz)
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+
+	undefined := svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "/", Access: map[string]string{"@nobody": "r"}}},
+	}
+	mockSVN.EXPECT().SetAuthz("1234", undefined, gomock.Any()).Times(1).Return(svnman.ErrInvalidAuthz)
+	respRec = s.setAuthz(c, "1234", undefined)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	mockSVN.EXPECT().SetAuthz("12345", authz, gomock.Any()).Times(1).Return(svnman.ErrNotFound)
+	respRec = s.setAuthz(c, "12345", authz)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	// Invalid permissions should not even reach SVNMan.
+	invalid := svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "/", Access: map[string]string{"alice": "write"}}},
+	}
+	respRec = s.setAuthz(c, "1234", invalid)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
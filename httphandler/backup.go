@@ -0,0 +1,106 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/backups"
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+// getBackupState returns the backup state of the repository, and false after sending an
+// error to the client. A missing backup is only an error when requireBackup is true.
+func (h *APIHandler) getBackupState(w http.ResponseWriter, repoID string, requireBackup bool,
+	logger *log.Entry) (svnman.BackupState, bool) {
+	state, err := h.svn.GetBackupState(repoID)
+	switch {
+	case err == nil:
+		return state, true
+	case err == svnman.ErrBackupsDisabled:
+		w.WriteHeader(http.StatusNotImplemented)
+		fmt.Fprint(w, "backups are not enabled on this SVN Manager")
+	case err == svnman.ErrNoBackup && !requireBackup:
+		return state, true
+	case err == svnman.ErrNoBackup:
+		logger.Warning("backup of repository requested, but there is none")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "no backup of this repository exists")
+	default:
+		logger.WithError(err).Error("unable to get backup state")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to get backup state: %s", err)
+	}
+	return state, false
+}
+
+func (h *APIHandler) getBackup(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	state, ok := h.getBackupState(w, repoID, true, logger)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(state); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+// submitBackupJob starts updating the backup of the repository in the background.
+func (h *APIHandler) submitBackupJob(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	if _, ok := h.getBackupState(w, repoID, false, logger); !ok {
+		return
+	}
+	if _, err := h.svn.GetUsernames(repoID); err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	}
+
+	logger.Info("repository backup requested")
+	job, err := h.jobs.Submit(backups.JobType, repoID, "",
+		backups.JobFunc(h.svn, repoID, copyLogFields(logFields)))
+	h.replyJobSubmitted(w, job, err, logger)
+}
+
+// submitRestoreBackupJob starts turning the backup into a live repository in the background.
+func (h *APIHandler) submitRestoreBackupJob(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	if _, ok := h.getBackupState(w, repoID, true, logger); !ok {
+		return
+	}
+	if !h.checkRepoAbsent(w, repoID, logger) {
+		return
+	}
+
+	logger.Info("restore of repository from backup requested")
+	job, err := h.jobs.Submit(backups.RestoreJobType, repoID, "",
+		backups.RestoreJobFunc(h.svn, repoID, copyLogFields(logFields)))
+	h.replyJobSubmitted(w, job, err, logger)
+}
@@ -0,0 +1,76 @@
+package httphandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) TestGetBackup(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	backedUpOn := time.Date(2017, 12, 1, 12, 0, 0, 0, time.UTC)
+	mockSVN.EXPECT().GetBackupState("1234").Times(1).
+		Return(svnman.BackupState{BackedUpOn: backedUpOn, HeadRevision: 47, DiskSize: 1024}, nil)
+	mockSVN.EXPECT().GetBackupState("12345").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	mockSVN.EXPECT().GetBackupState("123456").Times(1).Return(svnman.BackupState{}, svnman.ErrBackupsDisabled)
+
+	state := svnman.BackupState{}
+	parseJSON(c, s.get(c, "/unittests/repo/1234/backup"), http.StatusOK, &state)
+	assert.Equal(c, 47, state.HeadRevision)
+	assert.Equal(c, int64(1024), state.DiskSize)
+	assert.True(c, backedUpOn.Equal(state.BackedUpOn))
+
+	assert.Equal(c, http.StatusNotFound, s.get(c, "/unittests/repo/12345/backup").Code)
+	assert.Equal(c, http.StatusNotImplemented, s.get(c, "/unittests/repo/123456/backup").Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestSubmitBackupJob(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().GetBackupState("1234").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{}, nil)
+	mockSVN.EXPECT().BackupRepo(gomock.Any(), "1234", gomock.Any()).Times(1).
+		Return(svnman.BackupState{HeadRevision: 3}, nil)
+	mockSVN.EXPECT().GetBackupState("12345").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	mockSVN.EXPECT().GetUsernames("12345").Times(1).Return(nil, svnman.ErrNotFound)
+
+	job := jobs.Job{}
+	parseJSON(c, s.post(c, "/unittests/repo/1234/backup"), http.StatusAccepted, &job)
+	assert.Equal(c, "backup", job.Type)
+	job = s.waitForJob(c, job.ID)
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+
+	assert.Equal(c, http.StatusNotFound, s.post(c, "/unittests/repo/12345/backup").Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestSubmitRestoreBackupJob(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	// Happy flow.
+	mockSVN.EXPECT().GetBackupState("1234").Times(1).Return(svnman.BackupState{HeadRevision: 3}, nil)
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return(nil, svnman.ErrNotFound)
+	mockSVN.EXPECT().RestoreBackup(gomock.Any(), "1234", gomock.Any()).Times(1).Return(nil)
+	// No backup.
+	mockSVN.EXPECT().GetBackupState("12345").Times(1).Return(svnman.BackupState{}, svnman.ErrNoBackup)
+	// Repository still exists.
+	mockSVN.EXPECT().GetBackupState("123456").Times(1).Return(svnman.BackupState{HeadRevision: 3}, nil)
+	mockSVN.EXPECT().GetUsernames("123456").Times(1).Return([]string{}, nil)
+
+	job := jobs.Job{}
+	parseJSON(c, s.post(c, "/unittests/repo/1234/backup/restore"), http.StatusAccepted, &job)
+	assert.Equal(c, "restore-backup", job.Type)
+	job = s.waitForJob(c, job.ID)
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+
+	assert.Equal(c, http.StatusNotFound, s.post(c, "/unittests/repo/12345/backup/restore").Code)
+	assert.Equal(c, http.StatusConflict, s.post(c, "/unittests/repo/123456/backup/restore").Code)
+}
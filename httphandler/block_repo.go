@@ -0,0 +1,51 @@
+package httphandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+// blockRequest is sent as JSON to /api/repo/{repo-id}/block.
+type blockRequest struct {
+	Block bool `json:"block"`
+	svnman.BlockRepo
+}
+
+func (h *APIHandler) blockUnblockRepo(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithField("repo_id", repoID)
+
+	req := blockRequest{}
+	if err := decodeJSON(w, r, &req, "block_repo", logFields); err != nil {
+		return
+	}
+
+	var err error
+	if req.Block {
+		logger.WithField("read_only", req.ReadOnly).Info("repository block requested")
+		err = h.svn.BlockRepo(repoID, req.BlockRepo, logFields)
+	} else {
+		logger.Info("repository unblock requested")
+		err = h.svn.UnblockRepo(repoID, logFields)
+	}
+
+	if err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	} else if err != nil {
+		logger.WithError(err).Error("unable to change block state of repository")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to change block state of repository: %s", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
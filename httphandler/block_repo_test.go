@@ -0,0 +1,66 @@
+package httphandler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) blockRepo(c *check.C, repoID string, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/unittests/repo/"+repoID+"/block", bytes.NewReader([]byte(payload)))
+	req.Header.Set("Content-Type", "application/json")
+
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestBlockRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().BlockRepo("1234", svnman.BlockRepo{Reason: "unpaid"}, gomock.Any()).Times(1)
+	mockSVN.EXPECT().BlockRepo("1234", svnman.BlockRepo{Reason: "abuse", ReadOnly: true}, gomock.Any()).Times(1)
+
+	respRec := s.blockRepo(c, "1234", `{"block": true, "reason": "unpaid"}`)
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+
+	respRec = s.blockRepo(c, "1234", `{"block": true, "reason": "abuse", "read_only": true}`)
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestUnblockRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().UnblockRepo("1234", gomock.Any()).Times(1)
+	mockSVN.EXPECT().UnblockRepo("12345", gomock.Any()).Times(1).Return(svnman.ErrNotFound)
+	mockSVN.EXPECT().UnblockRepo("123456", gomock.Any()).Times(1).Return(errors.New("something unexpected"))
+
+	respRec := s.blockRepo(c, "1234", `{"block": false}`)
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+
+	respRec = s.blockRepo(c, "12345", `{"block": false}`)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	respRec = s.blockRepo(c, "123456", `{"block": false}`)
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestBlockRepoBadRequest(c *check.C) {
+	mockCtrl, _ := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	respRec := s.blockRepo(c, "1234", `{"block": "yes please"}`)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	respRec = s.blockRepo(c, "12", `{"block": true}`)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
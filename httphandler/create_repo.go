@@ -1,20 +1,25 @@
 package httphandler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
-	"strings"
+	"time"
 
+	"github.com/armadillica/svn-manager/backend"
 	"github.com/armadillica/svn-manager/svnman"
 )
 
-var invalidCreatorRegexp = regexp.MustCompile(`[^\pL\d_\-., @<>'()+]+`)
+// How long a /api/repo POST request with ?wait=true waits for the backend to be reloaded.
+const createRepoWaitTimeout = 1 * time.Minute
 
 // repoCreationResult is sent as JSON response to /api/repo POST requests.
 type repoCreationResult struct {
 	RepoID string `json:"repo_id"`
+	// Only set when waiting for the backend, see svnman.RepoDetails.
+	ApacheStatus      string `json:"apache_status,omitempty"`
+	ApacheConfigError string `json:"apache_config_error,omitempty"`
 }
 
 func (h *APIHandler) createRepo(w http.ResponseWriter, r *http.Request) {
@@ -25,10 +30,7 @@ func (h *APIHandler) createRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert repo ID to lower case, so limit the number of prefixes we have
-	// in the repository directory.
-	repoInfo.RepoID = strings.ToLower(repoInfo.RepoID)
-	repoInfo.Creator = invalidCreatorRegexp.ReplaceAllString(repoInfo.Creator, " ")
+	repoInfo.Normalise()
 
 	logger.Info("repository creation requested")
 	err := h.svn.CreateRepo(repoInfo, logFields)
@@ -50,9 +52,25 @@ func (h *APIHandler) createRepo(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Location", route.String())
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	reply := repoCreationResult{RepoID: repoInfo.RepoID}
+	status := http.StatusCreated
+	if r.URL.Query().Get("wait") == "true" {
+		ctx, cancel := context.WithTimeout(r.Context(), createRepoWaitTimeout)
+		reply.ApacheStatus, reply.ApacheConfigError = h.svn.WaitForConfig(ctx, repoInfo.RepoID)
+		cancel()
+
+		switch reply.ApacheStatus {
+		case backend.StatusPending:
+			logger.Warning("timeout waiting for backend reload")
+			status = http.StatusAccepted
+		case backend.StatusFailed:
+			logger.WithField("reason", reply.ApacheConfigError).Error("repository created, but backend config failed")
+			status = http.StatusBadGateway
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	enc := json.NewEncoder(w)
 	if err := enc.Encode(reply); err != nil {
 		logger.WithError(err).Error("unable to encode JSON")
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/armadillica/svn-manager/backend"
 	"github.com/armadillica/svn-manager/svnman"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -132,3 +133,49 @@ func (s *HTTPHandlerTestSuite) TestCreateRepoBadCreator(c *check.C) {
 	respRec := s.createRepo(c, repoInfo)
 	assert.Equal(c, http.StatusCreated, respRec.Code)
 }
+
+func (s *HTTPHandlerTestSuite) TestCreateRepoWait(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	repoInfo := svnman.CreateRepo{
+		RepoID:    "4444",
+		ProjectID: "8afae1eb1d171833df73416b",
+		Creator:   "creator <email@example.com>",
+	}
+	mockSVN.EXPECT().CreateRepo(gomock.Any(), gomock.Any()).Times(3)
+	gomock.InOrder(
+		mockSVN.EXPECT().WaitForConfig(gomock.Any(), "4444").Times(1).Return(backend.StatusActive, ""),
+		mockSVN.EXPECT().WaitForConfig(gomock.Any(), "4444").Times(1).Return(backend.StatusFailed, "Syntax error"),
+		mockSVN.EXPECT().WaitForConfig(gomock.Any(), "4444").Times(1).Return(backend.StatusPending, ""),
+	)
+
+	createAndWait := func() *httptest.ResponseRecorder {
+		body, err := json.Marshal(repoInfo)
+		assert.Nil(c, err, "marshalling failed")
+		req, _ := http.NewRequest("POST", "/unittests/repo?wait=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		respRec := httptest.NewRecorder()
+		s.route.ServeHTTP(respRec, req)
+		return respRec
+	}
+
+	resp := repoCreationResult{}
+	parseJSON(c, createAndWait(), http.StatusCreated, &resp)
+	assert.Equal(c, backend.StatusActive, resp.ApacheStatus)
+
+	resp = repoCreationResult{}
+	parseJSON(c, createAndWait(), http.StatusBadGateway, &resp)
+	assert.Equal(c, backend.StatusFailed, resp.ApacheStatus)
+	assert.Equal(c, "Syntax error", resp.ApacheConfigError)
+
+	resp = repoCreationResult{}
+	parseJSON(c, createAndWait(), http.StatusAccepted, &resp)
+	assert.Equal(c, backend.StatusPending, resp.ApacheStatus)
+
+	// Without waiting, the config status is unknown.
+	mockSVN.EXPECT().CreateRepo(gomock.Any(), gomock.Any()).Times(1)
+	resp = repoCreationResult{}
+	parseJSON(c, s.createRepo(c, repoInfo), http.StatusCreated, &resp)
+	assert.Equal(c, "", resp.ApacheStatus)
+}
@@ -0,0 +1,134 @@
+package httphandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+var dumpContentTypes = map[string]string{
+	"":     "application/octet-stream",
+	"gzip": "application/gzip",
+	"zstd": "application/zstd",
+}
+
+// writeTracker remembers whether anything was written to the response.
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (wt *writeTracker) Write(p []byte) (int, error) {
+	wt.written = true
+	return wt.ResponseWriter.Write(p)
+}
+
+// Returns the dump options from the query parameters, and the filename for the dump.
+// Returns false when the options are invalid, after sending an error to the client.
+func getDumpOptions(w http.ResponseWriter, r *http.Request, repoID string, logger *log.Entry) (svnman.DumpRepo, string, bool) {
+	params := r.URL.Query()
+	options := svnman.DumpRepo{
+		Revisions:   params.Get("revision"),
+		Deltas:      params.Get("deltas") == "true",
+		Compression: params.Get("compression"),
+	}
+	extension, ok := svnman.DumpCompressions[options.Compression]
+	if !ok {
+		logger.WithField("compression", options.Compression).Warning("unsupported compression requested")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "unsupported compression %q", options.Compression)
+		return options, "", false
+	}
+	return options, repoID + ".svndump" + extension, true
+}
+
+func (h *APIHandler) dumpRepo(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	options, filename, ok := getDumpOptions(w, r, repoID, logger)
+	if !ok {
+		return
+	}
+
+	// Headers have to be set before the dump starts streaming.
+	w.Header().Set("Content-Type", dumpContentTypes[options.Compression])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// The request context is cancelled when the client disconnects.
+	tracker := &writeTracker{ResponseWriter: w}
+	err := h.svn.DumpRepo(r.Context(), repoID, options, tracker, logFields)
+	if err == nil || r.Context().Err() != nil {
+		return
+	}
+	if tracker.written {
+		// Too late to report the error, the truncated dump will have to do.
+		logger.WithError(err).Error("dumping repository failed halfway")
+		return
+	}
+
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	switch err {
+	case svnman.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+	case svnman.ErrInvalidRevision:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "invalid revision %q requested", options.Revisions)
+	default:
+		logger.WithError(err).Error("unable to dump repository")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to dump repository: %s", err)
+	}
+}
+
+// submitDumpJob dumps the repository to a file in the background, which can be
+// downloaded via /api/jobs/{job-id}/output when the job is done.
+func (h *APIHandler) submitDumpJob(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	options, filename, ok := getDumpOptions(w, r, repoID, logger)
+	if !ok {
+		return
+	}
+
+	if _, err := h.svn.GetUsernames(repoID); err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	}
+
+	logger.Info("repository dump requested")
+	jobLogFields := copyLogFields(logFields)
+	job, err := h.jobs.Submit("dump", repoID, filename, func(ctx context.Context, run *jobs.Run) error {
+		jobLogFields["job_id"] = run.ID
+
+		output, err := os.Create(run.OutputPath)
+		if err != nil {
+			return err
+		}
+		defer output.Close()
+
+		if err := h.svn.DumpRepo(ctx, repoID, options, run.Writer(output), jobLogFields); err != nil {
+			return err
+		}
+		return output.Close()
+	})
+	h.replyJobSubmitted(w, job, err, logger)
+}
@@ -0,0 +1,114 @@
+package httphandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func writeDump(ctx context.Context, repoID string, options svnman.DumpRepo, w io.Writer, logFields log.Fields) error {
+	_, err := io.WriteString(w, "SVN-fs-dump-format-version: 2\n")
+	return err
+}
+
+func (s *HTTPHandlerTestSuite) TestDumpRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "1234", svnman.DumpRepo{}, gomock.Any(), gomock.Any()).
+		Times(1).DoAndReturn(writeDump)
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "1234", svnman.DumpRepo{Revisions: "3:5", Deltas: true, Compression: "gzip"},
+		gomock.Any(), gomock.Any()).Times(1).DoAndReturn(writeDump)
+
+	respRec := s.get(c, "/unittests/repo/1234/dump")
+	assert.Equal(c, http.StatusOK, respRec.Code)
+	assert.Equal(c, "application/octet-stream", respRec.Header().Get("Content-Type"))
+	assert.Equal(c, `attachment; filename="1234.svndump"`, respRec.Header().Get("Content-Disposition"))
+	assert.Equal(c, "SVN-fs-dump-format-version: 2\n", respRec.Body.String())
+
+	respRec = s.get(c, "/unittests/repo/1234/dump?revision=3:5&deltas=true&compression=gzip")
+	assert.Equal(c, http.StatusOK, respRec.Code)
+	assert.Equal(c, "application/gzip", respRec.Header().Get("Content-Type"))
+	assert.Equal(c, `attachment; filename="1234.svndump.gz"`, respRec.Header().Get("Content-Disposition"))
+}
+
+func (s *HTTPHandlerTestSuite) TestDumpRepoUnhappy(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "1234", gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).Return(svnman.ErrNotFound)
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "1234", svnman.DumpRepo{Revisions: "47"}, gomock.Any(), gomock.Any()).
+		Times(1).Return(svnman.ErrInvalidRevision)
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "12345", gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).Return(errors.New("something unexpected"))
+
+	respRec := s.get(c, "/unittests/repo/1234/dump")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+	assert.Equal(c, "", respRec.Header().Get("Content-Disposition"))
+
+	respRec = s.get(c, "/unittests/repo/1234/dump?revision=47")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	respRec = s.get(c, "/unittests/repo/12345/dump")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+
+	respRec = s.get(c, "/unittests/repo/1234/dump?compression=rar")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestDumpRepoFailedHalfway(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "1234", gomock.Any(), gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+		func(ctx context.Context, repoID string, options svnman.DumpRepo, w io.Writer, logFields log.Fields) error {
+			writeDump(ctx, repoID, options, w, logFields)
+			return errors.New("disk on fire")
+		})
+
+	respRec := s.get(c, "/unittests/repo/1234/dump")
+	assert.Equal(c, http.StatusOK, respRec.Code)
+	assert.Equal(c, "SVN-fs-dump-format-version: 2\n", respRec.Body.String())
+}
+
+func (s *HTTPHandlerTestSuite) TestSubmitDumpJob(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{}, nil)
+	mockSVN.EXPECT().GetUsernames("12345").Times(1).Return(nil, svnman.ErrNotFound)
+	mockSVN.EXPECT().DumpRepo(gomock.Any(), "1234", svnman.DumpRepo{Compression: "gzip"}, gomock.Any(), gomock.Any()).
+		Times(1).DoAndReturn(writeDump)
+
+	job := jobs.Job{}
+	req, _ := http.NewRequest("POST", "/unittests/repo/1234/dump?compression=gzip", nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	parseJSON(c, respRec, http.StatusAccepted, &job)
+	assert.Equal(c, "dump", job.Type)
+	assert.Equal(c, "1234.svndump.gz", job.OutputName)
+
+	job = s.waitForJob(c, job.ID)
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+	assert.Equal(c, jobs.Progress{Done: 30, Unit: "bytes"}, job.Progress)
+
+	respRec = s.get(c, "/unittests/jobs/"+job.ID+"/output")
+	assert.Equal(c, http.StatusOK, respRec.Code)
+	assert.Equal(c, `attachment; filename="1234.svndump.gz"`, respRec.Header().Get("Content-Disposition"))
+	assert.Equal(c, "SVN-fs-dump-format-version: 2\n", respRec.Body.String())
+
+	req, _ = http.NewRequest("POST", "/unittests/repo/12345/dump", nil)
+	respRec = httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+}
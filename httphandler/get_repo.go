@@ -10,8 +10,10 @@ import (
 
 // RepoDescription is sent as JSON response to /api/repo/{repo-id} requests.
 type RepoDescription struct {
-	RepoID string   `json:"repo_id"`
-	Access []string `json:"access"` // list of usernames
+	RepoID string            `json:"repo_id"`
+	Access []string          `json:"access"` // list of usernames
+	Block  svnman.BlockState `json:"block"`
+	svnman.RepoDetails
 }
 
 func (h *APIHandler) getRepo(w http.ResponseWriter, r *http.Request) {
@@ -35,9 +37,27 @@ func (h *APIHandler) getRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	block, err := h.svn.GetBlockState(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to get block state of repo")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to get block state: %s", err)
+		return
+	}
+
+	details, err := h.svn.GetRepoDetails(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to get details of repo")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to get repository details: %s", err)
+		return
+	}
+
 	reply := RepoDescription{
-		RepoID: repoID,
-		Access: names,
+		RepoID:      repoID,
+		Access:      names,
+		Block:       block,
+		RepoDetails: details,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"sort"
+	"time"
 
+	"github.com/armadillica/svn-manager/svnman"
 	"github.com/stretchr/testify/assert"
 	check "gopkg.in/check.v1"
 )
@@ -27,12 +29,15 @@ func (s *HTTPHandlerTestSuite) TestGetRepo(c *check.C) {
 	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{}, nil)
 	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{"mysterioususer", "someone.else"}, nil)
 	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return(nil, errors.New("test error"))
+	mockSVN.EXPECT().GetBlockState("1234").Times(2).Return(svnman.BlockState{}, nil)
+	mockSVN.EXPECT().GetRepoDetails("1234").Times(2).Return(svnman.RepoDetails{}, nil)
 
 	resp := RepoDescription{}
 	respRec := s.getRepo(c, "1234")
 	parseJSON(c, respRec, http.StatusOK, &resp)
 	assert.Equal(c, "1234", resp.RepoID)
 	assert.Equal(c, []string{}, resp.Access)
+	assert.False(c, resp.Block.Blocked)
 
 	resp = RepoDescription{}
 	respRec = s.getRepo(c, "1234")
@@ -44,3 +49,58 @@ func (s *HTTPHandlerTestSuite) TestGetRepo(c *check.C) {
 	respRec = s.getRepo(c, "1234")
 	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
 }
+
+func (s *HTTPHandlerTestSuite) TestGetBlockedRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	blockedOn := time.Date(2017, 11, 14, 16, 30, 0, 0, time.UTC)
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{"someone"}, nil)
+	mockSVN.EXPECT().GetBlockState("1234").Times(1).Return(svnman.BlockState{
+		Blocked:   true,
+		ReadOnly:  true,
+		Reason:    "unpaid subscription",
+		BlockedOn: &blockedOn,
+	}, nil)
+	mockSVN.EXPECT().GetRepoDetails("1234").Times(1).Return(svnman.RepoDetails{}, nil)
+
+	resp := RepoDescription{}
+	respRec := s.getRepo(c, "1234")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.True(c, resp.Block.Blocked)
+	assert.True(c, resp.Block.ReadOnly)
+	assert.Equal(c, "unpaid subscription", resp.Block.Reason)
+	assert.True(c, blockedOn.Equal(*resp.Block.BlockedOn))
+}
+
+func (s *HTTPHandlerTestSuite) TestGetRepoDetails(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	createdOn := time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC)
+	lastCommit := time.Date(2017, 11, 14, 16, 30, 4, 0, time.UTC)
+	details := svnman.RepoDetails{
+		ProjectID:        "59eefa9cf488554678cae036",
+		Creator:          "me",
+		CreatedOn:        createdOn,
+		AppName:          "SVN Manager",
+		AppVersion:       "0.2",
+		HeadRevision:     47,
+		LastCommitDate:   &lastCommit,
+		LastCommitAuthor: "someone",
+		DiskSize:         1024,
+	}
+	mockSVN.EXPECT().GetUsernames("1234").Times(2).Return([]string{"someone"}, nil)
+	mockSVN.EXPECT().GetBlockState("1234").Times(2).Return(svnman.BlockState{}, nil)
+	mockSVN.EXPECT().GetRepoDetails("1234").Times(1).Return(details, nil)
+	mockSVN.EXPECT().GetRepoDetails("1234").Times(1).Return(svnman.RepoDetails{}, errors.New("test error"))
+
+	resp := RepoDescription{}
+	respRec := s.getRepo(c, "1234")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, "1234", resp.RepoID)
+	assert.Equal(c, details, resp.RepoDetails)
+
+	respRec = s.getRepo(c, "1234")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+}
@@ -0,0 +1,104 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+// HookList is sent as JSON response to /api/hooks requests.
+type HookList struct {
+	Hooks []svnman.HookDescription `json:"hooks"`
+}
+
+// RepoHooks is sent as JSON response to GET /api/repo/{repo-id}/hooks requests.
+type RepoHooks struct {
+	RepoID string                 `json:"repo_id"`
+	Hooks  []svnman.InstalledHook `json:"hooks"`
+}
+
+func (h *APIHandler) listAvailableHooks(w http.ResponseWriter, r *http.Request) {
+	_, logger := logFieldsForRequest(r)
+
+	hooks, err := h.svn.ListAvailableHooks()
+	if err != nil {
+		logger.WithError(err).Error("unable to list available hooks")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to list available hooks: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(HookList{hooks}); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+func (h *APIHandler) reportRepoHooks(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithField("repo_id", repoID)
+
+	hooks, err := h.svn.GetRepoHooks(repoID)
+	if err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	} else if err != nil {
+		logger.WithError(err).Error("unable to get hooks for repo")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to get hooks: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(RepoHooks{repoID, hooks}); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+func (h *APIHandler) modifyHooks(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithField("repo_id", repoID)
+
+	mods := svnman.ModifyHooks{}
+	if err := decodeJSON(w, r, &mods, "modify_hooks", logFields); err != nil {
+		return
+	}
+
+	logger.Info("going to modify hooks on repository")
+	err := h.svn.ModifyHooks(repoID, mods, logFields)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case svnman.ErrNotFound:
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+	case svnman.ErrUnknownHook, svnman.ErrInvalidHookParam, svnman.ErrConflictingHooks:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "unable to modify hooks: %s", err)
+	default:
+		logger.WithError(err).Error("unable to modify hooks")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to modify hooks: %s", err)
+	}
+}
@@ -0,0 +1,125 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) modifyHooks(c *check.C, repoID string, payload svnman.ModifyHooks) *httptest.ResponseRecorder {
+	body, err := json.Marshal(payload)
+	assert.Nil(c, err, "marshalling failed")
+
+	req, _ := http.NewRequest("POST", "/unittests/repo/"+repoID+"/hooks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) get(c *check.C, url string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) post(c *check.C, url string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", url, nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestListAvailableHooks(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	available := []svnman.HookDescription{
+		svnman.HookDescription{
+			Name:        "reject-empty-log-message",
+			Type:        "pre-commit",
+			Description: "Rejects commits without a proper log message.",
+			Parameters: map[string]svnman.HookParameter{
+				"min_length": svnman.HookParameter{Description: "Minimum length", Default: "1"},
+			},
+		},
+	}
+	mockSVN.EXPECT().ListAvailableHooks().Times(1).Return(available, nil)
+
+	resp := HookList{}
+	respRec := s.get(c, "/unittests/hooks")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, available, resp.Hooks)
+}
+
+func (s *HTTPHandlerTestSuite) TestReportRepoHooks(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	installed := []svnman.InstalledHook{
+		svnman.InstalledHook{Name: "max-file-size", Params: map[string]string{"max_size_mb": "40"}},
+	}
+	mockSVN.EXPECT().GetRepoHooks("1234").Times(1).Return(installed, nil)
+	mockSVN.EXPECT().GetRepoHooks("12345").Times(1).Return(nil, svnman.ErrNotFound)
+
+	resp := RepoHooks{}
+	respRec := s.get(c, "/unittests/repo/1234/hooks")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, "1234", resp.RepoID)
+	assert.Equal(c, installed, resp.Hooks)
+
+	respRec = s.get(c, "/unittests/repo/12345/hooks")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestModifyHooksHappy(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	payload := svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{
+			svnman.InstalledHook{Name: "max-file-size", Params: map[string]string{"max_size_mb": "40"}},
+		},
+		Remove: []string{"reject-empty-log-message"},
+	}
+	mockSVN.EXPECT().ModifyHooks("1234", payload, gomock.Any()).Times(1)
+
+	respRec := s.modifyHooks(c, "1234", payload)
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestModifyHooksUnhappy(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	unknown := svnman.ModifyHooks{Install: []svnman.InstalledHook{svnman.InstalledHook{Name: "unknown"}}}
+	mockSVN.EXPECT().ModifyHooks("1234", unknown, gomock.Any()).Times(1).Return(svnman.ErrUnknownHook)
+	respRec := s.modifyHooks(c, "1234", unknown)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	conflicting := svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{svnman.InstalledHook{Name: "test-hook"}},
+		Remove:  []string{"test-hook"},
+	}
+	mockSVN.EXPECT().ModifyHooks("1234", conflicting, gomock.Any()).Times(1).Return(svnman.ErrConflictingHooks)
+	respRec = s.modifyHooks(c, "1234", conflicting)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	mockSVN.EXPECT().ModifyHooks("12345", unknown, gomock.Any()).Times(1).Return(svnman.ErrNotFound)
+	respRec = s.modifyHooks(c, "12345", unknown)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	// Invalid hook names should not even reach SVNMan.
+	invalid := svnman.ModifyHooks{Install: []svnman.InstalledHook{svnman.InstalledHook{Name: "../../evil"}}}
+	respRec = s.modifyHooks(c, "1234", invalid)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
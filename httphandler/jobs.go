@@ -0,0 +1,129 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// copyLogFields returns a copy of the log fields, for use by a job that outlives the request.
+func copyLogFields(logFields log.Fields) log.Fields {
+	copied := log.Fields{}
+	for key, value := range logFields {
+		copied[key] = value
+	}
+	return copied
+}
+
+// replyJobSubmitted sends the submitted job to the client, or the error if submitting failed.
+func (h *APIHandler) replyJobSubmitted(w http.ResponseWriter, job jobs.Job, err error, logger *log.Entry) {
+	switch err {
+	case nil:
+	case jobs.ErrQueueFull, jobs.ErrClosed:
+		logger.WithError(err).Warning("unable to submit job")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "unable to submit job: %s", err)
+		return
+	default:
+		logger.WithError(err).Error("unable to submit job")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to submit job: %s", err)
+		return
+	}
+
+	route, err := h.r.Get("get-job").URL("job-id", job.ID)
+	if err != nil {
+		logger.WithError(err).WithField("job_id", job.ID).Error("unable to find URL for job")
+	} else {
+		w.Header().Set("Location", route.String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(job); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		return
+	}
+}
+
+// Returns the job from the request, or false when it doesn't exist.
+func (h *APIHandler) getJobFromRequest(w http.ResponseWriter, r *http.Request, logger *log.Entry) (jobs.Job, bool) {
+	jobID := mux.Vars(r)["job-id"]
+	job, err := h.jobs.Get(jobID)
+	if err == jobs.ErrNotFound {
+		logger.WithField("job_id", jobID).Warning("nonexistent job requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent job requested")
+		return job, false
+	} else if err != nil {
+		logger.WithField("job_id", jobID).WithError(err).Error("unable to get job")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to get job: %s", err)
+		return job, false
+	}
+	return job, true
+}
+
+func (h *APIHandler) getJob(w http.ResponseWriter, r *http.Request) {
+	_, logger := logFieldsForRequest(r)
+	job, ok := h.getJobFromRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(job); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+// getJobOutput sends the output file of a completed job.
+func (h *APIHandler) getJobOutput(w http.ResponseWriter, r *http.Request) {
+	_, logger := logFieldsForRequest(r)
+	job, ok := h.getJobFromRequest(w, r, logger)
+	if !ok {
+		return
+	}
+	logger = logger.WithField("job_id", job.ID)
+
+	if job.OutputName == "" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "this job does not produce output")
+		return
+	}
+	if job.Status != jobs.StatusCompleted {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "output is not available, job is %s", job.Status)
+		return
+	}
+
+	output, err := os.Open(h.jobs.OutputPath(job.ID))
+	if err != nil {
+		logger.WithError(err).Error("unable to open job output")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to open job output: %s", err)
+		return
+	}
+	defer output.Close()
+	stat, err := output.Stat()
+	if err != nil {
+		logger.WithError(err).Error("unable to inspect job output")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to inspect job output: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.OutputName))
+	http.ServeContent(w, r, job.OutputName, stat.ModTime(), output)
+}
@@ -0,0 +1,50 @@
+package httphandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) TestGetJob(c *check.C) {
+	submitted, err := s.api.jobs.Submit("test", "1234", "", func(ctx context.Context, run *jobs.Run) error {
+		run.Progress(3, 5, "revisions")
+		return errors.New("something went wrong")
+	})
+	assert.Nil(c, err)
+	s.waitForJob(c, submitted.ID)
+
+	job := jobs.Job{}
+	respRec := s.get(c, "/unittests/jobs/"+submitted.ID)
+	parseJSON(c, respRec, http.StatusOK, &job)
+	assert.Equal(c, submitted.ID, job.ID)
+	assert.Equal(c, jobs.StatusFailed, job.Status)
+	assert.Equal(c, "something went wrong", job.Error)
+	assert.Equal(c, jobs.Progress{Done: 3, Total: 5, Unit: "revisions"}, job.Progress)
+
+	// This job has no output.
+	respRec = s.get(c, "/unittests/jobs/"+submitted.ID+"/output")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	respRec = s.get(c, "/unittests/jobs/0123456789abcdef0123456789abcdef")
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestGetJobOutputUnfinished(c *check.C) {
+	block := make(chan struct{})
+	submitted, err := s.api.jobs.Submit("test", "1234", "output.txt", func(ctx context.Context, run *jobs.Run) error {
+		<-block
+		return nil
+	})
+	assert.Nil(c, err)
+
+	respRec := s.get(c, "/unittests/jobs/"+submitted.ID+"/output")
+	assert.Equal(c, http.StatusConflict, respRec.Code)
+
+	close(block)
+	s.waitForJob(c, submitted.ID)
+}
@@ -0,0 +1,67 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+// Pagination limits of GET /api/repo.
+const (
+	defaultRepoListLimit = 100
+	maxRepoListLimit     = 1000
+)
+
+func (h *APIHandler) listRepos(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+
+	params := r.URL.Query()
+	query := svnman.ListRepos{
+		ProjectID: params.Get("project_id"),
+		Creator:   params.Get("creator"),
+		Cursor:    params.Get("cursor"),
+		Limit:     defaultRepoListLimit,
+	}
+
+	if query.Cursor != "" && !ValidRepoID(query.Cursor) {
+		logger.WithField("cursor", query.Cursor).Warning("invalid cursor given")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "invalid cursor given")
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		var err error
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > maxRepoListLimit {
+			logger.WithField("limit", limit).Warning("invalid limit given")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "limit should be a number between 1 and %d", maxRepoListLimit)
+			return
+		}
+	}
+
+	logFields["project_id"] = query.ProjectID
+	logFields["creator"] = query.Creator
+	logger = logger.WithFields(logFields)
+	logger.Debug("listing repositories")
+
+	list, err := h.svn.ListRepos(query)
+	if err != nil {
+		logger.WithError(err).Error("unable to list repositories")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to list repositories: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(list); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
@@ -0,0 +1,68 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) TestListRepos(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	list := svnman.RepoList{
+		Repos: []svnman.RepoSummary{
+			svnman.RepoSummary{
+				RepoID:    "1234",
+				ProjectID: "59eefa9cf488554678cae036",
+				Creator:   "me",
+				CreatedOn: time.Date(2017, 11, 14, 16, 30, 4, 0, time.UTC),
+				UserCount: 3,
+			},
+		},
+		NextCursor: "1234",
+	}
+	mockSVN.EXPECT().ListRepos(svnman.ListRepos{Limit: 100}).Times(1).Return(list, nil)
+	mockSVN.EXPECT().ListRepos(svnman.ListRepos{
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "Jan de Vries <jan@example.com>",
+		Cursor:    "1234",
+		Limit:     1,
+	}).Times(1).Return(svnman.RepoList{Repos: []svnman.RepoSummary{}}, nil)
+
+	resp := svnman.RepoList{}
+	respRec := s.get(c, "/unittests/repo")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, list, resp)
+
+	resp = svnman.RepoList{}
+	respRec = s.get(c, "/unittests/repo?project_id=59eefa9cf488554678cae036"+
+		"&creator=Jan+de+Vries+%3Cjan%40example.com%3E&cursor=1234&limit=1")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, svnman.RepoList{Repos: []svnman.RepoSummary{}}, resp)
+}
+
+func (s *HTTPHandlerTestSuite) TestListReposBadRequest(c *check.C) {
+	mockCtrl, _ := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	for _, query := range []string{"limit=0", "limit=1001", "limit=many", "cursor=../etc"} {
+		respRec := s.get(c, "/unittests/repo?"+query)
+		assert.Equal(c, http.StatusBadRequest, respRec.Code, "query %q", query)
+	}
+}
+
+func (s *HTTPHandlerTestSuite) TestListReposError(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().ListRepos(svnman.ListRepos{Limit: 100}).Times(1).
+		Return(svnman.RepoList{}, errors.New("something unexpected"))
+
+	respRec := s.get(c, "/unittests/repo")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+}
@@ -0,0 +1,145 @@
+package httphandler
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+// errDumpTooLarge is returned by limitedReader when the dump exceeds the maximum size.
+var errDumpTooLarge = errors.New("dump too large")
+
+// limitedReader reads from r, but fails once more than remaining bytes have been read.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.remaining -= int64(n)
+	if lr.remaining < 0 {
+		return n, errDumpTooLarge
+	}
+	return n, err
+}
+
+// loadRepo creates a repository from the dump file in the request body. As the body is
+// not JSON, the project ID and creator are passed as query parameters. The upload is
+// stored in a temporary file, and then loaded by a background job.
+func (h *APIHandler) loadRepo(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+
+	params := r.URL.Query()
+	repoInfo := svnman.CreateRepo{
+		RepoID:    repoID,
+		ProjectID: params.Get("project_id"),
+		Creator:   params.Get("creator"),
+	}
+	result, err := validRequest("create_repo", repoInfo)
+	if err != nil || !result.Valid() {
+		writeValidationError(result, err, w, logFields)
+		return
+	}
+	repoInfo.Normalise()
+	logger = logger.WithFields(logFields)
+
+	// The upload is stored on disk, so it must not be allowed to fill it up.
+	var dump io.Reader = &limitedReader{r.Body, h.maxDumpSize}
+	switch encoding := r.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(dump)
+		if err != nil {
+			logger.WithError(err).Warning("unable to decompress dump")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "unable to decompress dump: %s", err)
+			return
+		}
+		defer gz.Close()
+		dump = &limitedReader{gz, h.maxDumpSize}
+	default:
+		logger.WithField("content_encoding", encoding).Warning("unsupported content encoding")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		fmt.Fprintf(w, "unsupported content encoding %q", encoding)
+		return
+	}
+
+	// Refuse before the client uploads the entire dump.
+	if !h.checkRepoAbsent(w, repoInfo.RepoID, logger) {
+		return
+	}
+
+	upload, err := h.jobs.TempFile("load-" + repoInfo.RepoID + "-")
+	if err != nil {
+		logger.WithError(err).Error("unable to create temporary file for upload")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to store upload: %s", err)
+		return
+	}
+	size, err := io.Copy(upload, dump)
+	upload.Close()
+	if err == errDumpTooLarge {
+		logger.WithField("max_dump_size", h.maxDumpSize).Warning("dump too large")
+		os.Remove(upload.Name())
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "dump larger than %d bytes", h.maxDumpSize)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Warning("unable to receive dump")
+		os.Remove(upload.Name())
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "unable to receive dump: %s", err)
+		return
+	}
+	logger.WithField("size", size).Info("repository creation from dump requested")
+
+	jobLogFields := copyLogFields(logFields)
+	job, err := h.jobs.Submit("load", repoInfo.RepoID, "", func(ctx context.Context, run *jobs.Run) error {
+		defer os.Remove(upload.Name())
+		jobLogFields["job_id"] = run.ID
+
+		dumpFile, err := os.Open(upload.Name())
+		if err != nil {
+			return err
+		}
+		defer dumpFile.Close()
+
+		return h.svn.LoadRepo(ctx, repoInfo, run.Reader(dumpFile, size), nil, jobLogFields)
+	})
+	if err != nil {
+		os.Remove(upload.Name())
+	}
+	h.replyJobSubmitted(w, job, err, logger)
+}
+
+// checkRepoAbsent returns true when the repository does not exist yet.
+// Otherwise it returns false, after sending an error to the client.
+func (h *APIHandler) checkRepoAbsent(w http.ResponseWriter, repoID string, logger *log.Entry) bool {
+	_, err := h.svn.GetUsernames(repoID)
+	switch err {
+	case svnman.ErrNotFound:
+		return true
+	case nil:
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "repository %q already exists", repoID)
+	default:
+		logger.WithError(err).Error("unable to check for existing repository")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to check for existing repository: %s", err)
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package httphandler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+const testLoadQuery = "?project_id=59eefa9cf488554678cae036&creator=me+%3Cme%40example.com%3E"
+
+var testLoadRepo = svnman.CreateRepo{
+	RepoID:    "1234",
+	ProjectID: "59eefa9cf488554678cae036",
+	Creator:   "me <me@example.com>",
+}
+
+func (s *HTTPHandlerTestSuite) loadRepo(c *check.C, url string, body io.Reader, encoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", url, body)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	return respRec
+}
+
+// loadDump mocks LoadRepo by checking the dump.
+func loadDump(ctx context.Context, repoInfo svnman.CreateRepo, dump io.Reader,
+	progress svnman.LoadProgressFunc, logFields log.Fields) error {
+	contents, err := ioutil.ReadAll(dump)
+	if err != nil {
+		return err
+	}
+	if string(contents) != "dump" {
+		return svnman.ErrLoad
+	}
+	return nil
+}
+
+// submitLoad submits a load job, and waits for it to finish.
+func (s *HTTPHandlerTestSuite) submitLoad(c *check.C, body io.Reader, encoding string) jobs.Job {
+	job := jobs.Job{}
+	respRec := s.loadRepo(c, "/unittests/repo/1234/load"+testLoadQuery, body, encoding)
+	parseJSON(c, respRec, http.StatusAccepted, &job)
+	assert.Equal(c, "/unittests/jobs/"+job.ID, respRec.Header().Get("Location"))
+	assert.Equal(c, "load", job.Type)
+	assert.Equal(c, "1234", job.RepoID)
+	return s.waitForJob(c, job.ID)
+}
+
+func (s *HTTPHandlerTestSuite) TestLoadRepo(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().GetUsernames("1234").Times(2).Return(nil, svnman.ErrNotFound)
+	mockSVN.EXPECT().LoadRepo(gomock.Any(), testLoadRepo, gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(2).DoAndReturn(loadDump)
+
+	job := s.submitLoad(c, strings.NewReader("dump"), "")
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+	assert.Equal(c, jobs.Progress{Done: 4, Total: 4, Unit: "bytes"}, job.Progress)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("dump"))
+	gz.Close()
+
+	job = s.submitLoad(c, &compressed, "gzip")
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+}
+
+func (s *HTTPHandlerTestSuite) TestLoadRepoFailingJob(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return(nil, svnman.ErrNotFound)
+	mockSVN.EXPECT().LoadRepo(gomock.Any(), testLoadRepo, gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).DoAndReturn(loadDump)
+
+	job := s.submitLoad(c, strings.NewReader("not a dump"), "")
+	assert.Equal(c, jobs.StatusFailed, job.Status)
+	assert.Equal(c, svnman.ErrLoad.Error(), job.Error)
+}
+
+func (s *HTTPHandlerTestSuite) TestLoadRepoUnhappy(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{}, nil)
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return(nil, errors.New("something unexpected"))
+
+	url := "/unittests/repo/1234/load" + testLoadQuery
+	respRec := s.loadRepo(c, url, strings.NewReader("dump"), "")
+	assert.Equal(c, http.StatusConflict, respRec.Code)
+
+	respRec = s.loadRepo(c, url, strings.NewReader("dump"), "")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+
+	// These should not reach SVNMan.
+	respRec = s.loadRepo(c, url, strings.NewReader("dump"), "br")
+	assert.Equal(c, http.StatusUnsupportedMediaType, respRec.Code)
+
+	respRec = s.loadRepo(c, url, strings.NewReader("not gzipped"), "gzip")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	respRec = s.loadRepo(c, "/unittests/repo/1234/load?creator=me", strings.NewReader("dump"), "")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestLoadRepoTooLarge(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	// The test suite allows dumps of up to 1024 bytes; these should not reach LoadRepo.
+	mockSVN.EXPECT().GetUsernames("1234").Times(2).Return(nil, svnman.ErrNotFound)
+
+	url := "/unittests/repo/1234/load" + testLoadQuery
+	respRec := s.loadRepo(c, url, bytes.NewReader(make([]byte, 1025)), "")
+	assert.Equal(c, http.StatusRequestEntityTooLarge, respRec.Code)
+
+	// Small when compressed, but not when decompressed.
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(make([]byte, 100000))
+	gz.Close()
+	respRec = s.loadRepo(c, url, &compressed, "gzip")
+	assert.Equal(c, http.StatusRequestEntityTooLarge, respRec.Code)
+
+	uploads, err := filepath.Glob(filepath.Join(s.jobsDir, "tmp", "load-*"))
+	assert.Nil(c, err)
+	assert.Empty(c, uploads, "uploads should have been removed")
+}
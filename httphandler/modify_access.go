@@ -19,7 +19,16 @@ func (h *APIHandler) modifyAccess(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.Info("going to modify access on repository")
-	if err := h.svn.ModifyAccess(repoID, mods, logFields); err != nil {
+	err := h.svn.ModifyAccess(repoID, mods, logFields)
+	switch err {
+	case nil:
+	case svnman.ErrNoPassword:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "unable to modify access: %s", err)
+	case svnman.ErrPasswordConflict:
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "unable to modify access: %s", err)
+	default:
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprintf(w, "unable to modify htpasswd: %s", err.Error())
 		logger.WithError(err).Error("unable to modify htpasswd")
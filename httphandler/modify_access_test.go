@@ -41,3 +41,37 @@ func (s *HTTPHandlerTestSuite) TestModifyAccessHappy(c *check.C) {
 	respRec := s.modifyAccess(c, "1234", payload)
 	assert.Equal(c, http.StatusOK, respRec.Code)
 }
+
+func (s *HTTPHandlerTestSuite) TestModifyAccessNoPassword(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	payload := svnman.ModifyAccess{
+		Grant: []svnman.ModifyAccessGrantEntry{svnman.ModifyAccessGrantEntry{
+			Username: "mysterioususer",
+			Access:   svnman.AccessRead,
+		}},
+	}
+
+	mockSVN.EXPECT().ModifyAccess("1234", payload, gomock.Any()).Times(1).Return(svnman.ErrNoPassword)
+
+	respRec := s.modifyAccess(c, "1234", payload)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestModifyAccessPasswordConflict(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	payload := svnman.ModifyAccess{
+		Grant: []svnman.ModifyAccessGrantEntry{svnman.ModifyAccessGrantEntry{
+			Username: "existinguser",
+			Password: "$2y$05$different",
+		}},
+	}
+
+	mockSVN.EXPECT().ModifyAccess("1234", payload, gomock.Any()).Times(1).Return(svnman.ErrPasswordConflict)
+
+	respRec := s.modifyAccess(c, "1234", payload)
+	assert.Equal(c, http.StatusConflict, respRec.Code)
+}
@@ -0,0 +1,43 @@
+package httphandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+// commitNotification is sent as JSON to /api/repo/{repo-id}/commits by the post-commit hook.
+type commitNotification struct {
+	Revision int `json:"revision"`
+}
+
+func (h *APIHandler) notifyCommit(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithField("repo_id", repoID)
+
+	notification := commitNotification{}
+	if err := decodeJSON(w, r, &notification, "commit_notification", logFields); err != nil {
+		return
+	}
+
+	logger.WithField("revision", notification.Revision).Debug("commit notification received")
+	err := h.svn.NotifyCommit(repoID, notification.Revision, logFields)
+	if err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	} else if err != nil {
+		logger.WithError(err).Error("unable to handle commit notification")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to handle commit notification: %s", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package httphandler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) notifyCommit(c *check.C, repoID string, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/unittests/repo/"+repoID+"/commits", bytes.NewReader([]byte(payload)))
+	req.Header.Set("Content-Type", "application/json")
+
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestNotifyCommit(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().NotifyCommit("1234", 47, gomock.Any()).Times(1)
+	mockSVN.EXPECT().NotifyCommit("12345", 1, gomock.Any()).Times(1).Return(svnman.ErrNotFound)
+
+	respRec := s.notifyCommit(c, "1234", `{"revision": 47}`)
+	assert.Equal(c, http.StatusNoContent, respRec.Code)
+
+	respRec = s.notifyCommit(c, "12345", `{"revision": 1}`)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+
+	respRec = s.notifyCommit(c, "1234", `{"revision": 0}`)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+
+	respRec = s.notifyCommit(c, "1234", `{"revision": "HEAD"}`)
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
@@ -0,0 +1,35 @@
+package httphandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+// submitPackJob starts packing the repository in the background.
+func (h *APIHandler) submitPackJob(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	if _, err := h.svn.GetUsernames(repoID); err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	}
+
+	logger.Info("repository pack requested")
+	jobLogFields := copyLogFields(logFields)
+	job, err := h.jobs.Submit("pack", repoID, "", func(ctx context.Context, run *jobs.Run) error {
+		jobLogFields["job_id"] = run.ID
+		return h.svn.PackRepo(ctx, repoID, jobLogFields)
+	})
+	h.replyJobSubmitted(w, job, err, logger)
+}
@@ -0,0 +1,37 @@
+package httphandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) TestSubmitPackJob(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{}, nil)
+	mockSVN.EXPECT().GetUsernames("12345").Times(1).Return(nil, svnman.ErrNotFound)
+	mockSVN.EXPECT().PackRepo(gomock.Any(), "1234", gomock.Any()).Times(1).Return(svnman.ErrPack)
+
+	job := jobs.Job{}
+	req, _ := http.NewRequest("POST", "/unittests/repo/1234/pack", nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	parseJSON(c, respRec, http.StatusAccepted, &job)
+	assert.Equal(c, "pack", job.Type)
+
+	job = s.waitForJob(c, job.ID)
+	assert.Equal(c, jobs.StatusFailed, job.Status)
+	assert.Equal(c, svnman.ErrPack.Error(), job.Error)
+
+	req, _ = http.NewRequest("POST", "/unittests/repo/12345/pack", nil)
+	respRec = httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+}
@@ -0,0 +1,77 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+)
+
+// UserRepos is sent as JSON response to /api/users/{username}/repos requests.
+type UserRepos struct {
+	Repos []svnman.UserRepo `json:"repos"`
+}
+
+// PasswordChange is sent as JSON response to /api/users/{username}/password requests.
+type PasswordChange struct {
+	Repos []string `json:"repos"` // IDs of the repositories the user has access to.
+}
+
+func (h *APIHandler) getUserRepos(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	username := getUsername(w, r, logFields)
+	if username == "" {
+		return
+	}
+	logger = logger.WithField("username", username)
+
+	repos, err := h.svn.GetUserRepos(username)
+	if err != nil {
+		logger.WithError(err).Error("unable to find repositories of user")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to find repositories of user: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(UserRepos{repos}); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
+
+func (h *APIHandler) setPassword(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	username := getUsername(w, r, logFields)
+	if username == "" {
+		return
+	}
+	logger = logger.WithField("username", username)
+
+	pw := svnman.SetPassword{}
+	if err := decodeJSON(w, r, &pw, "set_password", logFields); err != nil {
+		return
+	}
+
+	logger.Info("going to set password of user")
+	repoIDs, err := h.svn.SetPassword(username, pw, logFields)
+	if err != nil {
+		logger.WithError(err).Error("unable to set password")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to set password: %s", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(PasswordChange{repoIDs}); err != nil {
+		logger.WithError(err).Error("unable to encode JSON")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "unable to encode reply as JSON: %s", err)
+		return
+	}
+}
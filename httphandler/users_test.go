@@ -0,0 +1,88 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) setPassword(c *check.C, username string, payload interface{}) *httptest.ResponseRecorder {
+	body, err := json.Marshal(payload)
+	assert.Nil(c, err, "marshalling failed")
+
+	req, _ := http.NewRequest("POST", "/unittests/users/"+username+"/password", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+
+	return respRec
+}
+
+func (s *HTTPHandlerTestSuite) TestGetUserRepos(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	repos := []svnman.UserRepo{{RepoID: "1234", ProjectID: "59eefa9cf488554678cae036"}}
+	mockSVN.EXPECT().GetUserRepos("alice@example.com").Times(1).Return(repos, nil)
+	mockSVN.EXPECT().GetUserRepos("nobody").Times(1).Return([]svnman.UserRepo{}, nil)
+	mockSVN.EXPECT().GetUserRepos("alice@example.com").Times(1).Return(nil, errors.New("disk full"))
+
+	resp := UserRepos{}
+	respRec := s.get(c, "/unittests/users/alice@example.com/repos")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, repos, resp.Repos)
+
+	resp = UserRepos{}
+	respRec = s.get(c, "/unittests/users/nobody/repos")
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, []svnman.UserRepo{}, resp.Repos)
+
+	respRec = s.get(c, "/unittests/users/alice@example.com/repos")
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+
+	respRec = s.get(c, "/unittests/users/x/repos")
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestSetPassword(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	pw := svnman.SetPassword{Password: "$2y$10$abcdef"}
+	mockSVN.EXPECT().SetPassword("alice@example.com", pw, gomock.Any()).Times(1).Return([]string{"1234", "5678"}, nil)
+	mockSVN.EXPECT().SetPassword("alice@example.com", pw, gomock.Any()).Times(1).Return([]string{}, nil)
+	mockSVN.EXPECT().SetPassword("alice@example.com", pw, gomock.Any()).Times(1).Return(nil, errors.New("disk full"))
+
+	resp := PasswordChange{}
+	respRec := s.setPassword(c, "alice@example.com", pw)
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, []string{"1234", "5678"}, resp.Repos)
+
+	resp = PasswordChange{}
+	respRec = s.setPassword(c, "alice@example.com", pw)
+	parseJSON(c, respRec, http.StatusOK, &resp)
+	assert.Equal(c, []string{}, resp.Repos)
+
+	respRec = s.setPassword(c, "alice@example.com", pw)
+	assert.Equal(c, http.StatusInternalServerError, respRec.Code)
+}
+
+func (s *HTTPHandlerTestSuite) TestSetPasswordInvalid(c *check.C) {
+	mockCtrl, _ := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	respRec := s.setPassword(c, "alice@example.com", svnman.SetPassword{Password: "plain text"})
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+	respRec = s.setPassword(c, "alice@example.com", map[string]string{})
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+	respRec = s.setPassword(c, "al", svnman.SetPassword{Password: "$2y$10$abcdef"})
+	assert.Equal(c, http.StatusBadRequest, respRec.Code)
+}
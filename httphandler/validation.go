@@ -1,15 +1,20 @@
 package httphandler
 
 import (
-	"path/filepath"
 	"regexp"
 
-	"github.com/armadillica/svn-manager/filelocator"
+	"github.com/armadillica/svn-manager/jsonschemas"
 	"github.com/xeipuuv/gojsonschema"
 )
 
 var (
 	validRepoRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_\-]+[a-zA-Z0-9]$`)
+
+	// Same as the usernames in the modify_access JSON schema.
+	validUsernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._\-+@]{3,255}$`)
+
+	// Timestamps formatted as svnman.RFC3339fs.
+	validAtticTimestampRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}(Z|[+\-]\d{2})-\d{2}$`)
 )
 
 // ValidRepoID returns true iff the repoID is safe to use as SVN repository name/path/ID.
@@ -20,18 +25,17 @@ func ValidRepoID(repoID string) bool {
 	return validRepoRegexp.MatchString(repoID)
 }
 
+// ValidUsername returns true iff the username can be used in htpasswd and Apache group files.
+func ValidUsername(username string) bool {
+	return validUsernameRegexp.MatchString(username)
+}
+
+// ValidAtticTimestamp returns true iff the timestamp is safe to use to find a repository in the attic.
+func ValidAtticTimestamp(timestamp string) bool {
+	return validAtticTimestampRegexp.MatchString(timestamp)
+}
+
 // ValidRequest validates the given document against the given schema.
 func validRequest(schemaName string, document interface{}) (*gojsonschema.Result, error) {
-	filename, err := filelocator.FindFile(filepath.Join("json_schemas", schemaName+".json"))
-	if err != nil {
-		return nil, err
-	}
-
-	schemaLoader := gojsonschema.NewReferenceLoader("file://" + filename)
-	documentLoader := gojsonschema.NewGoLoader(document)
-	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
-	if err != nil {
-		return nil, err
-	}
-	return result, nil
+	return jsonschemas.Validate(schemaName, document)
 }
@@ -0,0 +1,44 @@
+package httphandler
+
+import (
+	"github.com/armadillica/svn-manager/svnman"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ValidationTestSuite) TestAuthzHappy(t *check.C) {
+	s.assertValidJSON(t, "authz", svnman.Authz{})
+	s.assertValidJSON(t, "authz", svnman.Authz{
+		Groups: map[string][]string{
+			"leads":  {"alice@example.com", "bob"},
+			"render": {"farm-01"},
+		},
+		Rules: []svnman.AuthzRule{
+			{Path: "/", Access: map[string]string{"*": "r", "@leads": "rw"}},
+			{Path: "/trunk/release", Access: map[string]string{"*": "", "@leads": "rw", "carol": "r"}},
+		},
+	})
+}
+
+func (s *ValidationTestSuite) TestAuthzUnhappy(t *check.C) {
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Groups: map[string][]string{"lead s": {"alice"}},
+	})
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Groups: map[string][]string{"leads": {"@others"}},
+	})
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "trunk", Access: map[string]string{"*": "r"}}},
+	})
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "/trunk]\n[/", Access: map[string]string{"*": "r"}}},
+	})
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "/", Access: map[string]string{"alice": "w"}}},
+	})
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "/", Access: map[string]string{"$authenticated": "r"}}},
+	})
+	s.assertInvalidJSON(t, "authz", svnman.Authz{
+		Rules: []svnman.AuthzRule{{Path: "/", Access: map[string]string{"alice = rw\nbob": "r"}}},
+	})
+}
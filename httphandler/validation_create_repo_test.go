@@ -115,3 +115,14 @@ func (s *ValidationTestSuite) testInvalidProjectID(t *check.C, projectID string)
 	doc.ProjectID = projectID
 	s.assertInvalidJSON(t, "create_repo", &doc)
 }
+
+func (s *ValidationTestSuite) TestValidAtticTimestamp(t *check.C) {
+	assert.True(t, ValidAtticTimestamp("2017-11-14T16-30-04Z-00"))
+	assert.True(t, ValidAtticTimestamp("2017-11-14T16-30-04+01-00"))
+	assert.True(t, ValidAtticTimestamp("2017-11-14T16-30-04-05-00"))
+
+	assert.False(t, ValidAtticTimestamp(""))
+	assert.False(t, ValidAtticTimestamp("2017-11-14T16:30:04Z"))
+	assert.False(t, ValidAtticTimestamp("2017-11-14T16-30-04Z-00/../../etc"))
+	assert.False(t, ValidAtticTimestamp("../2017-11-14T16-30-04Z-00"))
+}
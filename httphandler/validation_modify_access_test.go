@@ -59,6 +59,24 @@ func (s *ValidationTestSuite) TestGrantUnhappy(t *check.C) {
 	})
 }
 
+func (s *ValidationTestSuite) TestGrantAccessLevel(t *check.C) {
+	grant := func(access string) svnman.ModifyAccess {
+		return svnman.ModifyAccess{
+			Grant: []svnman.ModifyAccessGrantEntry{
+				svnman.ModifyAccessGrantEntry{
+					Username: "joey",
+					Password: "$2y$05$cWVQLHS58K7fIKjz3tU52eBI2sxbE3KdAfZN0CJN/DcRKGkYTKOuG",
+					Access:   access,
+				},
+			},
+		}
+	}
+	s.assertValidJSON(t, "modify_access", grant(svnman.AccessRead))
+	s.assertValidJSON(t, "modify_access", grant(svnman.AccessWrite))
+	s.assertInvalidJSON(t, "modify_access", grant("admin"))
+	s.assertInvalidJSON(t, "modify_access", grant("rw"))
+}
+
 func (s *ValidationTestSuite) TestRevokeHappy(t *check.C) {
 	s.assertValidJSON(t, "modify_access", validModifyRevokeAccess)
 }
@@ -0,0 +1,39 @@
+package httphandler
+
+import (
+	"github.com/armadillica/svn-manager/svnman"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ValidationTestSuite) TestModifyHooksHappy(t *check.C) {
+	s.assertValidJSON(t, "modify_hooks", svnman.ModifyHooks{})
+	s.assertValidJSON(t, "modify_hooks", svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{
+			svnman.InstalledHook{Name: "reject-empty-log-message"},
+			svnman.InstalledHook{Name: "max-file-size", Params: map[string]string{"max_size_mb": "40"}},
+		},
+		Remove: []string{"some-hook"},
+	})
+}
+
+func (s *ValidationTestSuite) TestModifyHooksUnhappy(t *check.C) {
+	s.assertInvalidJSON(t, "modify_hooks", svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{svnman.InstalledHook{Name: ""}},
+	})
+	s.assertInvalidJSON(t, "modify_hooks", svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{svnman.InstalledHook{Name: "../hook"}},
+	})
+	s.assertInvalidJSON(t, "modify_hooks", svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{svnman.InstalledHook{
+			Name:   "max-file-size",
+			Params: map[string]string{"Invalid Name": "40"},
+		}},
+	})
+	s.assertInvalidJSON(t, "modify_hooks", svnman.ModifyHooks{
+		Install: []svnman.InstalledHook{svnman.InstalledHook{
+			Name:   "max-file-size",
+			Params: map[string]string{"max_size_mb": "40\nrm -rf /"},
+		}},
+	})
+	s.assertInvalidJSON(t, "modify_hooks", svnman.ModifyHooks{Remove: []string{"some/hook"}})
+}
@@ -0,0 +1,32 @@
+package httphandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/armadillica/svn-manager/verifier"
+)
+
+// submitVerifyJob starts verification of the repository in the background.
+// The svnman.VerifyResult is available as the job result when it has finished.
+func (h *APIHandler) submitVerifyJob(w http.ResponseWriter, r *http.Request) {
+	logFields, logger := logFieldsForRequest(r)
+	repoID := getRepoID(w, r, logFields)
+	if repoID == "" {
+		return
+	}
+	logger = logger.WithFields(logFields)
+
+	if _, err := h.svn.GetUsernames(repoID); err == svnman.ErrNotFound {
+		logger.Warning("nonexistent repository requested")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "nonexistent repository requested")
+		return
+	}
+
+	logger.Info("repository verification requested")
+	job, err := h.jobs.Submit(verifier.JobType, repoID, "",
+		verifier.JobFunc(h.svn, repoID, copyLogFields(logFields)))
+	h.replyJobSubmitted(w, job, err, logger)
+}
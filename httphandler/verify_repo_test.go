@@ -0,0 +1,54 @@
+package httphandler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *HTTPHandlerTestSuite) TestSubmitVerifyJob(c *check.C) {
+	mockCtrl, mockSVN := s.mockSVN(c)
+	defer mockCtrl.Finish()
+
+	failures := []svnman.VerifyFailure{{Revision: 3, Errors: []string{"E160004: Corrupt node-revision '0.0.r3/17'"}}}
+	mockSVN.EXPECT().GetUsernames("1234").Times(1).Return([]string{}, nil)
+	mockSVN.EXPECT().GetUsernames("12345").Times(1).Return(nil, svnman.ErrNotFound)
+	mockSVN.EXPECT().VerifyRepo(gomock.Any(), "1234", gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+		func(ctx context.Context, repoID string, progress svnman.VerifyProgressFunc,
+			logFields log.Fields) (svnman.VerifyResult, error) {
+			progress(4, 4)
+			return svnman.VerifyResult{HeadRevision: 4, Failures: failures}, nil
+		})
+
+	job := jobs.Job{}
+	req, _ := http.NewRequest("POST", "/unittests/repo/1234/verify", nil)
+	respRec := httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	parseJSON(c, respRec, http.StatusAccepted, &job)
+	assert.Equal(c, "verify", job.Type)
+
+	job = s.waitForJob(c, job.ID)
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+	assert.Equal(c, jobs.Progress{Done: 5, Total: 5, Unit: "revisions"}, job.Progress)
+
+	// The result should be part of the job JSON.
+	respRec = s.get(c, "/unittests/jobs/"+job.ID)
+	reply := struct {
+		Result svnman.VerifyResult `json:"result"`
+	}{}
+	parseJSON(c, respRec, http.StatusOK, &reply)
+	assert.False(c, reply.Result.Success)
+	assert.Equal(c, failures, reply.Result.Failures)
+
+	req, _ = http.NewRequest("POST", "/unittests/repo/12345/verify", nil)
+	respRec = httptest.NewRecorder()
+	s.route.ServeHTTP(respRec, req)
+	assert.Equal(c, http.StatusNotFound, respRec.Code)
+}
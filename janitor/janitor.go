@@ -0,0 +1,156 @@
+// Package janitor periodically purges repositories that have been in the attic for too long.
+package janitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+// How often the attic is inspected. The first run is done right after Go() is called.
+const checkInterval = 1 * time.Hour
+
+// Janitor enforces the attic retention policy in a background goroutine.
+type Janitor struct {
+	svn       svnman.Manager
+	retention time.Duration
+	dryRun    bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// summary describes the outcome of a single janitor run.
+type summary struct {
+	examined int
+	expired  int
+	purged   int
+	failed   int
+	freed    int64 // in bytes
+}
+
+// Create returns a Janitor that purges attic entries older than the retention period.
+// In dry-run mode, it only logs what it would purge. Call Go() to actually start it.
+func Create(svn svnman.Manager, retention time.Duration, dryRun bool) *Janitor {
+	log.WithFields(log.Fields{
+		"retention": retention,
+		"dry_run":   dryRun,
+	}).Info("creating attic janitor")
+
+	return &Janitor{
+		svn:       svn,
+		retention: retention,
+		dryRun:    dryRun,
+		done:      make(chan struct{}),
+	}
+}
+
+// Go starts the janitor in a background goroutine.
+func (j *Janitor) Go() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			j.run(time.Now())
+
+			select {
+			case <-j.done:
+				log.Info("attic janitor stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the janitor, and waits for the current attic entry to be handled.
+func (j *Janitor) Close() {
+	log.Info("shutting down attic janitor")
+	close(j.done)
+	j.wg.Wait()
+}
+
+// closing returns true when Close() has been called.
+func (j *Janitor) closing() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// run purges the attic entries that were deleted before now-retention, and logs a summary.
+func (j *Janitor) run(now time.Time) summary {
+	logger := log.WithFields(log.Fields{
+		"retention": j.retention,
+		"dry_run":   j.dryRun,
+	})
+	logger.Debug("attic janitor inspecting attic")
+
+	result := summary{}
+	entries, err := j.svn.ListAttic("")
+	if err != nil {
+		logger.WithError(err).Error("attic janitor unable to list attic")
+		return result
+	}
+
+	threshold := now.Add(-j.retention)
+	for _, entry := range entries {
+		if j.closing() {
+			logger.Info("attic janitor interrupted by shutdown")
+			break
+		}
+
+		result.examined++
+		if !entry.DeletedOn.Before(threshold) {
+			continue
+		}
+		result.expired++
+
+		entryLogger := logger.WithFields(log.Fields{
+			"repo_id":    entry.RepoID,
+			"timestamp":  entry.Timestamp,
+			"project_id": entry.ProjectID,
+			"disk_size":  entry.DiskSize,
+		})
+		if j.dryRun {
+			entryLogger.Info("attic janitor would purge repository (dry run)")
+			result.freed += entry.DiskSize
+			continue
+		}
+
+		logFields := log.Fields{
+			"repo_id":   entry.RepoID,
+			"timestamp": entry.Timestamp,
+			"in":        "janitor",
+		}
+		if err := j.svn.PurgeAtticEntry(entry.RepoID, entry.Timestamp, logFields); err != nil {
+			entryLogger.WithError(err).Error("attic janitor unable to purge repository")
+			result.failed++
+			continue
+		}
+		result.purged++
+		result.freed += entry.DiskSize
+	}
+
+	summaryLogger := logger.WithFields(log.Fields{
+		"examined":    result.examined,
+		"expired":     result.expired,
+		"purged":      result.purged,
+		"failed":      result.failed,
+		"freed_bytes": result.freed,
+	})
+	if result.failed > 0 {
+		summaryLogger.Warning("attic janitor finished with errors")
+	} else {
+		summaryLogger.Info("attic janitor finished")
+	}
+	return result
+}
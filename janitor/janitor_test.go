@@ -0,0 +1,95 @@
+package janitor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type JanitorTestSuite struct {
+	now     time.Time
+	entries []svnman.AtticEntry
+}
+
+var _ = check.Suite(&JanitorTestSuite{})
+
+func (s *JanitorTestSuite) SetUpTest(c *check.C) {
+	s.now = time.Date(2017, 12, 1, 12, 0, 0, 0, time.UTC)
+	s.entries = []svnman.AtticEntry{
+		svnman.AtticEntry{
+			RepoID:    "old-repo",
+			Timestamp: "2017-08-01T12-00-00Z-00",
+			DeletedOn: time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC),
+			DiskSize:  1000,
+		},
+		svnman.AtticEntry{
+			RepoID:    "older-repo",
+			Timestamp: "2017-07-01T12-00-00Z-00",
+			DeletedOn: time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC),
+			DiskSize:  300,
+		},
+		svnman.AtticEntry{
+			RepoID:    "recent-repo",
+			Timestamp: "2017-11-14T16-30-04Z-00",
+			DeletedOn: time.Date(2017, 11, 14, 16, 30, 4, 0, time.UTC),
+			DiskSize:  47,
+		},
+	}
+}
+
+func (s *JanitorTestSuite) janitor(c *check.C, dryRun bool) (*gomock.Controller, *svnman.MockManager, *Janitor) {
+	mockCtrl := gomock.NewController(c)
+	mockSVN := svnman.NewMockManager(mockCtrl)
+	return mockCtrl, mockSVN, Create(mockSVN, 90*24*time.Hour, dryRun)
+}
+
+func (s *JanitorTestSuite) TestPurgeExpired(c *check.C) {
+	mockCtrl, mockSVN, janitor := s.janitor(c, false)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().ListAttic("").Times(1).Return(s.entries, nil)
+	mockSVN.EXPECT().PurgeAtticEntry("old-repo", "2017-08-01T12-00-00Z-00", gomock.Any()).Times(1)
+	mockSVN.EXPECT().PurgeAtticEntry("older-repo", "2017-07-01T12-00-00Z-00", gomock.Any()).Times(1).
+		Return(svnman.ErrPurge)
+
+	result := janitor.run(s.now)
+	assert.Equal(c, summary{examined: 3, expired: 2, purged: 1, failed: 1, freed: 1000}, result)
+}
+
+func (s *JanitorTestSuite) TestDryRun(c *check.C) {
+	mockCtrl, mockSVN, janitor := s.janitor(c, true)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().ListAttic("").Times(1).Return(s.entries, nil)
+	mockSVN.EXPECT().PurgeAtticEntry(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	result := janitor.run(s.now)
+	assert.Equal(c, summary{examined: 3, expired: 2, freed: 1300}, result)
+}
+
+func (s *JanitorTestSuite) TestListAtticError(c *check.C) {
+	mockCtrl, mockSVN, janitor := s.janitor(c, false)
+	defer mockCtrl.Finish()
+
+	mockSVN.EXPECT().ListAttic("").Times(1).Return(nil, errors.New("something unexpected"))
+
+	result := janitor.run(s.now)
+	assert.Equal(c, summary{}, result)
+}
+
+func (s *JanitorTestSuite) TestClose(c *check.C) {
+	mockCtrl, mockSVN, janitor := s.janitor(c, false)
+	defer mockCtrl.Finish()
+
+	// Entries should not be purged after Close() has been called.
+	mockSVN.EXPECT().ListAttic("").AnyTimes().Return(s.entries, nil)
+	mockSVN.EXPECT().PurgeAtticEntry(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	close(janitor.done)
+	result := janitor.run(s.now)
+	assert.Equal(c, summary{}, result)
+}
@@ -0,0 +1,476 @@
+// Package jobs runs long-running repository operations in the background.
+//
+// Job state is persisted as JSON in the state directory, so that it can still be
+// queried after a restart. Jobs that were still queued or running when SVN Manager
+// stopped cannot be resumed, and are marked as cancelled at startup.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotFound is returned when the requested job does not exist.
+	ErrNotFound = errors.New("job with this ID does not exist")
+	// ErrQueueFull is returned when a job cannot be submitted because too many are waiting already.
+	ErrQueueFull = errors.New("too many jobs queued")
+	// ErrClosed is returned when a job is submitted after Close() was called.
+	ErrClosed = errors.New("job manager has been closed")
+)
+
+// Status of a job.
+type Status string
+
+// Possible job statuses.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Finished returns true when the job will not change status any more.
+func (s Status) Finished() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCancelled
+}
+
+// expired returns true when the job finished longer than the retention ago.
+func (job *Job) expired(now time.Time) bool {
+	return job.Status.Finished() && job.FinishedOn != nil && now.Sub(*job.FinishedOn) > retention
+}
+
+const (
+	// Maximum number of jobs waiting for a worker.
+	queueSize = 100
+	// Finished jobs are forgotten after this time.
+	retention = 7 * 24 * time.Hour
+	// Finished jobs are checked for being older than the retention this often.
+	pruneInterval = time.Hour
+	// Progress is saved to disk at most this often.
+	persistInterval = 5 * time.Second
+)
+
+// Progress describes how far along a job is.
+type Progress struct {
+	Done  int64  `json:"done"`
+	Total int64  `json:"total,omitempty"` // zero when unknown.
+	Unit  string `json:"unit,omitempty"`  // like "revisions" or "bytes".
+}
+
+// Job is the state of a job, as persisted and sent as JSON response to /api/jobs/{job-id} requests.
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"` // like "dump" or "load".
+	RepoID     string          `json:"repo_id"`
+	Status     Status          `json:"status"`
+	Progress   Progress        `json:"progress"`
+	Error      string          `json:"error,omitempty"`
+	OutputName string          `json:"output_name,omitempty"` // filename for downloading the output.
+	Result     json.RawMessage `json:"result,omitempty"`      // job-specific outcome, like a verification result.
+	CreatedOn  time.Time       `json:"created_on"`
+	StartedOn  *time.Time      `json:"started_on,omitempty"`
+	FinishedOn *time.Time      `json:"finished_on,omitempty"`
+}
+
+// Func does the actual work of a job. It should return quickly when the context is cancelled.
+type Func func(ctx context.Context, run *Run) error
+
+// Run gives a running job access to its output file and progress reporting.
+type Run struct {
+	ID         string
+	OutputPath string // where the job should write its output, if it has any.
+
+	manager *Manager
+}
+
+// Progress updates the progress of the job.
+func (r *Run) Progress(done, total int64, unit string) {
+	r.manager.update(r.ID, false, func(job *Job) {
+		job.Progress = Progress{Done: done, Total: total, Unit: unit}
+	})
+}
+
+// SetResult stores the job-specific outcome, which is included in the job state as JSON.
+func (r *Run) SetResult(result interface{}) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	r.manager.update(r.ID, true, func(job *Job) {
+		job.Result = resultJSON
+	})
+	return nil
+}
+
+type queuedJob struct {
+	id string
+	fn Func
+}
+
+// Manager keeps track of jobs, and runs them with a bounded number of workers.
+type Manager struct {
+	stateDir string
+
+	mutex       sync.Mutex
+	jobs        map[string]*Job
+	lastPersist map[string]time.Time
+	closed      bool
+
+	queue  chan queuedJob
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Create returns a Manager that persists job state in stateDir, and runs up to
+// 'workers' jobs concurrently. Job state from a previous run is loaded.
+func Create(stateDir string, workers int) (*Manager, error) {
+	logger := log.WithFields(log.Fields{
+		"state_dir": stateDir,
+		"workers":   workers,
+	})
+
+	if err := os.MkdirAll(stateDir, 0750); err != nil {
+		logger.WithError(err).Error("unable to create job state directory")
+		return nil, err
+	}
+	// Temporary files cannot be used by anything after a restart.
+	tempDir := filepath.Join(stateDir, "tmp")
+	if err := os.RemoveAll(tempDir); err != nil {
+		logger.WithError(err).Error("unable to clean temporary directory")
+		return nil, err
+	}
+	if err := os.MkdirAll(tempDir, 0750); err != nil {
+		logger.WithError(err).Error("unable to create temporary directory")
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		stateDir:    stateDir,
+		jobs:        map[string]*Job{},
+		lastPersist: map[string]time.Time{},
+		queue:       make(chan queuedJob, queueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	if err := m.loadState(); err != nil {
+		logger.WithError(err).Error("unable to load job state")
+		cancel()
+		return nil, err
+	}
+
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	m.wg.Add(1)
+	go m.pruner()
+
+	logger.WithField("jobs", len(m.jobs)).Info("job manager started")
+	return m, nil
+}
+
+func (m *Manager) statePath(jobID string) string {
+	return filepath.Join(m.stateDir, jobID+".json")
+}
+
+// OutputPath returns the path of the job's output file.
+func (m *Manager) OutputPath(jobID string) string {
+	return filepath.Join(m.stateDir, jobID+".output")
+}
+
+// TempFile creates a temporary file for use by a job, for example to store an upload.
+// The job should remove it when done; any leftovers are removed at the next startup.
+func (m *Manager) TempFile(prefix string) (*os.File, error) {
+	return ioutil.TempFile(filepath.Join(m.stateDir, "tmp"), prefix)
+}
+
+// loadState loads the persisted jobs, cancelling the unfinished and forgetting the old ones.
+func (m *Manager) loadState() error {
+	found, err := filepath.Glob(filepath.Join(m.stateDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, statePath := range found {
+		logger := log.WithField("state_file", statePath)
+
+		contents, err := ioutil.ReadFile(statePath)
+		if err != nil {
+			return err
+		}
+		job := Job{}
+		if err := json.Unmarshal(contents, &job); err != nil {
+			logger.WithError(err).Warning("ignoring invalid job state file")
+			continue
+		}
+		logger = logger.WithField("job_id", job.ID)
+
+		if job.expired(now) {
+			logger.Debug("forgetting old job")
+			os.Remove(m.OutputPath(job.ID))
+			os.Remove(statePath)
+			continue
+		}
+
+		if !job.Status.Finished() {
+			logger.WithField("status", job.Status).Warning("job was interrupted by a restart")
+			job.Status = StatusCancelled
+			job.Error = "interrupted by restart of SVN Manager"
+			job.FinishedOn = &now
+			os.Remove(m.OutputPath(job.ID))
+			if err := m.persist(&job); err != nil {
+				return err
+			}
+		}
+		m.jobs[job.ID] = &job
+	}
+	return nil
+}
+
+// pruner periodically forgets old jobs, until the manager is closed.
+func (m *Manager) pruner() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.prune(time.Now().UTC())
+		}
+	}
+}
+
+// prune forgets the jobs that finished longer than the retention ago, and removes their files.
+func (m *Manager) prune(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for jobID, job := range m.jobs {
+		if !job.expired(now) {
+			continue
+		}
+		log.WithField("job_id", jobID).Debug("forgetting old job")
+		os.Remove(m.OutputPath(jobID))
+		os.Remove(m.statePath(jobID))
+		delete(m.jobs, jobID)
+		delete(m.lastPersist, jobID)
+	}
+}
+
+// persist writes the job state to disk. The caller must hold the mutex or have sole access to the job.
+func (m *Manager) persist(job *Job) error {
+	contents, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that a crash cannot leave a half-written file.
+	statePath := m.statePath(job.ID)
+	if err := ioutil.WriteFile(statePath+"~", contents, 0640); err != nil {
+		return err
+	}
+	if err := os.Rename(statePath+"~", statePath); err != nil {
+		return err
+	}
+	m.lastPersist[job.ID] = time.Now()
+	return nil
+}
+
+// update modifies the job while holding the mutex, and persists it. Unless force is true,
+// persisting is skipped when the job was persisted recently.
+func (m *Manager) update(jobID string, force bool, modify func(job *Job)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[jobID]
+	if !ok {
+		return
+	}
+	modify(job)
+
+	if !force && time.Since(m.lastPersist[jobID]) < persistInterval {
+		return
+	}
+	if err := m.persist(job); err != nil {
+		log.WithField("job_id", jobID).WithError(err).Error("unable to persist job state")
+	}
+}
+
+// newJobID returns a random job ID.
+func newJobID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// Submit queues a job to run on the given repository. When the job produces output
+// that can be downloaded, outputName is used as the filename for the download.
+func (m *Manager) Submit(jobType, repoID, outputName string, fn Func) (Job, error) {
+	jobID, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	logger := log.WithFields(log.Fields{
+		"job_id":   jobID,
+		"job_type": jobType,
+		"repo_id":  repoID,
+	})
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return Job{}, ErrClosed
+	}
+
+	job := &Job{
+		ID:         jobID,
+		Type:       jobType,
+		RepoID:     repoID,
+		Status:     StatusQueued,
+		OutputName: outputName,
+		CreatedOn:  time.Now().UTC(),
+	}
+
+	select {
+	case m.queue <- queuedJob{jobID, fn}:
+	default:
+		logger.Warning("job queue is full")
+		return Job{}, ErrQueueFull
+	}
+
+	m.jobs[jobID] = job
+	if err := m.persist(job); err != nil {
+		logger.WithError(err).Error("unable to persist job state")
+	}
+	logger.Info("job queued")
+	return *job, nil
+}
+
+// Get returns the current state of the job.
+func (m *Manager) Get(jobID string) (Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[jobID]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case queued := <-m.queue:
+			m.run(queued)
+		}
+	}
+}
+
+// run runs a single job, and records its outcome.
+func (m *Manager) run(queued queuedJob) {
+	logger := log.WithField("job_id", queued.id)
+
+	// Don't start anything new when we're shutting down.
+	if m.ctx.Err() != nil {
+		m.finish(queued.id, m.ctx.Err())
+		return
+	}
+
+	m.update(queued.id, true, func(job *Job) {
+		now := time.Now().UTC()
+		job.Status = StatusRunning
+		job.StartedOn = &now
+	})
+	logger.Info("job started")
+
+	run := &Run{
+		ID:         queued.id,
+		OutputPath: m.OutputPath(queued.id),
+		manager:    m,
+	}
+	err := queued.fn(m.ctx, run)
+	// Cancelled commands fail with errors of their own, like "signal: killed".
+	if m.ctx.Err() != nil {
+		err = m.ctx.Err()
+	}
+	m.finish(queued.id, err)
+}
+
+// finish records the outcome of the job.
+func (m *Manager) finish(jobID string, err error) {
+	logger := log.WithField("job_id", jobID)
+
+	m.update(jobID, true, func(job *Job) {
+		now := time.Now().UTC()
+		job.FinishedOn = &now
+		switch {
+		case err == nil:
+			job.Status = StatusCompleted
+		case err == context.Canceled:
+			job.Status = StatusCancelled
+			job.Error = "cancelled by shutdown of SVN Manager"
+		default:
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+	})
+
+	switch {
+	case err == nil:
+		logger.Info("job completed")
+	case err == context.Canceled:
+		logger.Warning("job cancelled")
+		os.Remove(m.OutputPath(jobID))
+	default:
+		logger.WithError(err).Warning("job failed")
+		os.Remove(m.OutputPath(jobID))
+	}
+}
+
+// Close cancels running jobs and waits for them to stop. Jobs still in the queue are cancelled.
+func (m *Manager) Close() {
+	log.Info("shutting down job manager")
+
+	m.mutex.Lock()
+	m.closed = true
+	m.mutex.Unlock()
+
+	m.cancel()
+	m.wg.Wait()
+
+	// Nothing can be added to the queue any more, so we can safely drain it.
+	for {
+		select {
+		case queued := <-m.queue:
+			m.finish(queued.id, context.Canceled)
+		default:
+			log.Info("job manager stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type JobsTestSuite struct {
+	stateDir string
+	manager  *Manager
+}
+
+var _ = check.Suite(&JobsTestSuite{})
+
+func (s *JobsTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.stateDir, err = ioutil.TempDir("", "jobs")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	s.manager, err = Create(s.stateDir, 1)
+	if err != nil {
+		c.Fatalf("unable to create job manager: %s", err)
+	}
+}
+
+func (s *JobsTestSuite) TearDownTest(c *check.C) {
+	s.manager.Close()
+	os.RemoveAll(s.stateDir)
+}
+
+// waitForJob waits until the job has finished, and returns its final state.
+func waitForJob(c *check.C, manager *Manager, jobID string) Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := manager.Get(jobID)
+		if err != nil {
+			c.Fatalf("unable to get job %s: %s", jobID, err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("job %s did not finish in time", jobID)
+	return Job{}
+}
+
+func (s *JobsTestSuite) TestSubmitHappy(c *check.C) {
+	job, err := s.manager.Submit("test", "1234", "output.txt", func(ctx context.Context, run *Run) error {
+		run.Progress(47, 100, "revisions")
+		return ioutil.WriteFile(run.OutputPath, []byte("output"), 0644)
+	})
+	assert.Nil(c, err)
+	assert.Equal(c, "test", job.Type)
+	assert.Equal(c, "1234", job.RepoID)
+	assert.Equal(c, 32, len(job.ID))
+
+	job = waitForJob(c, s.manager, job.ID)
+	assert.Equal(c, StatusCompleted, job.Status)
+	assert.Equal(c, Progress{47, 100, "revisions"}, job.Progress)
+	assert.Equal(c, "", job.Error)
+	assert.NotNil(c, job.StartedOn)
+	assert.NotNil(c, job.FinishedOn)
+
+	output, err := ioutil.ReadFile(s.manager.OutputPath(job.ID))
+	assert.Nil(c, err)
+	assert.Equal(c, "output", string(output))
+
+	_, err = s.manager.Get("nonexistent")
+	assert.Equal(c, ErrNotFound, err)
+}
+
+func (s *JobsTestSuite) TestSubmitFailing(c *check.C) {
+	job, err := s.manager.Submit("test", "1234", "output.txt", func(ctx context.Context, run *Run) error {
+		ioutil.WriteFile(run.OutputPath, []byte("partial output"), 0644)
+		return errors.New("something went wrong")
+	})
+	assert.Nil(c, err)
+
+	job = waitForJob(c, s.manager, job.ID)
+	assert.Equal(c, StatusFailed, job.Status)
+	assert.Equal(c, "something went wrong", job.Error)
+
+	_, err = os.Stat(s.manager.OutputPath(job.ID))
+	assert.True(c, os.IsNotExist(err), "partial output should have been removed")
+}
+
+func (s *JobsTestSuite) TestSetResult(c *check.C) {
+	job, err := s.manager.Submit("test", "1234", "", func(ctx context.Context, run *Run) error {
+		return run.SetResult(map[string]bool{"success": true})
+	})
+	assert.Nil(c, err)
+
+	job = waitForJob(c, s.manager, job.ID)
+	assert.Equal(c, StatusCompleted, job.Status)
+	assert.JSONEq(c, `{"success": true}`, string(job.Result))
+}
+
+func (s *JobsTestSuite) TestProgressReaderWriter(c *check.C) {
+	job, err := s.manager.Submit("test", "1234", "", func(ctx context.Context, run *Run) error {
+		contents, err := ioutil.ReadAll(run.Reader(strings.NewReader("0123456789"), 10))
+		if err != nil {
+			return err
+		}
+		_, err = run.Writer(ioutil.Discard).Write(contents[:4])
+		return err
+	})
+	assert.Nil(c, err)
+
+	job = waitForJob(c, s.manager, job.ID)
+	assert.Equal(c, StatusCompleted, job.Status)
+	assert.Equal(c, Progress{4, 0, "bytes"}, job.Progress)
+}
+
+func (s *JobsTestSuite) TestCloseCancelsJobs(c *check.C) {
+	started := make(chan struct{})
+	running, err := s.manager.Submit("test", "1234", "", func(ctx context.Context, run *Run) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.Nil(c, err)
+	queued, err := s.manager.Submit("test", "5678", "", func(ctx context.Context, run *Run) error {
+		c.Error("queued job should not have been started")
+		return nil
+	})
+	assert.Nil(c, err)
+
+	<-started
+	s.manager.Close()
+
+	job, err := s.manager.Get(running.ID)
+	assert.Nil(c, err)
+	assert.Equal(c, StatusCancelled, job.Status)
+	job, err = s.manager.Get(queued.ID)
+	assert.Nil(c, err)
+	assert.Equal(c, StatusCancelled, job.Status)
+
+	_, err = s.manager.Submit("test", "1234", "", nil)
+	assert.Equal(c, ErrClosed, err)
+
+	// A new manager should know about the jobs.
+	s.manager, err = Create(s.stateDir, 1)
+	assert.Nil(c, err)
+	job, err = s.manager.Get(running.ID)
+	assert.Nil(c, err)
+	assert.Equal(c, StatusCancelled, job.Status)
+}
+
+func (s *JobsTestSuite) TestCloseCancelsJobsWithOtherErrors(c *check.C) {
+	started := make(chan struct{})
+	running, err := s.manager.Submit("test", "1234", "", func(ctx context.Context, run *Run) error {
+		close(started)
+		<-ctx.Done()
+		return errors.New("signal: killed")
+	})
+	assert.Nil(c, err)
+
+	<-started
+	s.manager.Close()
+
+	job, err := s.manager.Get(running.ID)
+	assert.Nil(c, err)
+	assert.Equal(c, StatusCancelled, job.Status)
+}
+
+func (s *JobsTestSuite) TestRestartCancelsUnfinishedJobs(c *check.C) {
+	s.manager.Close()
+
+	// Simulate a crash, leaving a running job, an old job, and a temporary file behind.
+	now := time.Now().UTC()
+	old := now.Add(-8 * 24 * time.Hour)
+	s.manager.persist(&Job{ID: "running", Status: StatusRunning, CreatedOn: now})
+	s.manager.persist(&Job{ID: "old", Status: StatusCompleted, CreatedOn: old, FinishedOn: &old})
+	ioutil.WriteFile(s.manager.OutputPath("old"), []byte("output"), 0644)
+	upload, err := s.manager.TempFile("upload-")
+	assert.Nil(c, err)
+	upload.Close()
+
+	s.manager, err = Create(s.stateDir, 1)
+	assert.Nil(c, err)
+
+	job, err := s.manager.Get("running")
+	assert.Nil(c, err)
+	assert.Equal(c, StatusCancelled, job.Status)
+	assert.NotEqual(c, "", job.Error)
+
+	_, err = s.manager.Get("old")
+	assert.Equal(c, ErrNotFound, err)
+	_, err = os.Stat(s.manager.OutputPath("old"))
+	assert.True(c, os.IsNotExist(err), "output of old job should have been removed")
+	_, err = os.Stat(upload.Name())
+	assert.True(c, os.IsNotExist(err), "temporary file should have been removed")
+
+	leftovers, err := filepath.Glob(filepath.Join(s.stateDir, "*~"))
+	assert.Nil(c, err)
+	assert.Empty(c, leftovers)
+}
+
+func (s *JobsTestSuite) TestPruneOldJobs(c *check.C) {
+	job, err := s.manager.Submit("test", "1234", "output.txt", func(ctx context.Context, run *Run) error {
+		return ioutil.WriteFile(run.OutputPath, []byte("output"), 0644)
+	})
+	assert.Nil(c, err)
+	job = waitForJob(c, s.manager, job.ID)
+
+	// Recent jobs are kept.
+	s.manager.prune(job.FinishedOn.Add(24 * time.Hour))
+	_, err = s.manager.Get(job.ID)
+	assert.Nil(c, err)
+
+	s.manager.prune(job.FinishedOn.Add(8 * 24 * time.Hour))
+	_, err = s.manager.Get(job.ID)
+	assert.Equal(c, ErrNotFound, err)
+	_, err = os.Stat(s.manager.OutputPath(job.ID))
+	assert.True(c, os.IsNotExist(err), "output of old job should have been removed")
+	_, err = os.Stat(s.manager.statePath(job.ID))
+	assert.True(c, os.IsNotExist(err), "state of old job should have been removed")
+}
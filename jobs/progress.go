@@ -0,0 +1,43 @@
+package jobs
+
+import "io"
+
+// progressReader reports the number of bytes read as job progress.
+type progressReader struct {
+	r     io.Reader
+	run   *Run
+	done  int64
+	total int64
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.done += int64(n)
+	pr.run.Progress(pr.done, pr.total, "bytes")
+	return n, err
+}
+
+// Reader wraps the reader, reporting the number of bytes read as progress of the job.
+// The total is the expected number of bytes, or zero when unknown.
+func (r *Run) Reader(reader io.Reader, total int64) io.Reader {
+	return &progressReader{r: reader, run: r, total: total}
+}
+
+// progressWriter reports the number of bytes written as job progress.
+type progressWriter struct {
+	w    io.Writer
+	run  *Run
+	done int64
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.done += int64(n)
+	pw.run.Progress(pw.done, 0, "bytes")
+	return n, err
+}
+
+// Writer wraps the writer, reporting the number of bytes written as progress of the job.
+func (r *Run) Writer(writer io.Writer) io.Writer {
+	return &progressWriter{w: writer, run: r}
+}
@@ -0,0 +1,25 @@
+// Package jsonschemas validates documents against the JSON schemas in the json_schemas directory.
+package jsonschemas
+
+import (
+	"path/filepath"
+
+	"github.com/armadillica/svn-manager/filelocator"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Validate validates the given document against the given schema.
+func Validate(schemaName string, document interface{}) (*gojsonschema.Result, error) {
+	filename, err := filelocator.FindFile(filepath.Join("json_schemas", schemaName+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	schemaLoader := gojsonschema.NewReferenceLoader("file://" + filename)
+	documentLoader := gojsonschema.NewGoLoader(document)
+	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
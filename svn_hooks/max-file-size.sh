@@ -0,0 +1,27 @@
+#!/bin/sh
+# Rejects commits that add or modify files larger than $SVNMAN_MAX_SIZE_MB
+# megabytes.
+
+REPOS="$1"
+TXN="$2"
+MAX_BYTES=$(( ${SVNMAN_MAX_SIZE_MB:-100} * 1024 * 1024 ))
+
+# 'svnlook changed' prints the action in the first columns, and the path from the fifth.
+svnlook changed -t "$TXN" "$REPOS" | while IFS= read -r line; do
+    action=$(printf '%s' "$line" | cut -c1)
+    path=$(printf '%s' "$line" | cut -c5-)
+
+    case "$action" in
+        A|U) ;;
+        *) continue ;;
+    esac
+    case "$path" in
+        */) continue ;;  # directory
+    esac
+
+    size=$(svnlook filesize -t "$TXN" "$REPOS" "$path") || continue
+    if [ "$size" -gt "$MAX_BYTES" ]; then
+        echo "File $path is $size bytes, exceeding the maximum of $MAX_BYTES bytes." >&2
+        exit 1
+    fi
+done
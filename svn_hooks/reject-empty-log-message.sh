@@ -0,0 +1,13 @@
+#!/bin/sh
+# Rejects commits whose log message is shorter than $SVNMAN_MIN_LENGTH
+# non-whitespace characters.
+
+REPOS="$1"
+TXN="$2"
+MIN_LENGTH="${SVNMAN_MIN_LENGTH:-1}"
+
+LOGMSG=$(svnlook log -t "$TXN" "$REPOS" | tr -d '[:space:]')
+if [ "${#LOGMSG}" -lt "$MIN_LENGTH" ]; then
+    echo "Please provide a log message of at least $MIN_LENGTH characters." >&2
+    exit 1
+fi
@@ -0,0 +1,290 @@
+package svnman
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/armadillica/svn-manager/backend"
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// Subjects of authz rules that aren't a username.
+const (
+	authzEveryone    = "*"
+	authzGroupPrefix = "@"
+)
+
+// Permissions in authz rules, ordered from least to most access.
+var authzPermissions = []string{"", "r", "rw"}
+
+// Stored in the info file of repositories with path-based authorization rules.
+type authzinfo struct {
+	Groups map[string][]string `yaml:"groups,omitempty"`
+	Rules  []authzruleinfo     `yaml:"rules"`
+}
+
+type authzruleinfo struct {
+	Path   string            `yaml:"path"`
+	Access map[string]string `yaml:"access"`
+}
+
+func authzinfoFromAuthz(authz Authz) *authzinfo {
+	if len(authz.Groups) == 0 && len(authz.Rules) == 0 {
+		return nil
+	}
+	ai := authzinfo{
+		Groups: authz.Groups,
+		Rules:  make([]authzruleinfo, len(authz.Rules)),
+	}
+	for idx, rule := range authz.Rules {
+		ai.Rules[idx] = authzruleinfo{rule.Path, rule.Access}
+	}
+	return &ai
+}
+
+func (ai *authzinfo) authz() Authz {
+	authz := Authz{
+		Groups: map[string][]string{},
+		Rules:  []AuthzRule{},
+	}
+	if ai == nil {
+		return authz
+	}
+	for name, members := range ai.Groups {
+		authz.Groups[name] = members
+	}
+	for _, rule := range ai.Rules {
+		authz.Rules = append(authz.Rules, AuthzRule{rule.Path, rule.Access})
+	}
+	return authz
+}
+
+// Returns the path of the svn authz file of the repository, referenced by the backend config.
+func (svn *SVNMan) authzPath(repoID string) string {
+	return filepath.Join(svn.repoPath(repoID), "authz")
+}
+
+// GetAuthz returns the path-based authorization rules of the repository.
+func (svn *SVNMan) GetAuthz(repoID string) (Authz, error) {
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		return Authz{}, err
+	}
+	return info.Authz.authz(), nil
+}
+
+// SetAuthz replaces the path-based authorization rules of the repository.
+// Without groups and rules, every user has access to the entire repository.
+func (svn *SVNMan) SetAuthz(repoID string, authz Authz, logFields log.Fields) error {
+	event := events.RepoEvent{RepoID: repoID}
+	if info, err := svn.readRepoInfo(repoID); err == nil {
+		event.ProjectID = info.ProjectID
+		event.Creator = info.Creator
+	}
+
+	err := svn.setAuthz(repoID, authz, logFields)
+	svn.publishRepoEvent(events.RepoAuthzChanged, event, err)
+	return err
+}
+
+func (svn *SVNMan) setAuthz(repoID string, authz Authz, logFields log.Fields) error {
+	filename := svn.authzPath(repoID)
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"group_count": len(authz.Groups),
+		"rule_count":  len(authz.Rules),
+		"filename":    filename,
+	})
+
+	if err := validateAuthz(authz, logger); err != nil {
+		return err
+	}
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Warning("unable to read repository info")
+		return err
+	}
+
+	previous := info
+	info.Authz = authzinfoFromAuthz(authz)
+	if err := svn.writeRepoInfo(info); err != nil {
+		logger.WithError(err).Error("unable to write repository info")
+		return err
+	}
+
+	// The authz file is read by the backend for every request, so it must never be half-written.
+	if info.Authz == nil {
+		err = os.Remove(filename)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		err = writeFileAtomically(filename, renderAuthzFile(info.Authz), 0644)
+	}
+	if err != nil {
+		logger.WithError(err).Error("unable to write authz file")
+		if err := svn.writeRepoInfo(previous); err != nil {
+			logger.WithError(err).Error("unable to restore repository info")
+		}
+		return err
+	}
+
+	changed, err := svn.writeConfig(info)
+	if err != nil {
+		logger.WithError(err).Error("unable to update backend config")
+		return err
+	}
+	if changed {
+		svn.backend.QueueReload(repoID, svn.confPath(repoID))
+	}
+
+	logger.Info("repository authorization rules modified")
+	return nil
+}
+
+// validateAuthz checks what the JSON schema cannot: that paths are clean and unique,
+// and that referenced groups exist.
+func validateAuthz(authz Authz, logger *log.Entry) error {
+	paths := map[string]bool{}
+	for _, rule := range authz.Rules {
+		ruleLogger := logger.WithField("path", rule.Path)
+		if !strings.HasPrefix(rule.Path, "/") || path.Clean(rule.Path) != rule.Path {
+			ruleLogger.Warning("authz rule path is not a clean absolute path")
+			return ErrInvalidAuthz
+		}
+		if paths[rule.Path] {
+			ruleLogger.Warning("multiple authz rules for the same path")
+			return ErrInvalidAuthz
+		}
+		paths[rule.Path] = true
+
+		for subject, perm := range rule.Access {
+			if authzPermissionLevel(perm) < 0 {
+				ruleLogger.WithField("permission", perm).Warning("unknown authz permission")
+				return ErrInvalidAuthz
+			}
+			if !strings.HasPrefix(subject, authzGroupPrefix) {
+				continue
+			}
+			if _, ok := authz.Groups[strings.TrimPrefix(subject, authzGroupPrefix)]; !ok {
+				ruleLogger.WithField("group", subject).Warning("authz rule refers to undefined group")
+				return ErrInvalidAuthz
+			}
+		}
+	}
+	return nil
+}
+
+// authzPermissionLevel returns the index in authzPermissions, or -1 for unknown permissions.
+func authzPermissionLevel(perm string) int {
+	for level, known := range authzPermissions {
+		if perm == known {
+			return level
+		}
+	}
+	return -1
+}
+
+// hasRootRule returns whether the rules determine the access to the entire repository.
+// Without such a rule, every user has read-write access to it.
+func (ai *authzinfo) hasRootRule() bool {
+	for _, rule := range ai.Rules {
+		if rule.Path == "/" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderAuthzFile returns the rules in the format of an svn authz file.
+func renderAuthzFile(ai *authzinfo) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by SVN Manager; changes will be overwritten.\n")
+
+	buf.WriteString("[groups]\n")
+	groupNames := make([]string, 0, len(ai.Groups))
+	for name := range ai.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		fmt.Fprintf(&buf, "%s = %s\n", name, strings.Join(ai.Groups[name], ", "))
+	}
+
+	if !ai.hasRootRule() {
+		fmt.Fprintf(&buf, "\n[/]\n%s = rw\n", authzEveryone)
+	}
+	rules := make([]authzruleinfo, len(ai.Rules))
+	copy(rules, ai.Rules)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Path < rules[j].Path })
+	for _, rule := range rules {
+		fmt.Fprintf(&buf, "\n[%s]\n", rule.Path)
+		subjects := make([]string, 0, len(rule.Access))
+		for subject := range rule.Access {
+			subjects = append(subjects, subject)
+		}
+		sort.Strings(subjects)
+		for _, subject := range subjects {
+			fmt.Fprintln(&buf, strings.TrimSpace(subject+" = "+rule.Access[subject]))
+		}
+	}
+	return buf.Bytes()
+}
+
+// expandAuthzRules returns the rules for the given users, with groups and "*" expanded.
+// Like Subversion, a user matched by multiple subjects of a rule gets the most access.
+// Without rules, every user has read-write access to the entire repository. Read-only users
+// never get more than read access.
+func expandAuthzRules(ai *authzinfo, usernames []string, readOnly map[string]bool) []backend.AuthzRule {
+	isUser := map[string]bool{}
+	for _, username := range usernames {
+		isUser[username] = true
+	}
+
+	rules := []authzruleinfo{}
+	if ai == nil || !ai.hasRootRule() {
+		rules = append(rules, authzruleinfo{"/", map[string]string{authzEveryone: "rw"}})
+	}
+	if ai != nil {
+		rules = append(rules, ai.Rules...)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Path < rules[j].Path })
+
+	expanded := make([]backend.AuthzRule, len(rules))
+	for idx, rule := range rules {
+		access := map[string]string{}
+		grant := func(username, perm string) {
+			if !isUser[username] {
+				return
+			}
+			if perm == "rw" && readOnly[username] {
+				perm = "r"
+			}
+			if current, ok := access[username]; !ok || authzPermissionLevel(perm) > authzPermissionLevel(current) {
+				access[username] = perm
+			}
+		}
+		for subject, perm := range rule.Access {
+			switch {
+			case subject == authzEveryone:
+				for _, username := range usernames {
+					grant(username, perm)
+				}
+			case strings.HasPrefix(subject, authzGroupPrefix):
+				for _, username := range ai.Groups[strings.TrimPrefix(subject, authzGroupPrefix)] {
+					grant(username, perm)
+				}
+			default:
+				grant(subject, perm)
+			}
+		}
+		expanded[idx] = backend.AuthzRule{Path: rule.Path, Access: access}
+	}
+	return expanded
+}
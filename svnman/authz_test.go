@@ -0,0 +1,147 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/armadillica/svn-manager/backend"
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+var testAuthz = Authz{
+	Groups: map[string][]string{"leads": {"alice", "carol"}},
+	Rules: []AuthzRule{
+		{Path: "/trunk/release", Access: map[string]string{"*": "r", "@leads": "rw"}},
+		{Path: "/secret", Access: map[string]string{"*": "", "bob": "r"}},
+	},
+}
+
+func (s *SVNManTestSuite) TestSetAuthz(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	s.mb = mockBackend{}
+	s.mp.Reset()
+	logFields := log.Fields{"in": "unittest"}
+
+	authz, err := s.svn.GetAuthz("my-repo-id")
+	assert.Nil(t, err)
+	assert.Empty(t, authz.Groups)
+	assert.Empty(t, authz.Rules)
+
+	assert.Nil(t, s.svn.SetAuthz("my-repo-id", testAuthz, logFields))
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+	published := s.mp.Events()
+	if assert.Equal(t, 1, len(published)) {
+		assert.Equal(t, events.RepoAuthzChanged, published[0].RoutingKey)
+		event := s.repoEvent(t, published[0])
+		assert.Equal(t, "my-repo-id", event.RepoID)
+		assert.Equal(t, "59eefa9cf488554678cae036", event.ProjectID)
+		assert.True(t, event.Success)
+	}
+
+	authz, err = s.svn.GetAuthz("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, testAuthz, authz)
+
+	authzFile, err := ioutil.ReadFile(s.svn.authzPath("my-repo-id"))
+	assert.Nil(t, err)
+	assert.Equal(t, `# Generated by SVN Manager; changes will be overwritten.
+[groups]
+leads = alice, carol
+
+[/]
+* = rw
+
+[/secret]
+* =
+bob = r
+
+[/trunk/release]
+* = r
+@leads = rw
+`, string(authzFile))
+
+	apa := s.readApacheConfig(t, "my-repo-id")
+	assert.Contains(t, apa, "AuthzSVNAccessFile "+s.svn.authzPath("my-repo-id")+"\n")
+
+	// Removing all rules removes the authz file.
+	s.mb = mockBackend{}
+	assert.Nil(t, s.svn.SetAuthz("my-repo-id", Authz{}, logFields))
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+	_, err = os.Stat(s.svn.authzPath("my-repo-id"))
+	assert.True(t, os.IsNotExist(err), "authz file should have been removed")
+	apa = s.readApacheConfig(t, "my-repo-id")
+	assert.NotContains(t, apa, "AuthzSVNAccessFile")
+}
+
+func (s *SVNManTestSuite) TestSetAuthzInvalid(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	logFields := log.Fields{"in": "unittest"}
+
+	for _, authz := range []Authz{
+		{Rules: []AuthzRule{{Path: "/trunk/", Access: map[string]string{"*": "r"}}}},
+		{Rules: []AuthzRule{{Path: "/trunk/../tags", Access: map[string]string{"*": "r"}}}},
+		{Rules: []AuthzRule{{Path: "/", Access: map[string]string{"@leads": "r"}}}},
+		{Rules: []AuthzRule{
+			{Path: "/trunk", Access: map[string]string{"*": "r"}},
+			{Path: "/trunk", Access: map[string]string{"*": "rw"}},
+		}},
+	} {
+		assert.Equal(t, ErrInvalidAuthz, s.svn.SetAuthz("my-repo-id", authz, logFields), "authz %v", authz)
+	}
+	_, err := os.Stat(s.svn.authzPath("my-repo-id"))
+	assert.True(t, os.IsNotExist(err), "authz file should not have been written")
+
+	assert.Equal(t, ErrNotFound, s.svn.SetAuthz("other-repo-id", testAuthz, logFields))
+}
+
+func (s *SVNManTestSuite) TestSetAuthzWriteFailure(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	logFields := log.Fields{"in": "unittest"}
+
+	// A non-empty directory where the authz file should be makes writing it impossible.
+	assert.Nil(t, os.MkdirAll(filepath.Join(s.svn.authzPath("my-repo-id"), "in-the-way"), 0750))
+	s.mb = mockBackend{}
+	assert.NotNil(t, s.svn.SetAuthz("my-repo-id", testAuthz, logFields))
+	assert.False(t, s.mb.reloadCalled, "backend should not be reloaded")
+
+	// The info file should still match what is on disk.
+	authz, err := s.svn.GetAuthz("my-repo-id")
+	assert.Nil(t, err)
+	assert.Empty(t, authz.Rules)
+}
+
+func (s *SVNManTestSuite) TestExpandAuthzRules(t *check.C) {
+	// Without rules, all users can write everywhere.
+	rules := expandAuthzRules(nil, []string{"alice", "bob"}, nil)
+	assert.Equal(t, []backend.AuthzRule{
+		{Path: "/", Access: map[string]string{"alice": "rw", "bob": "rw"}},
+	}, rules)
+
+	// Users without password are skipped, and the most permissive rule wins.
+	rules = expandAuthzRules(authzinfoFromAuthz(testAuthz), []string{"alice", "bob"}, nil)
+	assert.Equal(t, []backend.AuthzRule{
+		{Path: "/", Access: map[string]string{"alice": "rw", "bob": "rw"}},
+		{Path: "/secret", Access: map[string]string{"alice": "", "bob": "r"}},
+		{Path: "/trunk/release", Access: map[string]string{"alice": "rw", "bob": "r"}},
+	}, rules)
+
+	// An explicit rule for the root replaces the default.
+	rules = expandAuthzRules(authzinfoFromAuthz(Authz{
+		Rules: []AuthzRule{{Path: "/", Access: map[string]string{"*": "r"}}},
+	}), []string{"alice"}, nil)
+	assert.Equal(t, []backend.AuthzRule{
+		{Path: "/", Access: map[string]string{"alice": "r"}},
+	}, rules)
+
+	// Read-only users never get write access.
+	rules = expandAuthzRules(authzinfoFromAuthz(testAuthz), []string{"alice", "bob"}, map[string]bool{"alice": true})
+	assert.Equal(t, []backend.AuthzRule{
+		{Path: "/", Access: map[string]string{"alice": "r", "bob": "rw"}},
+		{Path: "/secret", Access: map[string]string{"alice": "", "bob": "r"}},
+		{Path: "/trunk/release", Access: map[string]string{"alice": "r", "bob": "r"}},
+	}, rules)
+}
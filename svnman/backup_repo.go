@@ -0,0 +1,197 @@
+package svnman
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Stored as YAML next to every backup.
+type backupinfo struct {
+	BackedUpOn   time.Time `yaml:"backed_up_on"`
+	HeadRevision int       `yaml:"head_revision"`
+	DiskSize     int64     `yaml:"disk_size"`
+}
+
+// runSvnadmin runs svnadmin, logging its stderr when it fails.
+func runSvnadmin(ctx context.Context, logger *log.Entry, args ...string) error {
+	out, err := exec.CommandContext(ctx, "svnadmin", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			logger = logger.WithField("stderr", string(exitErr.Stderr))
+		}
+		logger.WithError(err).WithField("args", args).Warning("error running svnadmin")
+		return err
+	}
+	logger.WithField("stdout", string(out)).Debugf("'svnadmin %s' successful", args[0])
+	return nil
+}
+
+// BackupRepo updates the backup of the repository with an incremental 'svnadmin hotcopy'.
+// This is safe to do while the repository is in use.
+func (svn *SVNMan) BackupRepo(ctx context.Context, repoID string, logFields log.Fields) (BackupState, error) {
+	repodir := svn.repoPath(repoID)
+	backupdir := svn.backupPath(repoID)
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"repo_id":    repoID,
+		"repo_dir":   repodir,
+		"backup_dir": backupdir,
+	})
+
+	if svn.backupRoot == "" {
+		return BackupState{}, ErrBackupsDisabled
+	}
+	if _, err := os.Stat(repodir); os.IsNotExist(err) {
+		logger.Warning("nonexistent repository requested")
+		return BackupState{}, ErrNotFound
+	}
+
+	defer svn.backupLocks.lock(repoID).Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(backupdir), 0750); err != nil {
+		logger.WithError(err).Error("unable to create backup directory")
+		return BackupState{}, ErrBackup
+	}
+
+	logger.Info("backing up repository")
+	startTime := time.Now().UTC()
+	if err := runSvnadmin(ctx, logger, "hotcopy", "--incremental", repodir, backupdir); err != nil {
+		if ctx.Err() != nil {
+			return BackupState{}, ctx.Err()
+		}
+		return BackupState{}, ErrBackup
+	}
+
+	info := backupinfo{BackedUpOn: startTime}
+	var err error
+	if info.HeadRevision, err = readHeadRevision(backupdir); err != nil {
+		logger.WithError(err).Error("unable to determine HEAD revision of backup")
+		return BackupState{}, ErrBackup
+	}
+	if info.DiskSize, err = dirSize(backupdir); err != nil {
+		logger.WithError(err).Warning("unable to determine disk size of backup")
+	}
+
+	infobytes, err := yaml.Marshal(&info)
+	if err != nil {
+		logger.WithError(err).Error("unable to encode backup info")
+		return BackupState{}, ErrBackup
+	}
+	if err := ioutil.WriteFile(svn.backupInfoPath(repoID), infobytes, 0640); err != nil {
+		logger.WithError(err).Error("unable to save backup info")
+		return BackupState{}, ErrBackup
+	}
+
+	logger.WithFields(log.Fields{
+		"head_revision": info.HeadRevision,
+		"disk_size":     info.DiskSize,
+		"duration":      time.Since(startTime),
+	}).Info("repository backed up")
+	return info.state(), nil
+}
+
+func (bi backupinfo) state() BackupState {
+	return BackupState{
+		BackedUpOn:   bi.BackedUpOn,
+		HeadRevision: bi.HeadRevision,
+		DiskSize:     bi.DiskSize,
+	}
+}
+
+// GetBackupState returns information about the last successful backup of the repository.
+// The repository itself does not have to exist any more.
+func (svn *SVNMan) GetBackupState(repoID string) (BackupState, error) {
+	if svn.backupRoot == "" {
+		return BackupState{}, ErrBackupsDisabled
+	}
+
+	infobytes, err := ioutil.ReadFile(svn.backupInfoPath(repoID))
+	if os.IsNotExist(err) {
+		return BackupState{}, ErrNoBackup
+	} else if err != nil {
+		return BackupState{}, err
+	}
+	info := backupinfo{}
+	if err := yaml.Unmarshal(infobytes, &info); err != nil {
+		return BackupState{}, err
+	}
+	return info.state(), nil
+}
+
+// RestoreBackup turns the backup into a live repository, which must not exist yet.
+// The backup itself is kept.
+func (svn *SVNMan) RestoreBackup(ctx context.Context, repoID string, logFields log.Fields) error {
+	event := events.RepoEvent{RepoID: repoID}
+	err := svn.restoreBackup(ctx, repoID, logFields)
+	if err == nil {
+		if info, infoErr := svn.readRepoInfo(repoID); infoErr == nil {
+			event.ProjectID = info.ProjectID
+			event.Creator = info.Creator
+		}
+	}
+	svn.publishRepoEvent(events.RepoBackupRestored, event, err)
+	return err
+}
+
+func (svn *SVNMan) restoreBackup(ctx context.Context, repoID string, logFields log.Fields) error {
+	repodir := svn.repoPath(repoID)
+	backupdir := svn.backupPath(repoID)
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"repo_id":     repoID,
+		"repo_dir":    repodir,
+		"backup_dir":  backupdir,
+		"config_file": svn.confPath(repoID),
+	})
+
+	if svn.backupRoot == "" {
+		return ErrBackupsDisabled
+	}
+	if _, err := os.Stat(repodir); err == nil {
+		logger.Warning("repository already exists")
+		return ErrAlreadyExists
+	}
+	if _, err := os.Stat(svn.backupInfoPath(repoID)); os.IsNotExist(err) {
+		logger.Warning("no backup of repository exists")
+		return ErrNoBackup
+	}
+
+	// Prevent the scheduled backup from updating the backup while we copy it.
+	defer svn.backupLocks.lock(repoID).Unlock()
+
+	logger.Info("restoring repository from backup")
+	if err := os.MkdirAll(filepath.Dir(repodir), 0750); err != nil {
+		logger.WithError(err).Error("unable to create path for repo")
+		return ErrRestore
+	}
+	// A hotcopy rather than a plain copy, so that the result is a proper repository of its own.
+	if err := runSvnadmin(ctx, logger, "hotcopy", backupdir, repodir); err != nil {
+		svn.discardRepo(repoID, logger)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrRestore
+	}
+
+	// The info file, htpasswd and member list are copied along with the rest of the repository.
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to read info of restored repository")
+		svn.discardRepo(repoID, logger)
+		return ErrRestore
+	}
+	if err := svn.enableRepo(info, logger); err != nil {
+		logger.WithError(err).Error("unable to enable restored repository")
+		svn.discardRepo(repoID, logger)
+		return ErrRestore
+	}
+
+	logger.Info("repository restored from backup")
+	return nil
+}
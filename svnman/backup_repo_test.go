@@ -0,0 +1,90 @@
+package svnman
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestBackupRepo(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(testDump), nil, logFields)
+	assert.Nil(t, err)
+
+	_, err = s.svn.GetBackupState("my-repo-id")
+	assert.Equal(t, ErrNoBackup, err)
+
+	state, err := s.svn.BackupRepo(context.Background(), "my-repo-id", logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, state.HeadRevision)
+	assert.True(t, state.DiskSize > 0)
+	assert.False(t, state.BackedUpOn.IsZero())
+
+	_, err = os.Stat(s.svn.backupPath("my-repo-id") + "/format")
+	assert.Nil(t, err, "the backup should be a repository")
+
+	stored, err := s.svn.GetBackupState("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, state.HeadRevision, stored.HeadRevision)
+	assert.True(t, state.BackedUpOn.Equal(stored.BackedUpOn))
+
+	// A second, incremental, backup should also work.
+	_, err = s.svn.BackupRepo(context.Background(), "my-repo-id", logFields)
+	assert.Nil(t, err)
+}
+
+func (s *SVNManTestSuite) TestBackupRepoErrors(t *check.C) {
+	_, err := s.svn.BackupRepo(context.Background(), "my-repo-id", log.Fields{})
+	assert.Equal(t, ErrNotFound, err)
+
+	s.svn.backupRoot = ""
+	_, err = s.svn.BackupRepo(context.Background(), "my-repo-id", log.Fields{})
+	assert.Equal(t, ErrBackupsDisabled, err)
+	_, err = s.svn.GetBackupState("my-repo-id")
+	assert.Equal(t, ErrBackupsDisabled, err)
+}
+
+func (s *SVNManTestSuite) TestRestoreBackup(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	_, err := s.svn.BackupRepo(context.Background(), "my-repo-id", logFields)
+	assert.Nil(t, err)
+
+	// Restoring on top of the existing repository is not allowed.
+	err = s.svn.RestoreBackup(context.Background(), "my-repo-id", logFields)
+	assert.Equal(t, ErrAlreadyExists, err)
+
+	s.deleteTestRepo(t, "my-repo-id")
+	s.mb = mockBackend{}
+	s.mp.Reset()
+
+	err = s.svn.RestoreBackup(context.Background(), "my-repo-id", logFields)
+	assert.Nil(t, err)
+	assert.True(t, s.mb.reloadCalled, "a backend reload should have been queued")
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err, "Apache config should have been created")
+	_, err = s.svn.GetUsernames("my-repo-id")
+	assert.Nil(t, err, "htpasswd file should have been restored")
+	_, err = os.Stat(s.svn.backupPath("my-repo-id"))
+	assert.Nil(t, err, "the backup should have been kept")
+
+	published := s.mp.Events()
+	if len(published) != 1 {
+		t.Fatalf("expected 1 event, got %#v", published)
+	}
+	assert.Equal(t, events.RepoBackupRestored, published[0].RoutingKey)
+	event := s.repoEvent(t, published[0])
+	assert.True(t, event.Success)
+	assert.Equal(t, "59eefa9cf488554678cae036", event.ProjectID)
+}
+
+func (s *SVNManTestSuite) TestRestoreBackupNoBackup(t *check.C) {
+	err := s.svn.RestoreBackup(context.Background(), "my-repo-id", log.Fields{})
+	assert.Equal(t, ErrNoBackup, err)
+	assert.False(t, s.mb.reloadCalled, "backend should not be reloaded")
+}
@@ -0,0 +1,91 @@
+package svnman
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BlockRepo denies access to a repository, without deleting it.
+func (svn *SVNMan) BlockRepo(repoID string, block BlockRepo, logFields log.Fields) error {
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"read_only": block.ReadOnly,
+		"reason":    block.Reason,
+	})
+
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Warning("unable to read repository info")
+		return err
+	}
+
+	info.Block = &blockinfo{
+		ReadOnly:  block.ReadOnly,
+		Reason:    block.Reason,
+		BlockedOn: time.Now().UTC(),
+	}
+	if err := svn.updateBlock(info); err != nil {
+		logger.WithError(err).Error("unable to block repository")
+		return err
+	}
+
+	logger.Info("repository blocked")
+	return nil
+}
+
+// UnblockRepo restores access to a blocked repository.
+func (svn *SVNMan) UnblockRepo(repoID string, logFields log.Fields) error {
+	logger := log.WithFields(logFields)
+
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Warning("unable to read repository info")
+		return err
+	}
+	if info.Block == nil {
+		logger.Debug("repository was not blocked, rewriting config anyway")
+	}
+
+	info.Block = nil
+	if err := svn.updateBlock(info); err != nil {
+		logger.WithError(err).Error("unable to unblock repository")
+		return err
+	}
+
+	logger.Info("repository unblocked")
+	return nil
+}
+
+// updateBlock stores the block state and rewrites the backend config to match.
+func (svn *SVNMan) updateBlock(info repoinfo) error {
+	// Write the backend config first, as that is what actually determines access.
+	if _, err := svn.writeConfig(info); err != nil {
+		return err
+	}
+	if err := svn.writeRepoInfo(info); err != nil {
+		return err
+	}
+
+	svn.backend.QueueReload(info.RepoID, svn.confPath(info.RepoID))
+	return nil
+}
+
+// GetBlockState returns whether and how the repository is blocked.
+func (svn *SVNMan) GetBlockState(repoID string) (BlockState, error) {
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		return BlockState{}, err
+	}
+	if info.Block == nil {
+		return BlockState{}, nil
+	}
+
+	return BlockState{
+		Blocked:   true,
+		ReadOnly:  info.Block.ReadOnly,
+		Reason:    info.Block.Reason,
+		BlockedOn: &info.Block.BlockedOn,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package svnman
+
+import (
+	"io/ioutil"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) readApacheConfig(t *check.C, repoID string) string {
+	apafile := s.svn.confPath(repoID)
+	apabytes, err := ioutil.ReadFile(apafile)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", apafile, err)
+	}
+	return string(apabytes)
+}
+
+func (s *SVNManTestSuite) TestBlockUnblockHappy(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+
+	repoInfo := CreateRepo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "dr. Stüvel <sybren@blender.studio>",
+	}
+	if err := s.svn.CreateRepo(repoInfo, logFields); err != nil {
+		t.Fatalf("Unable to create repo: %s", err)
+	}
+	s.mb = mockBackend{}
+
+	state, err := s.svn.GetBlockState("1234")
+	assert.Nil(t, err)
+	assert.False(t, state.Blocked)
+
+	// Block completely.
+	err = s.svn.BlockRepo("1234", BlockRepo{Reason: "unpaid subscription"}, logFields)
+	assert.Nil(t, err)
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+
+	apa := s.readApacheConfig(t, "1234")
+	assert.Contains(t, apa, "Require all denied")
+	assert.NotContains(t, apa, "Require valid-user")
+
+	state, err = s.svn.GetBlockState("1234")
+	assert.Nil(t, err)
+	assert.True(t, state.Blocked)
+	assert.False(t, state.ReadOnly)
+	assert.Equal(t, "unpaid subscription", state.Reason)
+	assert.NotNil(t, state.BlockedOn)
+
+	// Block for writing only.
+	err = s.svn.BlockRepo("1234", BlockRepo{Reason: "abuse", ReadOnly: true}, logFields)
+	assert.Nil(t, err)
+
+	apa = s.readApacheConfig(t, "1234")
+	assert.Contains(t, apa, "<LimitExcept GET PROPFIND OPTIONS REPORT>")
+	assert.Contains(t, apa, "Require valid-user")
+
+	state, err = s.svn.GetBlockState("1234")
+	assert.Nil(t, err)
+	assert.True(t, state.Blocked)
+	assert.True(t, state.ReadOnly)
+	assert.Equal(t, "abuse", state.Reason)
+
+	// Unblock again.
+	s.mb = mockBackend{}
+	err = s.svn.UnblockRepo("1234", logFields)
+	assert.Nil(t, err)
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+
+	apa = s.readApacheConfig(t, "1234")
+	assert.NotContains(t, apa, "Require all denied")
+	assert.Contains(t, apa, "Require valid-user")
+
+	state, err = s.svn.GetBlockState("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, BlockState{}, state)
+
+	// The other info should have survived all this.
+	info, err := s.svn.readRepoInfo("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "59eefa9cf488554678cae036", info.ProjectID)
+	assert.Equal(t, "dr. Stüvel <sybren@blender.studio>", info.Creator)
+}
+
+func (s *SVNManTestSuite) TestBlockNonExistantRepo(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+
+	err := s.svn.BlockRepo("1234", BlockRepo{Reason: "unpaid subscription"}, logFields)
+	assert.Equal(t, ErrNotFound, err)
+	err = s.svn.UnblockRepo("1234", logFields)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = s.svn.GetBlockState("1234")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.False(t, s.mb.reloadCalled, "backend reload should not be requested")
+}
@@ -0,0 +1,120 @@
+package svnman
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// Layout of the date in 'svnlook info' output, ignoring the human-readable part in parentheses.
+const svnlookDateLayout = "2006-01-02 15:04:05 -0700"
+
+// errUnexpectedOutput is returned when svnlook output cannot be parsed.
+var errUnexpectedOutput = errors.New("unexpected output from svnlook")
+
+// svnlook runs 'svnlook {subcmd} {args} {repodir}' and returns its output.
+func (svn *SVNMan) svnlook(logger *log.Entry, repoID, subcmd string, args ...string) (string, error) {
+	cmdargs := append([]string{subcmd}, args...)
+	cmdargs = append(cmdargs, svn.repoPath(repoID))
+
+	out, err := exec.Command("svnlook", cmdargs...).Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			logger = logger.WithField("stderr", string(e.Stderr))
+		}
+		logger.WithError(err).WithField("subcmd", subcmd).Warning("error running svnlook")
+		return "", err
+	}
+	return string(out), nil
+}
+
+// NotifyCommit publishes the details of a commit. It is called from the post-commit hook.
+func (svn *SVNMan) NotifyCommit(repoID string, revision int, logFields log.Fields) error {
+	logger := log.WithFields(logFields).WithField("revision", revision)
+
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Warning("unable to read repository info")
+		return err
+	}
+
+	rev := strconv.Itoa(revision)
+	infoOut, err := svn.svnlook(logger, repoID, "info", "-r", rev)
+	if err != nil {
+		return err
+	}
+	changedOut, err := svn.svnlook(logger, repoID, "changed", "-r", rev)
+	if err != nil {
+		return err
+	}
+
+	event := events.CommitEvent{
+		RepoID:       repoID,
+		ProjectID:    info.ProjectID,
+		Revision:     revision,
+		ChangedPaths: parseSvnlookChanged(changedOut),
+	}
+	event.Author, event.Date, event.LogMessage, err = parseSvnlookInfo(infoOut)
+	if err != nil {
+		logger.WithError(err).WithField("output", infoOut).Error("unable to parse svnlook output")
+		return err
+	}
+
+	svn.publisher.Publish(events.RepoCommit, event)
+	logger.WithField("author", event.Author).Debug("commit notification published")
+	return nil
+}
+
+// parseSvnlookInfo parses the output of 'svnlook info', which consists of the
+// author, date, log message size, and log message.
+func parseSvnlookInfo(output string) (author string, date time.Time, logMessage string, err error) {
+	lines := strings.SplitN(output, "\n", 4)
+	if len(lines) < 3 {
+		return "", time.Time{}, "", errUnexpectedOutput
+	}
+
+	author = lines[0]
+
+	dateStr := lines[1]
+	if idx := strings.Index(dateStr, " ("); idx >= 0 {
+		dateStr = dateStr[:idx]
+	}
+	date, err = time.Parse(svnlookDateLayout, dateStr)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+
+	logSize, err := strconv.Atoi(lines[2])
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+	if len(lines) == 4 {
+		logMessage = lines[3]
+	}
+	if logSize <= len(logMessage) {
+		logMessage = logMessage[:logSize]
+	}
+
+	return author, date.UTC(), logMessage, nil
+}
+
+// parseSvnlookChanged parses the output of 'svnlook changed'. Each line consists
+// of the action in the first four columns, followed by the path.
+func parseSvnlookChanged(output string) []events.ChangedPath {
+	changed := []events.ChangedPath{}
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 5 {
+			continue
+		}
+		changed = append(changed, events.ChangedPath{
+			Action: strings.TrimSpace(line[:4]),
+			Path:   line[4:],
+		})
+	}
+	return changed
+}
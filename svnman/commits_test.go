@@ -0,0 +1,55 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/armadillica/svn-manager/events"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestCreateRepoInstallsPostCommit(t *check.C) {
+	s.createTestRepo(t, "1234")
+
+	hook := filepath.Join(s.svn.repoPath("1234"), "hooks", "post-commit")
+	content, err := ioutil.ReadFile(hook)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", hook, err)
+	}
+	assert.Contains(t, string(content), hookScriptMarker)
+	assert.Contains(t, string(content), `'http://localhost:8085/api/repo/1234/commits'`)
+	assert.Contains(t, string(content), `--data "{\"revision\": $2}"`)
+}
+
+func (s *SVNManTestSuite) TestParseSvnlookInfo(t *check.C) {
+	output := "sybren\n2017-11-14 16:30:04 +0100 (Tue, 14 Nov 2017)\n25\nFixed a bug\n\nin the thing\n"
+	author, date, logMessage, err := parseSvnlookInfo(output)
+	assert.Nil(t, err)
+	assert.Equal(t, "sybren", author)
+	assert.Equal(t, time.Date(2017, 11, 14, 15, 30, 4, 0, time.UTC), date)
+	assert.Equal(t, "Fixed a bug\n\nin the thing", logMessage)
+
+	// Empty log message
+	output = "sybren\n2017-11-14 16:30:04 +0100 (Tue, 14 Nov 2017)\n0\n\n"
+	author, _, logMessage, err = parseSvnlookInfo(output)
+	assert.Nil(t, err)
+	assert.Equal(t, "sybren", author)
+	assert.Equal(t, "", logMessage)
+
+	_, _, _, err = parseSvnlookInfo("svnlook: E160006: No such revision 47\n")
+	assert.NotNil(t, err)
+}
+
+func (s *SVNManTestSuite) TestParseSvnlookChanged(t *check.C) {
+	output := "A   trunk/\nU   trunk/file with spaces.blend\n_U  trunk/props\nD   branches/old/\n"
+	assert.Equal(t, []events.ChangedPath{
+		events.ChangedPath{Action: "A", Path: "trunk/"},
+		events.ChangedPath{Action: "U", Path: "trunk/file with spaces.blend"},
+		events.ChangedPath{Action: "_U", Path: "trunk/props"},
+		events.ChangedPath{Action: "D", Path: "branches/old/"},
+	}, parseSvnlookChanged(output))
+
+	assert.Equal(t, []events.ChangedPath{}, parseSvnlookChanged(""))
+}
@@ -0,0 +1,182 @@
+package svnman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/armadillica/svn-manager/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// backendRepo returns what the backend needs to know to serve the repository.
+func (svn *SVNMan) backendRepo(info repoinfo) (backend.Repo, error) {
+	usernames, err := svn.GetUsernames(info.RepoID)
+	if err != nil {
+		return backend.Repo{}, err
+	}
+	sort.Strings(usernames)
+
+	access := backend.AccessReadWrite
+	if info.Block != nil {
+		if info.Block.ReadOnly {
+			access = backend.AccessReadOnly
+		} else {
+			access = backend.AccessNone
+		}
+	}
+
+	readOnly := info.readOnlyUsers()
+
+	repo := backend.Repo{
+		RepoID:         info.RepoID,
+		ProjectID:      info.ProjectID,
+		Creator:        info.Creator,
+		CreatedOn:      info.Creation,
+		RepoPath:       svn.repoPath(info.RepoID),
+		Htpasswd:       svn.htpasswd(info.RepoID),
+		Usernames:      usernames,
+		Access:         access,
+		ReadOnlyUsers:  []string{},
+		ReadWriteUsers: []string{},
+		AuthzRules:     expandAuthzRules(info.Authz, usernames, readOnly),
+	}
+	for _, username := range usernames {
+		if readOnly[username] {
+			repo.ReadOnlyUsers = append(repo.ReadOnlyUsers, username)
+		} else {
+			repo.ReadWriteUsers = append(repo.ReadWriteUsers, username)
+		}
+	}
+	if svn.userDir != "" {
+		repo.Htpasswd = svn.userHtpasswd()
+		repo.GroupFile = svn.membersPath(info.RepoID)
+	}
+	if info.Authz != nil {
+		repo.AuthzFile = svn.authzPath(info.RepoID)
+	}
+	return repo, nil
+}
+
+// writeConfig (re)writes the backend config file for the repository.
+// Returns whether the file changed, so that needless reloads can be avoided.
+func (svn *SVNMan) writeConfig(info repoinfo) (bool, error) {
+	if err := svn.migrateUsers(info.RepoID, log.WithField("repo_id", info.RepoID)); err != nil {
+		return false, err
+	}
+	repo, err := svn.backendRepo(info)
+	if err != nil {
+		return false, err
+	}
+	conf, err := svn.backend.RenderConfig(repo)
+	if err != nil {
+		return false, err
+	}
+
+	confFile := svn.confPath(info.RepoID)
+	if existing, err := ioutil.ReadFile(confFile); err == nil && bytes.Equal(existing, conf) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(confFile), 0750); err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(confFile, conf, 0644)
+}
+
+// rewriteConfig writes the backend config file based on the repository's info file.
+func (svn *SVNMan) rewriteConfig(repoID string) (bool, error) {
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		return false, err
+	}
+	return svn.writeConfig(info)
+}
+
+// WriteMissingConfigs writes the backend config of every repository that doesn't have one,
+// for example after switching to another backend, and of every repository that still has to
+// be moved into the user directory.
+func (svn *SVNMan) WriteMissingConfigs(logFields log.Fields) error {
+	_, err := svn.writeConfigs(logFields, true)
+	return err
+}
+
+// RewriteConfigs rewrites the backend config of every repository, for example after the
+// Apache template was changed. Returns the previous contents of the configs that changed, by
+// repository ID, which are nil for configs that didn't exist.
+func (svn *SVNMan) RewriteConfigs(logFields log.Fields) (map[string][]byte, error) {
+	return svn.writeConfigs(logFields, false)
+}
+
+// RestoreConfigs puts back the backend configs as they were before RewriteConfigs, and
+// queues a reload.
+func (svn *SVNMan) RestoreConfigs(previous map[string][]byte, logFields log.Fields) error {
+	var lastErr error
+	for repoID, contents := range previous {
+		confFile := svn.confPath(repoID)
+		logger := log.WithFields(logFields).WithFields(log.Fields{
+			"repo_id":     repoID,
+			"config_file": confFile,
+		})
+		if contents == nil {
+			if err := os.Remove(confFile); err != nil && !os.IsNotExist(err) {
+				logger.WithError(err).Error("unable to remove backend config")
+				lastErr = err
+				continue
+			}
+			svn.backend.QueueReload(repoID, "")
+		} else {
+			if err := writeFileAtomically(confFile, contents, 0644); err != nil {
+				logger.WithError(err).Error("unable to restore backend config")
+				lastErr = err
+				continue
+			}
+			svn.backend.QueueReload(repoID, confFile)
+		}
+		logger.Info("backend config restored")
+	}
+	return lastErr
+}
+
+// writeConfigs writes the backend configs, and queues a reload when any of them changed.
+// Returns the previous contents of the changed configs, by repository ID.
+func (svn *SVNMan) writeConfigs(logFields log.Fields, onlyMissing bool) (map[string][]byte, error) {
+	repoIDs, err := svn.repoIDs()
+	if err != nil {
+		log.WithFields(logFields).WithError(err).Error("unable to list repositories")
+		return nil, err
+	}
+
+	previous := map[string][]byte{}
+	for _, repoID := range repoIDs {
+		confFile := svn.confPath(repoID)
+		if _, err := os.Stat(confFile); onlyMissing && !os.IsNotExist(err) && !svn.needsUserMigration(repoID) {
+			continue
+		}
+		logger := log.WithFields(logFields).WithFields(log.Fields{
+			"repo_id":     repoID,
+			"config_file": confFile,
+		})
+		contents, err := ioutil.ReadFile(confFile)
+		if os.IsNotExist(err) {
+			contents = nil
+		} else if err != nil {
+			logger.WithError(err).Error("unable to read backend config")
+			continue
+		}
+		changed, err := svn.rewriteConfig(repoID)
+		if err != nil {
+			logger.WithError(err).Error("unable to write backend config")
+			continue
+		}
+		if !changed {
+			continue
+		}
+		logger.Info("backend config written")
+		svn.backend.QueueReload(repoID, confFile)
+		previous[repoID] = contents
+	}
+	return previous, nil
+}
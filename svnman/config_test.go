@@ -0,0 +1,119 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/armadillica/svn-manager/backend"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestBackendRepo(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	logFields := log.Fields{"in": "unittest"}
+	assert.Nil(t, s.svn.ModifyAccess("my-repo-id", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{
+			{Username: "zed", Password: "$2y$05$cWZN0CJN"},
+			{Username: "alice", Password: "$2y$05$cW---ZN0CJN"},
+		},
+	}, logFields))
+
+	info, err := s.svn.readRepoInfo("my-repo-id")
+	assert.Nil(t, err)
+	repo, err := s.svn.backendRepo(info)
+	assert.Nil(t, err)
+	assert.Equal(t, "my-repo-id", repo.RepoID)
+	assert.Equal(t, s.svn.repoPath("my-repo-id"), repo.RepoPath)
+	assert.Equal(t, s.svn.htpasswd("my-repo-id"), repo.Htpasswd)
+	assert.Equal(t, []string{"alice", "zed"}, repo.Usernames)
+	assert.Equal(t, backend.AccessReadWrite, repo.Access)
+
+	info.Block = &blockinfo{ReadOnly: true}
+	repo, err = s.svn.backendRepo(info)
+	assert.Nil(t, err)
+	assert.Equal(t, backend.AccessReadOnly, repo.Access)
+
+	info.Block = &blockinfo{}
+	repo, err = s.svn.backendRepo(info)
+	assert.Nil(t, err)
+	assert.Equal(t, backend.AccessNone, repo.Access)
+}
+
+func (s *SVNManTestSuite) TestModifyAccessReloadsOnlyOnChange(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	s.mb = mockBackend{}
+	logFields := log.Fields{"in": "unittest"}
+
+	// The Apache config doesn't list the users, so it doesn't change.
+	assert.Nil(t, s.svn.ModifyAccess("my-repo-id", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$cWZN0CJN"}},
+	}, logFields))
+	assert.False(t, s.mb.reloadCalled, "backend reload should not be requested")
+
+	// A missing config file is written again.
+	assert.Nil(t, os.Remove(s.svn.confPath("my-repo-id")))
+	assert.Nil(t, s.svn.ModifyAccess("my-repo-id", ModifyAccess{Revoke: []string{"alice"}}, logFields))
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+	_, err := os.Stat(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err, "backend config should have been written")
+}
+
+func (s *SVNManTestSuite) TestWriteMissingConfigs(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	s.createTestRepo(t, "other-repo-id")
+	assert.Nil(t, os.Remove(s.svn.confPath("my-repo-id")))
+	s.mb = mockBackend{}
+
+	assert.Nil(t, s.svn.WriteMissingConfigs(log.Fields{"in": "unittest"}))
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+	apa := s.readApacheConfig(t, "my-repo-id")
+	assert.Contains(t, apa, "<Location /repo/my-repo-id>")
+
+	// Nothing to do when every repository has a config file.
+	s.mb = mockBackend{}
+	assert.Nil(t, s.svn.WriteMissingConfigs(log.Fields{"in": "unittest"}))
+	assert.False(t, s.mb.reloadCalled, "backend reload should not be requested")
+}
+
+func (s *SVNManTestSuite) TestRewriteConfigs(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	s.createTestRepo(t, "other-repo-id")
+	s.mb = mockBackend{}
+
+	// Nothing changes with the same template.
+	previous, err := s.svn.RewriteConfigs(log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	assert.Empty(t, previous)
+	assert.False(t, s.mb.reloadCalled, "backend reload should not be requested")
+
+	// An outdated config file is rewritten.
+	assert.Nil(t, ioutil.WriteFile(s.svn.confPath("other-repo-id"), []byte("outdated"), 0644))
+	previous, err = s.svn.RewriteConfigs(log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]byte{"other-repo-id": []byte("outdated")}, previous)
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+	apa := s.readApacheConfig(t, "other-repo-id")
+	assert.Contains(t, apa, "<Location /repo/other-repo-id>")
+}
+
+func (s *SVNManTestSuite) TestRestoreConfigs(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	s.createTestRepo(t, "other-repo-id")
+	assert.Nil(t, ioutil.WriteFile(s.svn.confPath("other-repo-id"), []byte("outdated"), 0644))
+	assert.Nil(t, os.Remove(s.svn.confPath("my-repo-id")))
+
+	previous, err := s.svn.RewriteConfigs(log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	assert.Len(t, previous, 2)
+
+	s.mb = mockBackend{}
+	assert.Nil(t, s.svn.RestoreConfigs(previous, log.Fields{"in": "unittest"}))
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+	contents, err := ioutil.ReadFile(s.svn.confPath("other-repo-id"))
+	assert.Nil(t, err)
+	assert.Equal(t, "outdated", string(contents))
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.True(t, os.IsNotExist(err), "config that didn't exist before should be removed")
+}
@@ -1,29 +1,16 @@
 package svnman
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"time"
 
-	yaml "gopkg.in/yaml.v2"
-
+	"github.com/armadillica/svn-manager/events"
 	log "github.com/sirupsen/logrus"
 )
 
-const apacheTemplate = `# Location directive for project %q
-<Location /repo/%s>
-    DAV svn
-    SVNPath %s
-    AuthType Basic
-    AuthName %q
-    AuthUserFile %s
-    Require valid-user
-</Location>
-`
-
 // Stored as YAML in every SVN repository we create.
 type repoinfo struct {
 	AppName   string    `yaml:"app_name"`
@@ -32,36 +19,179 @@ type repoinfo struct {
 	RepoID    string    `yaml:"repo_id"`
 	ProjectID string    `yaml:"project_id"`
 	Creator   string    `yaml:"creator"`
+
+	Block  *blockinfo                   `yaml:"blocked,omitempty"`
+	Hooks  map[string]map[string]string `yaml:"hooks,omitempty"` // hook name to parameters.
+	Verify *verifyinfo                  `yaml:"last_verification,omitempty"`
+	Authz  *authzinfo                   `yaml:"authz,omitempty"`
+
+	ReadOnlyUsers []string `yaml:"read_only_users,omitempty"` // sorted.
 }
 
-// CreateRepo creates a repository and Apache location directive.
+// Stored in repoinfo when the repository has been blocked.
+type blockinfo struct {
+	ReadOnly  bool      `yaml:"read_only"`
+	Reason    string    `yaml:"reason"`
+	BlockedOn time.Time `yaml:"blocked_on"`
+}
+
+// CreateRepo creates a repository and its backend config.
 func (svn *SVNMan) CreateRepo(repoInfo CreateRepo, logFields log.Fields) error {
-	repodir := svn.repoPath(repoInfo.RepoID)
-	apafile := svn.apaConfPath(repoInfo.RepoID)
+	err := svn.createRepo(repoInfo, logFields)
+	svn.publishRepoEvent(events.RepoCreated, events.RepoEvent{
+		RepoID:    repoInfo.RepoID,
+		ProjectID: repoInfo.ProjectID,
+		Creator:   repoInfo.Creator,
+	}, err)
+	return err
+}
 
-	logger := log.WithFields(logFields).WithFields(log.Fields{
+func (svn *SVNMan) createRepo(repoInfo CreateRepo, logFields log.Fields) error {
+	logger := svn.createRepoLogger(repoInfo, logFields)
+	info, err := svn.createRepoDir(repoInfo, svn.repoCreationSteps(), logger)
+	if err != nil {
+		return err
+	}
+	if err := svn.enableRepo(info, logger); err != nil {
+		logger.WithError(err).Warning("unable to enable repository, removing it again")
+		svn.discardRepo(repoInfo.RepoID, logger)
+		return err
+	}
+	return nil
+}
+
+func (svn *SVNMan) createRepoLogger(repoInfo CreateRepo, logFields log.Fields) *log.Entry {
+	return log.WithFields(logFields).WithFields(log.Fields{
 		"repo_id":     repoInfo.RepoID,
 		"project_id":  repoInfo.ProjectID,
 		"creator":     repoInfo.Creator,
-		"repo_dir":    repodir,
-		"apache_file": apafile,
+		"repo_dir":    svn.repoPath(repoInfo.RepoID),
+		"config_file": svn.confPath(repoInfo.RepoID),
 	})
+}
+
+// Directory in repoRoot in which new repositories are assembled. It is on the same
+// filesystem as the repositories themselves, so that moving them into place is atomic.
+const stagingDirName = ".staging"
+
+// repoCreationStep is a single step in assembling a new repository in its staging directory.
+type repoCreationStep struct {
+	name string
+	run  func(svn *SVNMan, stagingDir string, info repoinfo) error
+}
+
+// repoCreationSteps returns the steps of assembling a new repository, in order.
+func (svn *SVNMan) repoCreationSteps() []repoCreationStep {
+	if svn.creationSteps != nil {
+		return svn.creationSteps
+	}
+	return []repoCreationStep{
+		{"svnadmin create", (*SVNMan).stageSvnadminCreate},
+		{"info file", (*SVNMan).stageRepoInfo},
+		{"htpasswd", (*SVNMan).stageHtpasswd},
+		{"hooks", (*SVNMan).stageHooks},
+	}
+}
+
+// RemoveStaleStaging removes what is left in the staging directory. Repositories are only
+// staged while being created, so anything there at startup was left behind by a crash.
+func (svn *SVNMan) RemoveStaleStaging(logFields log.Fields) error {
+	stagingRoot := filepath.Join(svn.repoRoot, stagingDirName)
+	logger := log.WithFields(logFields).WithField("staging_dir", stagingRoot)
+
+	staged, err := ioutil.ReadDir(stagingRoot)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		logger.WithError(err).Error("unable to read staging directory")
+		return err
+	}
+	for _, entry := range staged {
+		path := filepath.Join(stagingRoot, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			logger.WithError(err).WithField("path", path).Error("unable to remove stale staging directory")
+			return err
+		}
+		logger.WithField("path", path).Warning("removed stale staging directory")
+	}
+	return nil
+}
+
+// createRepoDir creates the SVN repository by performing the given steps, normally those
+// of repoCreationSteps(). It is not accessible via the backend until enableRepo() is called.
+//
+// The repository is assembled in a staging directory, and only moved into place when
+// complete. As a result, a failure never leaves a partial repository behind, and nothing
+// has to be discarded when this returns an error.
+func (svn *SVNMan) createRepoDir(repoInfo CreateRepo, steps []repoCreationStep, logger *log.Entry) (repoinfo, error) {
+	repodir := svn.repoPath(repoInfo.RepoID)
 
 	if _, err := os.Stat(repodir); err == nil {
 		logger.Warning("repository already exists")
-		return ErrAlreadyExists
+		return repoinfo{}, ErrAlreadyExists
 	}
 
 	logger.Info("creating repository")
-	if err := os.MkdirAll(repodir, 0750); err != nil {
-		return err
+	stagingRoot := filepath.Join(svn.repoRoot, stagingDirName)
+	if err := os.MkdirAll(stagingRoot, 0750); err != nil {
+		logger.WithError(err).Error("unable to create staging directory")
+		return repoinfo{}, err
 	}
-	if err := os.MkdirAll(filepath.Dir(apafile), 0750); err != nil {
-		return err
+	stagingDir, err := ioutil.TempDir(stagingRoot, repoInfo.RepoID+"-")
+	if err != nil {
+		logger.WithError(err).Error("unable to create staging directory")
+		return repoinfo{}, err
+	}
+	// After a successful move this no longer exists, and there is nothing to remove.
+	defer os.RemoveAll(stagingDir)
+	if err := os.Chmod(stagingDir, 0750); err != nil {
+		return repoinfo{}, err
+	}
+
+	info := repoinfo{
+		AppName:   svn.appName,
+		AppVer:    svn.appVersion,
+		Creation:  time.Now().UTC(),
+		RepoID:    repoInfo.RepoID,
+		ProjectID: repoInfo.ProjectID,
+		Creator:   repoInfo.Creator,
+	}
+	for _, step := range steps {
+		if err := step.run(svn, stagingDir, info); err != nil {
+			logger.WithError(err).WithField("step", step.name).Warning("unable to create repository")
+			return repoinfo{}, err
+		}
 	}
 
-	// Create the SVN repository. This must happen first, because svnadmin wants the dir to be empty.
-	out, err := exec.Command("svnadmin", "create", "--fs-type", "fsfs", repodir).Output()
+	if err := os.MkdirAll(filepath.Dir(repodir), 0750); err != nil {
+		logger.WithError(err).Warning("unable to create path for repository")
+		return repoinfo{}, err
+	}
+	// Renaming replaces empty directories, so make sure nobody beat us to it.
+	defer svn.createLocks.lock(repoInfo.RepoID).Unlock()
+	if _, err := os.Stat(repodir); err == nil {
+		logger.Warning("repository was created concurrently")
+		return repoinfo{}, ErrAlreadyExists
+	}
+	if err := os.Rename(stagingDir, repodir); err != nil {
+		if _, statErr := os.Stat(repodir); os.IsExist(err) || statErr == nil {
+			logger.WithError(err).Warning("repository was created concurrently")
+			return repoinfo{}, ErrAlreadyExists
+		}
+		logger.WithError(err).Warning("unable to move repository into place")
+		return repoinfo{}, err
+	}
+	return info, nil
+}
+
+// stageSvnadminCreate creates the SVN repository itself. This must happen first,
+// because svnadmin wants the directory to be empty.
+func (svn *SVNMan) stageSvnadminCreate(stagingDir string, info repoinfo) error {
+	logger := log.WithFields(log.Fields{
+		"repo_id":     info.RepoID,
+		"staging_dir": stagingDir,
+	})
+	out, err := exec.Command("svnadmin", "create", "--fs-type", "fsfs", stagingDir).Output()
 	if err != nil {
 		switch e := err.(type) {
 		case *exec.ExitError:
@@ -72,43 +202,45 @@ func (svn *SVNMan) CreateRepo(repoInfo CreateRepo, logFields log.Fields) error {
 		return err
 	}
 	logger.WithField("stdout", string(out)).Debug("'svnadmin create' successful")
+	return nil
+}
 
-	// Create the info file.
-	info := repoinfo{
-		svn.appName,
-		svn.appVersion,
-		time.Now().UTC(),
-		repoInfo.RepoID,
-		repoInfo.ProjectID,
-		repoInfo.Creator,
-	}
-	infobytes, err := yaml.Marshal(&info)
-	if err != nil {
-		return err
-	}
-	if err = ioutil.WriteFile(filepath.Join(repodir, "info.yaml"), infobytes, 0644); err != nil {
-		return err
-	}
+// stageRepoInfo creates the info file.
+func (svn *SVNMan) stageRepoInfo(stagingDir string, info repoinfo) error {
+	return writeRepoInfoFile(filepath.Join(stagingDir, "info.yaml"), info)
+}
 
-	// Create an empty htpasswd file.
-	htpasswd := filepath.Join(repodir, "htpasswd")
-	if err = ioutil.WriteFile(htpasswd, []byte{}, 0640); err != nil {
-		return err
+// stageHtpasswd creates an empty htpasswd file.
+func (svn *SVNMan) stageHtpasswd(stagingDir string, info repoinfo) error {
+	return ioutil.WriteFile(filepath.Join(stagingDir, "htpasswd"), []byte{}, 0640)
+}
+
+// stageHooks installs the hook scripts, so that we get notified of commits.
+func (svn *SVNMan) stageHooks(stagingDir string, info repoinfo) error {
+	return svn.installHooks(stagingDir, info.RepoID, nil)
+}
+
+// discardRepo removes the repository and its backend config file, after creating,
+// loading or restoring it failed halfway.
+func (svn *SVNMan) discardRepo(repoID string, logger *log.Entry) {
+	if err := os.Remove(svn.confPath(repoID)); err != nil && !os.IsNotExist(err) {
+		logger.WithError(err).Error("unable to remove backend config of failed repository")
 	}
+	if err := os.RemoveAll(svn.repoPath(repoID)); err != nil {
+		logger.WithError(err).Error("unable to remove failed repository")
+	}
+	svn.userIndex.removeRepo(repoID)
+}
 
-	// Create the Apache configuration file.
-	conf := fmt.Sprintf(apacheTemplate,
-		repoInfo.ProjectID,
-		repoInfo.RepoID,
-		repodir,
-		fmt.Sprintf("Blender Cloud SVN repository %q", repoInfo.RepoID),
-		htpasswd)
-	if err = ioutil.WriteFile(apafile, []byte(conf), 0644); err != nil {
+// enableRepo makes the repository accessible by creating its backend config file.
+func (svn *SVNMan) enableRepo(info repoinfo, logger *log.Entry) error {
+	if _, err := svn.writeConfig(info); err != nil {
 		return err
 	}
+	svn.indexRepoUsers(info.RepoID)
 
-	logger.Debug("repository created, requesting Apache restart")
-	svn.restarter.QueueRestart()
+	logger.Debug("repository created, requesting backend reload")
+	svn.backend.QueueReload(info.RepoID, svn.confPath(info.RepoID))
 
 	return nil
 }
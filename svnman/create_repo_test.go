@@ -1,10 +1,12 @@
 package svnman
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -59,7 +61,7 @@ func (s *SVNManTestSuite) TestCreateRepoHappy(t *check.C) {
 	}
 
 	// Check Apache location directive file.
-	apache := filepath.Join(s.svn.apacheConfigDir, "12", "svn-"+repoInfo.RepoID+".conf")
+	apache := filepath.Join(s.svn.configDir, "12", "svn-"+repoInfo.RepoID+".conf")
 	apabytes, err := ioutil.ReadFile(apache)
 	if err != nil {
 		assert.Fail(t, err.Error(), "file %q should exist", apache)
@@ -71,7 +73,7 @@ func (s *SVNManTestSuite) TestCreateRepoHappy(t *check.C) {
 		assert.Contains(t, apa, `\"1234\"`, "Auth realm should be quoted properly")
 	}
 
-	assert.True(t, s.mr.restartCalled, "Apache restart not requested")
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
 }
 
 func (s *SVNManTestSuite) TestCreateRepoAlreadyExists(t *check.C) {
@@ -88,3 +90,147 @@ func (s *SVNManTestSuite) TestCreateRepoAlreadyExists(t *check.C) {
 	err = s.svn.CreateRepo(repoInfo, logFields)
 	assert.Equal(t, ErrAlreadyExists, err)
 }
+
+// assertNoRepoLeftovers checks that a failed creation left nothing behind.
+func (s *SVNManTestSuite) assertNoRepoLeftovers(t *check.C, repoID string) {
+	_, err := os.Stat(s.svn.repoPath(repoID))
+	assert.True(t, os.IsNotExist(err), "repository %q should not exist", repoID)
+	_, err = os.Stat(s.svn.confPath(repoID))
+	assert.True(t, os.IsNotExist(err), "Apache config of %q should not exist", repoID)
+
+	staged, err := ioutil.ReadDir(filepath.Join(s.svn.repoRoot, stagingDirName))
+	assert.Nil(t, err)
+	assert.Empty(t, staged, "staging directory should be empty")
+}
+
+func (s *SVNManTestSuite) TestCreateRepoConcurrently(t *check.C) {
+	repoInfo := CreateRepo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "dr. Stüvel <sybren@blender.studio>",
+	}
+	logFields := log.Fields{"in": "unittest"}
+
+	// Both creations are staged before either of them moves its repository into place.
+	staged := sync.WaitGroup{}
+	staged.Add(2)
+	s.svn.creationSteps = append(s.svn.repoCreationSteps(), repoCreationStep{"wait for the other",
+		func(svn *SVNMan, stagingDir string, info repoinfo) error {
+			staged.Done()
+			staged.Wait()
+			return nil
+		}})
+	defer func() { s.svn.creationSteps = nil }()
+
+	errs := make(chan error, 2)
+	for idx := 0; idx < 2; idx++ {
+		go func() { errs <- s.svn.CreateRepo(repoInfo, logFields) }()
+	}
+	results := []error{<-errs, <-errs}
+	assert.Contains(t, results, nil)
+	assert.Contains(t, results, ErrAlreadyExists)
+
+	s.assertRepoCreated(t, repoInfo.RepoID)
+	leftovers, err := ioutil.ReadDir(filepath.Join(s.svn.repoRoot, stagingDirName))
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers, "staging directory should be empty")
+}
+
+func (s *SVNManTestSuite) TestCreateRepoStepFailure(t *check.C) {
+	repoInfo := CreateRepo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "dr. Stüvel <sybren@blender.studio>",
+	}
+	logFields := log.Fields{"in": "unittest"}
+	injected := errors.New("injected failure")
+
+	steps := s.svn.repoCreationSteps()
+	for idx, step := range steps {
+		s.svn.creationSteps = append([]repoCreationStep{}, steps...)
+		s.svn.creationSteps[idx].run = func(svn *SVNMan, stagingDir string, info repoinfo) error {
+			// Let the real step do its work, so that there is something to clean up.
+			step.run(svn, stagingDir, info)
+			return injected
+		}
+		err := s.svn.CreateRepo(repoInfo, logFields)
+		s.svn.creationSteps = nil
+
+		assert.Equal(t, injected, err, "step %q", step.name)
+		s.assertNoRepoLeftovers(t, repoInfo.RepoID)
+		assert.False(t, s.mb.reloadCalled, "backend should not be reloaded after failing step %q", step.name)
+	}
+
+	// A retry should not be hindered by the failures.
+	err := s.svn.CreateRepo(repoInfo, logFields)
+	assert.Nil(t, err)
+}
+
+func (s *SVNManTestSuite) TestCreateRepoMoveFailure(t *check.C) {
+	repoInfo := CreateRepo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "dr. Stüvel <sybren@blender.studio>",
+	}
+	logFields := log.Fields{"in": "unittest"}
+
+	// A file where the prefix directory should be makes moving into place impossible.
+	prefixDir := filepath.Dir(s.svn.repoPath(repoInfo.RepoID))
+	assert.Nil(t, ioutil.WriteFile(prefixDir, []byte("in the way"), 0644))
+
+	err := s.svn.CreateRepo(repoInfo, logFields)
+	assert.NotNil(t, err)
+	assert.False(t, s.mb.reloadCalled, "backend should not be reloaded")
+	staged, err := ioutil.ReadDir(filepath.Join(s.svn.repoRoot, stagingDirName))
+	assert.Nil(t, err)
+	assert.Empty(t, staged, "staging directory should be empty")
+
+	assert.Nil(t, os.Remove(prefixDir))
+	err = s.svn.CreateRepo(repoInfo, logFields)
+	assert.Nil(t, err)
+}
+
+func (s *SVNManTestSuite) TestRemoveStaleStaging(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+
+	// Nothing to do before anything was ever staged.
+	assert.Nil(t, s.svn.RemoveStaleStaging(logFields))
+
+	stale := filepath.Join(s.svn.repoRoot, stagingDirName, "1234-123456")
+	assert.Nil(t, os.MkdirAll(filepath.Join(stale, "db"), 0750))
+	assert.Nil(t, s.svn.RemoveStaleStaging(logFields))
+	s.assertNoRepoLeftovers(t, "1234")
+}
+
+func (s *SVNManTestSuite) TestCreateRepoConfigFailure(t *check.C) {
+	repoInfo := CreateRepo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "dr. Stüvel <sybren@blender.studio>",
+	}
+	logFields := log.Fields{"in": "unittest"}
+
+	// A directory where the Apache config file should be makes writing it impossible.
+	apafile := s.svn.confPath(repoInfo.RepoID)
+	assert.Nil(t, os.MkdirAll(apafile, 0755))
+
+	err := s.svn.CreateRepo(repoInfo, logFields)
+	assert.NotNil(t, err)
+	assert.False(t, s.mb.reloadCalled, "backend should not be reloaded")
+	_, err = os.Stat(s.svn.repoPath(repoInfo.RepoID))
+	assert.True(t, os.IsNotExist(err), "repository should have been removed")
+
+	os.RemoveAll(apafile)
+	err = s.svn.CreateRepo(repoInfo, logFields)
+	assert.Nil(t, err)
+	s.assertRepoCreated(t, repoInfo.RepoID)
+}
+
+// assertRepoCreated checks that the repository exists and is accessible via Apache.
+func (s *SVNManTestSuite) assertRepoCreated(t *check.C, repoID string) {
+	_, err := os.Stat(filepath.Join(s.svn.repoPath(repoID), "format"))
+	assert.Nil(t, err, "repository %q should exist", repoID)
+	_, err = os.Stat(s.svn.confPath(repoID))
+	assert.Nil(t, err, "Apache config of %q should exist", repoID)
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+}
@@ -5,59 +5,85 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/armadillica/svn-manager/events"
 	log "github.com/sirupsen/logrus"
 )
 
-// DeleteRepo moves a repository into the attic.
+// DeleteRepo moves a repository into the attic. Deleting a repository that doesn't exist is
+// not an error, but doesn't publish an event either.
 func (svn *SVNMan) DeleteRepo(repoID string, logFields log.Fields) error {
+	event := events.RepoEvent{RepoID: repoID}
+
+	// The info file is moved into the attic too, so read it while we still can.
+	if info, err := svn.readRepoInfo(repoID); err == nil {
+		event.ProjectID = info.ProjectID
+		event.Creator = info.Creator
+	}
+
+	found, err := svn.deleteRepo(repoID, logFields)
+	if found || err != nil {
+		svn.publishRepoEvent(events.RepoDeleted, event, err)
+	}
+	return err
+}
+
+// deleteRepo moves the repository and its backend config into the attic, and returns
+// whether there was anything to move.
+func (svn *SVNMan) deleteRepo(repoID string, logFields log.Fields) (bool, error) {
 	logger := log.WithFields(logFields)
 	logger.Debug("deleting repository")
 
 	timestamp := time.Now()
-	apaConfPath := svn.apaConfPath(repoID)
-	apaAtticPath := svn.apaAtticPath(repoID, timestamp)
+	confPath := svn.confPath(repoID)
+	confAtticPath := svn.confAtticPath(repoID, timestamp)
 	repoPath := svn.repoPath(repoID)
 	atticPath := svn.atticPath(repoID, timestamp)
 
 	logger = logger.WithFields(log.Fields{
-		"apa_conf":  apaConfPath,
-		"apa_attic": apaAtticPath,
-		"repo":      repoPath,
-		"attic":     atticPath,
+		"conf":       confPath,
+		"conf_attic": confAtticPath,
+		"repo":       repoPath,
+		"attic":      atticPath,
 	})
 
 	if err := os.MkdirAll(filepath.Dir(atticPath), 0750); err != nil {
 		logger.WithError(err).Error("unable to create attic path for repo")
-		return ErrDeletion
+		return false, ErrDeletion
 	}
-	if err := os.MkdirAll(filepath.Dir(apaAtticPath), 0750); err != nil {
-		logger.WithError(err).Error("unable to create attic path for Apache config")
-		return ErrDeletion
+	if err := os.MkdirAll(filepath.Dir(confAtticPath), 0750); err != nil {
+		logger.WithError(err).Error("unable to create attic path for backend config")
+		return false, ErrDeletion
 	}
 
-	// Remove the Apache configuration first. With that, the repository
+	// Remove the backend config first. With that, the repository
 	// should be inaccessible, even when the repo files themselves cannot
 	// be moved to the attic. Doing it the other way around (repo dir first)
-	// will cause errors when the Apache file cannot be moved but the repo can.
-	err := os.Rename(apaConfPath, apaAtticPath)
+	// will cause errors when the config file cannot be moved but the repo can.
+	found := true
+	err := os.Rename(confPath, confAtticPath)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			logger.WithError(err).Error("unable to move Apache config to attic")
-			return ErrDeletion
+			logger.WithError(err).Error("unable to move backend config to attic")
+			return false, ErrDeletion
 		}
-		logger.Warning("trying to remove non-existant Apache config file")
+		logger.Warning("trying to remove non-existant backend config file")
+		found = false
 	}
 
 	err = os.Rename(repoPath, atticPath)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			logger.WithError(err).Error("unable to move repository to attic")
-			return ErrDeletion
+			return found, ErrDeletion
 		}
 		logger.Warning("trying to remove non-existant repository")
+	} else {
+		found = true
 	}
 
-	svn.restarter.QueueRestart()
+	svn.userIndex.removeRepo(repoID)
+	svn.statsCache.forget(repoID)
+	svn.backend.QueueReload(repoID, "")
 	logger.Info("repository deleted")
-	return nil
+	return found, nil
 }
@@ -21,7 +21,7 @@ func (s *SVNManTestSuite) TestDeleteHappy(t *check.C) {
 		t.Fatalf("Unable to create repo: %s", err)
 	}
 	// Any restarts queued by CreateRepo are irrelevant to this test.
-	s.mr = mockRestarter{}
+	s.mb = mockBackend{}
 
 	if err := s.svn.DeleteRepo("my-repo-id", logFields); err != nil {
 		t.Fatalf("unexpected error deleting repo: %s", err)
@@ -41,19 +41,26 @@ func (s *SVNManTestSuite) TestDeleteHappy(t *check.C) {
 	assert.Equal(t, 1, len(found), "the repository should be moved into the attic")
 
 	// same for the Apache configuration file.
-	glob = filepath.Join(s.svn.apacheConfigDir, "attic", "my", "svn-my-repo-id.conf-2*")
+	glob = filepath.Join(s.svn.configDir, "attic", "my", "svn-my-repo-id.conf-2*")
 	found, err = filepath.Glob(glob)
 	if err != nil {
 		t.Fatalf("error globbing %s: %s", glob, err)
 	}
 	assert.Equal(t, 1, len(found), "the Apache config file should be moved into the attic")
 
-	assert.True(t, s.mr.restartCalled, "an Apache restart should have been queued")
+	assert.True(t, s.mb.reloadCalled, "a backend reload should have been queued")
 }
 
 func (s *SVNManTestSuite) TestDeleteNonExistantRepoHappy(t *check.C) {
 	logFields := log.Fields{"in": "unittest"}
 	err := s.svn.DeleteRepo("my-repo-id", logFields)
 	assert.Nil(t, err)
-	assert.True(t, s.mr.restartCalled, "an Apache restart should have been queued")
+	assert.True(t, s.mb.reloadCalled, "a backend reload should have been queued")
+}
+
+func (s *SVNManTestSuite) TestDeleteNonExistantRepoNoEvent(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	err := s.svn.DeleteRepo("my-repo-id", logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(s.mp.Events()), "nothing was deleted, so nothing should be published")
 }
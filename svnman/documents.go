@@ -1,5 +1,13 @@
 package svnman
 
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var invalidCreatorRegexp = regexp.MustCompile(`[^\pL\d_\-., @<>'()+]+`)
+
 // CreateRepo is contains the info required to create a repository.
 type CreateRepo struct {
 	RepoID    string `json:"repo_id"`
@@ -7,10 +15,30 @@ type CreateRepo struct {
 	Creator   string `json:"creator"` // Full Name <email> notation.
 }
 
+// Normalise converts the repository ID to lower case, and replaces unexpected characters
+// in the creator by spaces.
+func (cr *CreateRepo) Normalise() {
+	// Convert repo ID to lower case, so limit the number of prefixes we have
+	// in the repository directory.
+	cr.RepoID = strings.ToLower(cr.RepoID)
+	cr.Creator = invalidCreatorRegexp.ReplaceAllString(cr.Creator, " ")
+}
+
+// Access levels of users of a repository.
+const (
+	AccessRead  = "read"  // can check out, but not commit.
+	AccessWrite = "write" // can check out and commit.
+)
+
 // ModifyAccessGrantEntry contains info about one user to allow access.
 type ModifyAccessGrantEntry struct {
 	Username string `json:"username"`
-	Password string `json:"password"` // always bcrypted and base64-encoded.
+	// Always bcrypted and base64-encoded. Only optional for users that already have a
+	// password, which they then keep.
+	Password string `json:"password,omitempty"`
+	// AccessRead or AccessWrite. When empty, existing users keep their access level,
+	// and new users get AccessWrite.
+	Access string `json:"access,omitempty"`
 }
 
 // ModifyAccess contains the changes in access rules for users of a specific repository.
@@ -18,3 +46,165 @@ type ModifyAccess struct {
 	Grant  []ModifyAccessGrantEntry `json:"grant"`
 	Revoke []string                 `json:"revoke"` // list of usernames
 }
+
+// SetPassword contains the new password of a user in the user directory.
+type SetPassword struct {
+	Password string `json:"password"` // always bcrypted and base64-encoded.
+}
+
+// UserRepo describes a repository a user has access to.
+type UserRepo struct {
+	RepoID    string `json:"repo_id"`
+	ProjectID string `json:"project_id"`
+}
+
+// Authz contains the path-based authorization rules of a repository.
+type Authz struct {
+	Groups map[string][]string `json:"groups"` // group name to usernames.
+	Rules  []AuthzRule         `json:"rules"`
+}
+
+// AuthzRule determines the access to a path in the repository, and everything below it.
+type AuthzRule struct {
+	Path string `json:"path"` // like "/trunk/release".
+	// Username, "@group" or "*" to "rw", "r", or "" for no access.
+	Access map[string]string `json:"access"`
+}
+
+// BlockRepo contains the info required to block a repository.
+type BlockRepo struct {
+	Reason   string `json:"reason"`
+	ReadOnly bool   `json:"read_only"` // allow checkouts, but refuse commits.
+}
+
+// BlockState describes whether and how a repository is blocked.
+type BlockState struct {
+	Blocked   bool       `json:"blocked"`
+	ReadOnly  bool       `json:"read_only,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	BlockedOn *time.Time `json:"blocked_on,omitempty"`
+}
+
+// HookDescription describes a hook from the hook catalogue.
+type HookDescription struct {
+	Name        string                   `json:"name"`
+	Type        string                   `json:"type"` // SVN hook type, like "pre-commit".
+	Description string                   `json:"description"`
+	Parameters  map[string]HookParameter `json:"parameters"`
+}
+
+// HookParameter describes a parameter of a hook from the hook catalogue.
+type HookParameter struct {
+	Description string `json:"description"`
+	Default     string `json:"default"`
+}
+
+// InstalledHook describes a hook installed in a repository.
+type InstalledHook struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"`
+}
+
+// ModifyHooks contains the hooks to install into or remove from a specific repository.
+type ModifyHooks struct {
+	Install []InstalledHook `json:"install"`
+	Remove  []string        `json:"remove"` // list of hook names
+}
+
+// AtticEntry describes a deleted repository in the attic.
+type AtticEntry struct {
+	RepoID    string     `json:"repo_id"`
+	Timestamp string     `json:"timestamp"` // as used in the attic, formatted as RFC3339fs.
+	DeletedOn time.Time  `json:"deleted_on"`
+	ProjectID string     `json:"project_id,omitempty"`
+	Creator   string     `json:"creator,omitempty"`
+	CreatedOn *time.Time `json:"created_on,omitempty"`
+	DiskSize  int64      `json:"disk_size"` // in bytes
+}
+
+// ListRepos contains the filters and pagination for listing repositories.
+// Empty filters match every repository.
+type ListRepos struct {
+	ProjectID string
+	Creator   string
+	Cursor    string // from RepoList.NextCursor, to continue a previous listing.
+	Limit     int    // maximum number of repositories to return.
+}
+
+// RepoList contains one page of the repository listing.
+type RepoList struct {
+	Repos      []RepoSummary `json:"repos"`
+	NextCursor string        `json:"next_cursor,omitempty"` // empty on the last page.
+}
+
+// RepoSummary describes a repository in a repository listing.
+type RepoSummary struct {
+	RepoID    string    `json:"repo_id"`
+	ProjectID string    `json:"project_id"`
+	Creator   string    `json:"creator"`
+	CreatedOn time.Time `json:"created_on"`
+	UserCount int       `json:"user_count"`
+}
+
+// RepoDetails contains information about a repository, from its info file and from SVN itself.
+type RepoDetails struct {
+	ProjectID        string        `json:"project_id"`
+	Creator          string        `json:"creator"`
+	CreatedOn        time.Time     `json:"created_on"`
+	AppName          string        `json:"app_name"`    // of the SVN Manager that created the repository.
+	AppVersion       string        `json:"app_version"` // of the SVN Manager that created the repository.
+	HeadRevision     int           `json:"head_revision"`
+	LastCommitDate   *time.Time    `json:"last_commit_date,omitempty"`
+	LastCommitAuthor string        `json:"last_commit_author,omitempty"`
+	DiskSize         int64         `json:"disk_size"` // in bytes
+	LastVerification *VerifyResult `json:"last_verification,omitempty"`
+	Users            []UserAccess  `json:"users"` // sorted by username.
+	// Status of the backend config; one of "pending", "active" or "failed". Named after
+	// Apache, but reported for every backend.
+	ApacheStatus string `json:"apache_status"`
+	// Why the backend refused the repository's config. The previous config is still in use,
+	// or the repository is inaccessible when it didn't have one.
+	ApacheConfigError string `json:"apache_config_error,omitempty"`
+}
+
+// UserAccess describes the access level of a user of a repository.
+type UserAccess struct {
+	Username string `json:"username"`
+	Access   string `json:"access"` // AccessRead or AccessWrite.
+}
+
+// DumpRepo contains the options for dumping a repository.
+type DumpRepo struct {
+	Revisions   string // "N" or "N:M" to dump only those revisions, empty to dump everything.
+	Deltas      bool   // use deltas in the dump output, which is smaller but slower to produce.
+	Compression string // one of DumpCompressions.
+}
+
+// DumpCompressions maps the supported dump compressions to their file extension.
+var DumpCompressions = map[string]string{
+	"":     "",
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// VerifyResult contains the outcome of verifying a repository with 'svnadmin verify'.
+type VerifyResult struct {
+	VerifiedOn   time.Time       `json:"verified_on"`
+	Success      bool            `json:"success"`
+	HeadRevision int             `json:"head_revision"`
+	Failures     []VerifyFailure `json:"failures,omitempty"`
+	Errors       []string        `json:"errors,omitempty"` // not specific to a revision.
+}
+
+// VerifyFailure contains the errors found while verifying a specific revision.
+type VerifyFailure struct {
+	Revision int      `json:"revision"`
+	Errors   []string `json:"errors"`
+}
+
+// BackupState describes the last successful backup of a repository.
+type BackupState struct {
+	BackedUpOn   time.Time `json:"backed_up_on"`
+	HeadRevision int       `json:"head_revision"` // youngest revision in the backup.
+	DiskSize     int64     `json:"disk_size"`     // of the backup, in bytes.
+}
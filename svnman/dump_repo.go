@@ -0,0 +1,153 @@
+package svnman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var validRevisionsRegexp = regexp.MustCompile(`^\d+(:\d+)?$`)
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.count += int64(n)
+	return n, err
+}
+
+// checkRevisions returns ErrInvalidRevision when the revision range is invalid for the repository.
+func (svn *SVNMan) checkRevisions(repoID, revisions string) error {
+	if revisions == "" {
+		return nil
+	}
+	if !validRevisionsRegexp.MatchString(revisions) {
+		return ErrInvalidRevision
+	}
+
+	head, err := svn.headRevision(repoID)
+	if err != nil {
+		return err
+	}
+
+	// The regexp guarantees these are valid numbers, apart from overflow.
+	previous := 0
+	for _, revStr := range strings.Split(revisions, ":") {
+		rev, err := strconv.Atoi(revStr)
+		if err != nil || rev > head || rev < previous {
+			return ErrInvalidRevision
+		}
+		previous = rev
+	}
+	return nil
+}
+
+// DumpRepo streams the output of 'svnadmin dump' to the writer, without buffering it on disk.
+// Cancelling the context aborts the dump. Once anything has been written, errors can only
+// be reported by truncating the output, so nothing is written when the options are invalid.
+func (svn *SVNMan) DumpRepo(ctx context.Context, repoID string, options DumpRepo, w io.Writer, logFields log.Fields) error {
+	repodir := svn.repoPath(repoID)
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"revisions":   options.Revisions,
+		"deltas":      options.Deltas,
+		"compression": options.Compression,
+	})
+
+	if _, err := os.Stat(repodir); os.IsNotExist(err) {
+		logger.Warning("nonexistent repository requested")
+		return ErrNotFound
+	}
+	if _, ok := DumpCompressions[options.Compression]; !ok {
+		return fmt.Errorf("unsupported compression %q", options.Compression)
+	}
+	if err := svn.checkRevisions(repoID, options.Revisions); err != nil {
+		logger.WithError(err).Warning("invalid revisions requested")
+		return err
+	}
+
+	args := []string{"dump", "--quiet"}
+	if options.Revisions != "" {
+		args = append(args, "--revision", options.Revisions)
+	}
+	if options.Deltas {
+		args = append(args, "--deltas")
+	}
+	args = append(args, repodir)
+
+	counter := &countingWriter{w: w}
+	dump := exec.CommandContext(ctx, "svnadmin", args...)
+	var stderr bytes.Buffer
+	dump.Stderr = &stderr
+
+	logger.Info("dumping repository")
+	var err error
+	switch options.Compression {
+	case "":
+		dump.Stdout = counter
+		err = dump.Run()
+	case "gzip":
+		gz := gzip.NewWriter(counter)
+		dump.Stdout = gz
+		err = dump.Run()
+		// Only close on success, so that a broken dump results in a broken gzip stream.
+		if err == nil {
+			err = gz.Close()
+		}
+	case "zstd":
+		err = runCompressed(ctx, dump, counter, "zstd", "--quiet", "--stdout")
+	}
+
+	logger = logger.WithField("bytes_written", counter.count)
+	if err != nil {
+		if ctx.Err() != nil {
+			logger.WithError(ctx.Err()).Warning("repository dump cancelled")
+			return ctx.Err()
+		}
+		logger.WithError(err).WithField("stderr", stderr.String()).Error("unable to dump repository")
+		return err
+	}
+	logger.Info("repository dumped")
+	return nil
+}
+
+// runCompressed runs the command, piping its output through an external compressor into the writer.
+func runCompressed(ctx context.Context, cmd *exec.Cmd, w io.Writer, compressor string, args ...string) error {
+	compress := exec.CommandContext(ctx, compressor, args...)
+	compress.Stdout = w
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	compress.Stdin = pipe
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := compress.Start(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	// The compressor has its own copy of the pipe, so it's fine for Wait() to close ours.
+	cmdErr := cmd.Wait()
+	compressErr := compress.Wait()
+	if cmdErr != nil {
+		return cmdErr
+	}
+	return compressErr
+}
@@ -0,0 +1,89 @@
+package svnman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os/exec"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+const dumpHeader = "SVN-fs-dump-format-version: "
+
+func (s *SVNManTestSuite) TestDumpRepo(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+
+	var buf bytes.Buffer
+	err := s.svn.DumpRepo(context.Background(), "my-repo-id", DumpRepo{}, &buf, logFields)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), dumpHeader)
+	assert.Contains(t, buf.String(), "Revision-number: 0")
+
+	buf.Reset()
+	err = s.svn.DumpRepo(context.Background(), "my-repo-id", DumpRepo{Revisions: "0:0", Deltas: true}, &buf, logFields)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), dumpHeader+"3")
+}
+
+func (s *SVNManTestSuite) TestDumpRepoGzip(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+
+	var buf bytes.Buffer
+	err := s.svn.DumpRepo(context.Background(), "my-repo-id", DumpRepo{Compression: "gzip"}, &buf, log.Fields{})
+	assert.Nil(t, err)
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("unable to read gzip stream: %s", err)
+	}
+	dump, err := ioutil.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Contains(t, string(dump), dumpHeader)
+}
+
+func (s *SVNManTestSuite) TestDumpRepoZstd(t *check.C) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	s.createTestRepo(t, "my-repo-id")
+
+	var buf bytes.Buffer
+	err := s.svn.DumpRepo(context.Background(), "my-repo-id", DumpRepo{Compression: "zstd"}, &buf, log.Fields{})
+	assert.Nil(t, err)
+
+	unzstd := exec.Command("zstd", "--decompress", "--stdout")
+	unzstd.Stdin = &buf
+	dump, err := unzstd.Output()
+	assert.Nil(t, err)
+	assert.Contains(t, string(dump), dumpHeader)
+}
+
+func (s *SVNManTestSuite) TestDumpRepoUnhappy(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	err := s.svn.DumpRepo(ctx, "other-repo", DumpRepo{}, &buf, logFields)
+	assert.Equal(t, ErrNotFound, err)
+
+	for _, revisions := range []string{"1", "0:1", "HEAD", "-1", "1:0", "0:"} {
+		err = s.svn.DumpRepo(ctx, "my-repo-id", DumpRepo{Revisions: revisions}, &buf, logFields)
+		assert.Equal(t, ErrInvalidRevision, err, "revisions %q", revisions)
+	}
+
+	err = s.svn.DumpRepo(ctx, "my-repo-id", DumpRepo{Compression: "rar"}, &buf, logFields)
+	assert.NotNil(t, err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = s.svn.DumpRepo(cancelled, "my-repo-id", DumpRepo{}, &buf, logFields)
+	assert.Equal(t, context.Canceled, err)
+
+	assert.Equal(t, 0, buf.Len(), "nothing should have been written")
+}
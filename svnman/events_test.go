@@ -0,0 +1,93 @@
+package svnman
+
+import (
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) repoEvent(t *check.C, published events.PublishedEvent) events.RepoEvent {
+	event, ok := published.Payload.(events.RepoEvent)
+	if !ok {
+		t.Fatalf("unexpected event payload %#v", published.Payload)
+	}
+	assert.False(t, event.Timestamp.IsZero(), "event should have a timestamp")
+	return event
+}
+
+func (s *SVNManTestSuite) TestRepoEvents(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+
+	err := s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant:  []ModifyAccessGrantEntry{ModifyAccessGrantEntry{Username: "testkees", Password: "$2y$05$cWZN0CJN"}},
+		Revoke: []string{"someone"},
+	}, logFields)
+	assert.Nil(t, err)
+
+	err = s.svn.DeleteRepo("1234", logFields)
+	assert.Nil(t, err)
+
+	published := s.mp.Events()
+	if len(published) != 3 {
+		t.Fatalf("expected 3 events, got %#v", published)
+	}
+
+	assert.Equal(t, events.RepoCreated, published[0].RoutingKey)
+	event := s.repoEvent(t, published[0])
+	assert.Equal(t, "1234", event.RepoID)
+	assert.Equal(t, "59eefa9cf488554678cae036", event.ProjectID)
+	assert.Equal(t, "dr. Stüvel <sybren@blender.studio>", event.Creator)
+	assert.True(t, event.Success)
+	assert.Equal(t, "", event.Error)
+
+	assert.Equal(t, events.RepoAccessChanged, published[1].RoutingKey)
+	event = s.repoEvent(t, published[1])
+	assert.Equal(t, "1234", event.RepoID)
+	assert.Equal(t, "59eefa9cf488554678cae036", event.ProjectID)
+	assert.Equal(t, []string{"testkees"}, event.Granted)
+	assert.Equal(t, []string{"someone"}, event.Revoked)
+	assert.True(t, event.Success)
+
+	assert.Equal(t, events.RepoDeleted, published[2].RoutingKey)
+	event = s.repoEvent(t, published[2])
+	assert.Equal(t, "1234", event.RepoID)
+	assert.Equal(t, "59eefa9cf488554678cae036", event.ProjectID)
+	assert.Equal(t, "dr. Stüvel <sybren@blender.studio>", event.Creator)
+	assert.True(t, event.Success)
+}
+
+func (s *SVNManTestSuite) TestRepoEventsFailure(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+	s.mp.Reset()
+
+	repoInfo := CreateRepo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "someone else <someone@example.com>",
+	}
+	err := s.svn.CreateRepo(repoInfo, logFields)
+	assert.Equal(t, ErrAlreadyExists, err)
+
+	err = s.svn.ModifyAccess("4567", ModifyAccess{Revoke: []string{"someone"}}, logFields)
+	assert.NotNil(t, err)
+
+	published := s.mp.Events()
+	if len(published) != 2 {
+		t.Fatalf("expected 2 events, got %#v", published)
+	}
+
+	assert.Equal(t, events.RepoCreated, published[0].RoutingKey)
+	event := s.repoEvent(t, published[0])
+	assert.False(t, event.Success)
+	assert.Equal(t, ErrAlreadyExists.Error(), event.Error)
+	assert.Equal(t, "someone else <someone@example.com>", event.Creator)
+
+	assert.Equal(t, events.RepoAccessChanged, published[1].RoutingKey)
+	event = s.repoEvent(t, published[1])
+	assert.False(t, event.Success)
+	assert.Equal(t, "4567", event.RepoID)
+	assert.NotEqual(t, "", event.Error)
+}
@@ -0,0 +1,370 @@
+package svnman
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/armadillica/svn-manager/filelocator"
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Marker to recognise hook scripts we generated ourselves.
+const hookScriptMarker = "# Generated by SVN Manager; changes will be overwritten."
+
+// Only hooks that do not read from stdin can be chained by the dispatcher script.
+var supportedHookTypes = map[string]bool{
+	"start-commit": true,
+	"pre-commit":   true,
+	"post-commit":  true,
+}
+
+var validHookParamName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Stored as YAML next to every hook script in the catalogue.
+type hookspec struct {
+	Type        string                   `yaml:"type"`
+	Description string                   `yaml:"description"`
+	Parameters  map[string]hookparamspec `yaml:"parameters"`
+}
+
+type hookparamspec struct {
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+	Pattern     string `yaml:"pattern"`
+}
+
+// Returns the directory containing the hook catalogue.
+func (svn *SVNMan) hookCatalogueDir() (string, error) {
+	if svn.hookCatalogue != "" {
+		return svn.hookCatalogue, nil
+	}
+	return filelocator.FindFile("svn_hooks")
+}
+
+// Returns the directory in the repository that contains the installed hook scripts.
+func repoHookDir(repodir string) string {
+	return filepath.Join(repodir, "hooks", "svnman")
+}
+
+// Returns the path of the hook script that SVN actually runs.
+func hookScriptPath(repodir, hookType string) string {
+	return filepath.Join(repodir, "hooks", hookType)
+}
+
+func (svn *SVNMan) loadHookSpec(name string) (hookspec, error) {
+	spec := hookspec{}
+
+	catalogue, err := svn.hookCatalogueDir()
+	if err != nil {
+		return spec, err
+	}
+	specbytes, err := ioutil.ReadFile(filepath.Join(catalogue, name+".yaml"))
+	if os.IsNotExist(err) {
+		return spec, ErrUnknownHook
+	} else if err != nil {
+		return spec, err
+	}
+	if err := yaml.Unmarshal(specbytes, &spec); err != nil {
+		return spec, err
+	}
+	if !supportedHookTypes[spec.Type] {
+		return spec, fmt.Errorf("hook %q has unsupported type %q", name, spec.Type)
+	}
+	for paramName := range spec.Parameters {
+		if !validHookParamName.MatchString(paramName) {
+			return spec, fmt.Errorf("hook %q has invalid parameter name %q", name, paramName)
+		}
+	}
+	return spec, nil
+}
+
+// ListAvailableHooks returns the hooks in the catalogue.
+func (svn *SVNMan) ListAvailableHooks() ([]HookDescription, error) {
+	catalogue, err := svn.hookCatalogueDir()
+	if err != nil {
+		return nil, err
+	}
+	specfiles, err := filepath.Glob(filepath.Join(catalogue, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(specfiles)
+
+	hooks := make([]HookDescription, 0, len(specfiles))
+	for _, specfile := range specfiles {
+		name := strings.TrimSuffix(filepath.Base(specfile), ".yaml")
+		spec, err := svn.loadHookSpec(name)
+		if err != nil {
+			log.WithField("hook", name).WithError(err).Warning("skipping invalid hook in catalogue")
+			continue
+		}
+
+		params := make(map[string]HookParameter, len(spec.Parameters))
+		for paramName, paramSpec := range spec.Parameters {
+			params[paramName] = HookParameter{
+				Description: paramSpec.Description,
+				Default:     paramSpec.Default,
+			}
+		}
+		hooks = append(hooks, HookDescription{
+			Name:        name,
+			Type:        spec.Type,
+			Description: spec.Description,
+			Parameters:  params,
+		})
+	}
+
+	return hooks, nil
+}
+
+// GetRepoHooks returns the hooks installed in the repository.
+func (svn *SVNMan) GetRepoHooks(repoID string) ([]InstalledHook, error) {
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := make([]InstalledHook, 0, len(info.Hooks))
+	for name, params := range info.Hooks {
+		hooks = append(hooks, InstalledHook{Name: name, Params: params})
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].Name < hooks[j].Name })
+	return hooks, nil
+}
+
+// Returns the parameters to use for the hook, with defaults filled in.
+func checkHookParams(spec hookspec, given map[string]string, logger *log.Entry) (map[string]string, error) {
+	params := map[string]string{}
+	for name, value := range given {
+		paramSpec, ok := spec.Parameters[name]
+		if !ok {
+			logger.WithField("param", name).Warning("unknown hook parameter")
+			return nil, ErrInvalidHookParam
+		}
+		if paramSpec.Pattern != "" {
+			matched, err := regexp.MatchString(paramSpec.Pattern, value)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				logger.WithFields(log.Fields{
+					"param":   name,
+					"value":   value,
+					"pattern": paramSpec.Pattern,
+				}).Warning("hook parameter value does not match pattern")
+				return nil, ErrInvalidHookParam
+			}
+		}
+		params[name] = value
+	}
+	for name, paramSpec := range spec.Parameters {
+		if _, ok := params[name]; !ok {
+			params[name] = paramSpec.Default
+		}
+	}
+	return params, nil
+}
+
+// ModifyHooks installs and removes hooks from the catalogue into a repository.
+func (svn *SVNMan) ModifyHooks(repoID string, mods ModifyHooks, logFields log.Fields) error {
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"install_count": len(mods.Install),
+		"remove_count":  len(mods.Remove),
+	})
+	logger.Debug("modifying repository hooks")
+
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Warning("unable to read repository info")
+		return err
+	}
+	if info.Hooks == nil {
+		info.Hooks = map[string]map[string]string{}
+	}
+
+	// Check everything before touching the repository.
+	removing := map[string]bool{}
+	for _, remove := range mods.Remove {
+		removing[remove] = true
+	}
+	for _, install := range mods.Install {
+		if removing[install.Name] {
+			logger.WithField("hook", install.Name).Warning("hook is both installed and removed")
+			return ErrConflictingHooks
+		}
+	}
+	for _, install := range mods.Install {
+		hookLogger := logger.WithField("hook", install.Name)
+		spec, err := svn.loadHookSpec(install.Name)
+		if err != nil {
+			hookLogger.WithError(err).Warning("unable to load hook spec")
+			return err
+		}
+		params, err := checkHookParams(spec, install.Params, hookLogger)
+		if err != nil {
+			return err
+		}
+		info.Hooks[install.Name] = params
+	}
+	for _, remove := range mods.Remove {
+		delete(info.Hooks, remove)
+	}
+
+	if err := svn.installHooks(svn.repoPath(repoID), repoID, info.Hooks); err != nil {
+		logger.WithError(err).Error("unable to install hooks")
+		return err
+	}
+	if err := svn.writeRepoInfo(info); err != nil {
+		logger.WithError(err).Error("unable to save repository info")
+		return err
+	}
+
+	logger.Info("repository hooks modified")
+	return nil
+}
+
+// WriteHookDispatchers regenerates the hooks of every repository, so that repositories created
+// before commit notification existed, or with another notification URL, notify SVN Manager
+// of their commits too. Failures are logged, and don't stop the other repositories.
+func (svn *SVNMan) WriteHookDispatchers(logFields log.Fields) error {
+	repoIDs, err := svn.repoIDs()
+	if err != nil {
+		log.WithFields(logFields).WithError(err).Error("unable to list repositories")
+		return err
+	}
+
+	for _, repoID := range repoIDs {
+		logger := log.WithFields(logFields).WithField("repo_id", repoID)
+		if err := svn.rewriteHooks(repoID); err != nil {
+			logger.WithError(err).Error("unable to write repository hooks")
+		}
+	}
+	log.WithFields(logFields).WithField("repo_count", len(repoIDs)).Debug("repository hooks written")
+	return nil
+}
+
+// rewriteHooks installs the hooks again, based on the repository's info file.
+func (svn *SVNMan) rewriteHooks(repoID string) error {
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		return err
+	}
+	return svn.installHooks(svn.repoPath(repoID), repoID, info.Hooks)
+}
+
+// installHooks copies the hook scripts into the repository in repodir and (re)generates
+// the dispatcher scripts that call them.
+func (svn *SVNMan) installHooks(repodir, repoID string, hooks map[string]map[string]string) error {
+	// Never overwrite hook scripts that were put there by someone else.
+	for hookType := range supportedHookTypes {
+		filename := hookScriptPath(repodir, hookType)
+		existing, err := ioutil.ReadFile(filename)
+		if err == nil && !bytes.Contains(existing, []byte(hookScriptMarker)) {
+			return fmt.Errorf("refusing to overwrite hook script %s", filename)
+		} else if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	hookdir := repoHookDir(repodir)
+	if err := os.RemoveAll(hookdir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hookdir, 0750); err != nil {
+		return err
+	}
+
+	// Always copy the scripts, so that updates to the catalogue are picked up.
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	byType := map[string][]string{}
+	for _, name := range names {
+		catalogue, err := svn.hookCatalogueDir()
+		if err != nil {
+			return err
+		}
+		spec, err := svn.loadHookSpec(name)
+		if err != nil {
+			return err
+		}
+		script, err := ioutil.ReadFile(filepath.Join(catalogue, name+".sh"))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(hookdir, name), script, 0750); err != nil {
+			return err
+		}
+		byType[spec.Type] = append(byType[spec.Type], name)
+	}
+
+	for hookType := range supportedHookTypes {
+		if err := svn.writeHookDispatcher(repodir, repoID, hookType, byType[hookType], hooks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHookDispatcher writes the actual SVN hook script, which calls the installed hooks in turn.
+// The post-commit script also notifies SVN Manager of the commit.
+// Without anything to call, the script is removed.
+func (svn *SVNMan) writeHookDispatcher(repodir, repoID, hookType string, names []string,
+	hooks map[string]map[string]string) error {
+	filename := hookScriptPath(repodir, hookType)
+	notify := hookType == "post-commit" && svn.notifyURL != ""
+	if len(names) == 0 && !notify {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var script bytes.Buffer
+	fmt.Fprintf(&script, "#!/bin/sh\n%s\n\n", hookScriptMarker)
+	// SVN runs hooks with an empty environment.
+	fmt.Fprint(&script, "PATH=/usr/local/bin:/usr/bin:/bin\nexport PATH\n")
+	fmt.Fprint(&script, "HOOKDIR=\"$(dirname \"$0\")/svnman\"\n")
+	if notify {
+		// Never fail the hook because of this; that would only confuse the SVN user.
+		url := svn.notifyURL + "/repo/" + repoID + "/commits"
+		fmt.Fprintf(&script, `curl --silent --max-time 10 -H 'Content-Type: application/json' `+
+			`--data "{\"revision\": $2}" %s >/dev/null 2>&1 || true`+"\n", shellQuote(url))
+	}
+	for _, name := range names {
+		paramNames := make([]string, 0, len(hooks[name]))
+		for paramName := range hooks[name] {
+			paramNames = append(paramNames, paramName)
+		}
+		sort.Strings(paramNames)
+
+		for _, paramName := range paramNames {
+			fmt.Fprintf(&script, "%s=%s ", hookEnvVar(paramName), shellQuote(hooks[name][paramName]))
+		}
+		fmt.Fprintf(&script, "\"$HOOKDIR/%s\" \"$@\" || exit $?\n", name)
+	}
+
+	return ioutil.WriteFile(filename, script.Bytes(), 0750)
+}
+
+// hookEnvVar returns the name of the environment variable used to pass a parameter to a hook.
+func hookEnvVar(paramName string) string {
+	return "SVNMAN_" + strings.ToUpper(paramName)
+}
+
+// shellQuote quotes the string for safe use in a POSIX shell script.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,235 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+const testHookSpec = `type: pre-commit
+description: Test hook.
+parameters:
+  max_count:
+    description: Some number.
+    default: "3"
+    pattern: "^[0-9]+$"
+  message:
+    description: Some text.
+    default: "it's a test"
+`
+
+const testPostHookSpec = `type: post-commit
+description: Test hook without parameters.
+`
+
+func (s *SVNManTestSuite) createHookCatalogue(t *check.C) {
+	s.svn.hookCatalogue = mustTempDir("", "hooks")
+
+	write := func(fname, content string) {
+		if err := ioutil.WriteFile(filepath.Join(s.svn.hookCatalogue, fname), []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %s: %s", fname, err)
+		}
+	}
+	write("test-hook.yaml", testHookSpec)
+	write("test-hook.sh", "#!/bin/sh\necho test-hook\n")
+	write("post-hook.yaml", testPostHookSpec)
+	write("post-hook.sh", "#!/bin/sh\necho post-hook\n")
+}
+
+func (s *SVNManTestSuite) createTestRepo(t *check.C, repoID string) {
+	repoInfo := CreateRepo{
+		RepoID:    repoID,
+		ProjectID: "59eefa9cf488554678cae036",
+		Creator:   "dr. Stüvel <sybren@blender.studio>",
+	}
+	if err := s.svn.CreateRepo(repoInfo, log.Fields{"in": "unittest"}); err != nil {
+		t.Fatalf("Unable to create repo: %s", err)
+	}
+}
+
+func (s *SVNManTestSuite) TestListAvailableHooks(t *check.C) {
+	s.createHookCatalogue(t)
+	defer os.RemoveAll(s.svn.hookCatalogue)
+
+	hooks, err := s.svn.ListAvailableHooks()
+	assert.Nil(t, err)
+	assert.Equal(t, []HookDescription{
+		HookDescription{
+			Name:        "post-hook",
+			Type:        "post-commit",
+			Description: "Test hook without parameters.",
+			Parameters:  map[string]HookParameter{},
+		},
+		HookDescription{
+			Name:        "test-hook",
+			Type:        "pre-commit",
+			Description: "Test hook.",
+			Parameters: map[string]HookParameter{
+				"max_count": HookParameter{"Some number.", "3"},
+				"message":   HookParameter{"Some text.", "it's a test"},
+			},
+		},
+	}, hooks)
+}
+
+func (s *SVNManTestSuite) TestShippedHookCatalogue(t *check.C) {
+	hooks, err := s.svn.ListAvailableHooks()
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, hook := range hooks {
+		names = append(names, hook.Name)
+	}
+	assert.Contains(t, names, "reject-empty-log-message")
+	assert.Contains(t, names, "max-file-size")
+}
+
+func (s *SVNManTestSuite) TestModifyHooksHappy(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createHookCatalogue(t)
+	defer os.RemoveAll(s.svn.hookCatalogue)
+	s.createTestRepo(t, "1234")
+
+	hooks, err := s.svn.GetRepoHooks("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, []InstalledHook{}, hooks)
+
+	err = s.svn.ModifyHooks("1234", ModifyHooks{
+		Install: []InstalledHook{
+			InstalledHook{Name: "test-hook", Params: map[string]string{"max_count": "47"}},
+			InstalledHook{Name: "post-hook"},
+		},
+	}, logFields)
+	assert.Nil(t, err)
+
+	hooks, err = s.svn.GetRepoHooks("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, []InstalledHook{
+		InstalledHook{Name: "post-hook", Params: map[string]string{}},
+		InstalledHook{Name: "test-hook", Params: map[string]string{"max_count": "47", "message": "it's a test"}},
+	}, hooks)
+
+	hookdir := filepath.Join(s.svn.repoPath("1234"), "hooks")
+	precommit, err := ioutil.ReadFile(filepath.Join(hookdir, "pre-commit"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(precommit), hookScriptMarker)
+	assert.Contains(t, string(precommit), `SVNMAN_MAX_COUNT='47' SVNMAN_MESSAGE='it'\''s a test' "$HOOKDIR/test-hook" "$@" || exit $?`)
+	assert.NotContains(t, string(precommit), "post-hook")
+
+	postcommit, err := ioutil.ReadFile(filepath.Join(hookdir, "post-commit"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(postcommit), `"$HOOKDIR/post-hook" "$@" || exit $?`)
+
+	stat, err := os.Stat(filepath.Join(hookdir, "svnman", "test-hook"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, os.FileMode(0), stat.Mode()&0100, "hook script should be executable")
+
+	// Remove one hook again.
+	err = s.svn.ModifyHooks("1234", ModifyHooks{Remove: []string{"test-hook"}}, logFields)
+	assert.Nil(t, err)
+
+	hooks, err = s.svn.GetRepoHooks("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, []InstalledHook{InstalledHook{Name: "post-hook", Params: map[string]string{}}}, hooks)
+
+	_, err = os.Stat(filepath.Join(hookdir, "pre-commit"))
+	assert.True(t, os.IsNotExist(err), "pre-commit hook should have been removed")
+	_, err = os.Stat(filepath.Join(hookdir, "svnman", "test-hook"))
+	assert.True(t, os.IsNotExist(err), "test-hook script should have been removed")
+	_, err = os.Stat(filepath.Join(hookdir, "post-commit"))
+	assert.Nil(t, err, "post-commit hook should still exist")
+}
+
+func (s *SVNManTestSuite) TestModifyHooksUnhappy(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createHookCatalogue(t)
+	defer os.RemoveAll(s.svn.hookCatalogue)
+	s.createTestRepo(t, "1234")
+
+	err := s.svn.ModifyHooks("1234", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "nonexistant-hook"}},
+	}, logFields)
+	assert.Equal(t, ErrUnknownHook, err)
+
+	err = s.svn.ModifyHooks("1234", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "test-hook", Params: map[string]string{"unknown": "1"}}},
+	}, logFields)
+	assert.Equal(t, ErrInvalidHookParam, err)
+
+	err = s.svn.ModifyHooks("1234", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "test-hook", Params: map[string]string{"max_count": "many"}}},
+	}, logFields)
+	assert.Equal(t, ErrInvalidHookParam, err)
+
+	err = s.svn.ModifyHooks("1234", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "test-hook"}},
+		Remove:  []string{"test-hook"},
+	}, logFields)
+	assert.Equal(t, ErrConflictingHooks, err)
+
+	err = s.svn.ModifyHooks("4567", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "test-hook"}},
+	}, logFields)
+	assert.Equal(t, ErrNotFound, err)
+
+	// Nothing should have been installed.
+	hooks, err := s.svn.GetRepoHooks("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, []InstalledHook{}, hooks)
+}
+
+func (s *SVNManTestSuite) TestModifyHooksForeignScript(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createHookCatalogue(t)
+	defer os.RemoveAll(s.svn.hookCatalogue)
+	s.createTestRepo(t, "1234")
+
+	foreign := filepath.Join(s.svn.repoPath("1234"), "hooks", "pre-commit")
+	if err := ioutil.WriteFile(foreign, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("unable to write %s: %s", foreign, err)
+	}
+
+	err := s.svn.ModifyHooks("1234", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "test-hook"}},
+	}, logFields)
+	assert.NotNil(t, err)
+
+	content, err := ioutil.ReadFile(foreign)
+	assert.Nil(t, err)
+	assert.Equal(t, "#!/bin/sh\nexit 0\n", string(content), "foreign hook script should not be touched")
+}
+
+func (s *SVNManTestSuite) TestWriteHookDispatchers(t *check.C) {
+	s.createHookCatalogue(t)
+	defer os.RemoveAll(s.svn.hookCatalogue)
+	s.createTestRepo(t, "1234")
+	s.createTestRepo(t, "4567")
+	assert.Nil(t, s.svn.ModifyHooks("4567", ModifyHooks{
+		Install: []InstalledHook{InstalledHook{Name: "test-hook"}},
+	}, log.Fields{"in": "unittest"}))
+
+	// Pretend the repositories were created before commits were notified.
+	for _, repoID := range []string{"1234", "4567"} {
+		postCommit := hookScriptPath(s.svn.repoPath(repoID), "post-commit")
+		assert.Nil(t, os.Remove(postCommit))
+	}
+
+	assert.Nil(t, s.svn.WriteHookDispatchers(log.Fields{"in": "unittest"}))
+
+	for _, repoID := range []string{"1234", "4567"} {
+		content, err := ioutil.ReadFile(hookScriptPath(s.svn.repoPath(repoID), "post-commit"))
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), "http://localhost:8085/api/repo/"+repoID+"/commits")
+	}
+
+	// Installed hooks should be kept.
+	hooks, err := s.svn.GetRepoHooks("4567")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(hooks))
+	_, err = os.Stat(hookScriptPath(s.svn.repoPath("4567"), "pre-commit"))
+	assert.Nil(t, err, "pre-commit hook should still exist")
+}
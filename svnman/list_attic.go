@@ -0,0 +1,75 @@
+package svnman
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Splits the names produced by atticPath() into repository ID and timestamp.
+var atticNameRegexp = regexp.MustCompile(`^(.+)-(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}(?:Z|[+\-]\d{2})-\d{2})$`)
+
+// ListAttic returns the deleted repositories in the attic.
+// When repoID is not empty, only deleted repositories with that ID are returned.
+func (svn *SVNMan) ListAttic(repoID string) ([]AtticEntry, error) {
+	glob := filepath.Join(svn.repoRoot, "attic", "*", "*")
+	if repoID != "" {
+		glob = svn.atticPathFs(repoID, "*")
+	}
+	found, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []AtticEntry{}
+	for _, path := range found {
+		logger := log.WithField("attic", path)
+
+		match := atticNameRegexp.FindStringSubmatch(filepath.Base(path))
+		if match == nil {
+			logger.Warning("unexpected file in attic")
+			continue
+		}
+		if repoID != "" && match[1] != repoID {
+			// The glob also matches repository IDs that start with the requested one.
+			continue
+		}
+
+		entry := AtticEntry{
+			RepoID:    match[1],
+			Timestamp: match[2],
+		}
+		deletedOn, err := time.Parse(RFC3339fs, entry.Timestamp)
+		if err != nil {
+			logger.WithError(err).Warning("unable to parse timestamp in attic")
+			continue
+		}
+		entry.DeletedOn = deletedOn.UTC()
+
+		if info, err := readRepoInfoFile(filepath.Join(path, "info.yaml")); err != nil {
+			logger.WithError(err).Warning("unable to read repository info in attic")
+		} else {
+			entry.ProjectID = info.ProjectID
+			entry.Creator = info.Creator
+			createdOn := info.Creation
+			entry.CreatedOn = &createdOn
+		}
+
+		if entry.DiskSize, err = dirSize(path); err != nil {
+			logger.WithError(err).Warning("unable to determine disk size of repository in attic")
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RepoID != entries[j].RepoID {
+			return entries[i].RepoID < entries[j].RepoID
+		}
+		return entries[i].DeletedOn.Before(entries[j].DeletedOn)
+	})
+	return entries, nil
+}
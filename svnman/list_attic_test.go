@@ -0,0 +1,68 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestListAttic(t *check.C) {
+	entries, err := s.svn.ListAttic("")
+	assert.Nil(t, err)
+	assert.Equal(t, []AtticEntry{}, entries)
+
+	s.createTestRepo(t, "my-repo")
+	s.createTestRepo(t, "my-repo-id")
+	timestamp1 := s.deleteTestRepo(t, "my-repo")
+	timestamp2 := s.deleteTestRepo(t, "my-repo-id")
+
+	// Junk in the attic should be ignored.
+	junk := filepath.Join(s.svn.repoRoot, "attic", "my", "junk")
+	if err := ioutil.WriteFile(junk, []byte("junk"), 0644); err != nil {
+		t.Fatalf("unable to write %s: %s", junk, err)
+	}
+
+	entries, err = s.svn.ListAttic("")
+	assert.Nil(t, err)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %#v", entries)
+	}
+
+	assert.Equal(t, "my-repo", entries[0].RepoID)
+	assert.Equal(t, timestamp1, entries[0].Timestamp)
+	assert.Equal(t, "59eefa9cf488554678cae036", entries[0].ProjectID)
+	assert.Equal(t, "dr. Stüvel <sybren@blender.studio>", entries[0].Creator)
+	assert.NotNil(t, entries[0].CreatedOn)
+	assert.True(t, entries[0].DiskSize > 0, "disk size should be positive")
+	assert.True(t, time.Since(entries[0].DeletedOn) < time.Minute, "unexpected deletion time %s", entries[0].DeletedOn)
+
+	assert.Equal(t, "my-repo-id", entries[1].RepoID)
+	assert.Equal(t, timestamp2, entries[1].Timestamp)
+
+	// Filtering on repository ID should not include repositories with a longer ID.
+	entries, err = s.svn.ListAttic("my-repo")
+	assert.Nil(t, err)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %#v", entries)
+	}
+	assert.Equal(t, "my-repo", entries[0].RepoID)
+}
+
+func (s *SVNManTestSuite) TestListAtticWithoutInfo(t *check.C) {
+	path := s.svn.atticPathFs("old-repo", "2017-11-14T16-30-04+01-00")
+	if err := os.MkdirAll(path, 0750); err != nil {
+		t.Fatalf("unable to create %s: %s", path, err)
+	}
+
+	entries, err := s.svn.ListAttic("")
+	assert.Nil(t, err)
+	assert.Equal(t, []AtticEntry{AtticEntry{
+		RepoID:    "old-repo",
+		Timestamp: "2017-11-14T16-30-04+01-00",
+		DeletedOn: time.Date(2017, 11, 14, 15, 30, 4, 0, time.UTC),
+	}}, entries)
+}
@@ -0,0 +1,89 @@
+package svnman
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// repoIDs returns the IDs of all repositories, sorted alphabetically.
+func (svn *SVNMan) repoIDs() ([]string, error) {
+	found, err := filepath.Glob(filepath.Join(svn.repoRoot, "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	repoIDs := make([]string, 0, len(found))
+	for _, path := range found {
+		prefix := filepath.Base(filepath.Dir(path))
+		if prefix == "attic" {
+			continue
+		}
+		repoID := filepath.Base(path)
+		if svn.repoPath(repoID) != path {
+			log.WithField("path", path).Debug("skipping unexpected file in repository root")
+			continue
+		}
+		if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+			continue
+		}
+		repoIDs = append(repoIDs, repoID)
+	}
+	sort.Strings(repoIDs)
+	return repoIDs, nil
+}
+
+// ListRepos returns the repositories matching the query, sorted by repository ID.
+// The cursor is the last repository ID of the previous page.
+func (svn *SVNMan) ListRepos(query ListRepos) (RepoList, error) {
+	list := RepoList{Repos: []RepoSummary{}}
+
+	repoIDs, err := svn.repoIDs()
+	if err != nil {
+		return list, err
+	}
+	if query.Cursor != "" {
+		start := sort.SearchStrings(repoIDs, query.Cursor)
+		if start < len(repoIDs) && repoIDs[start] == query.Cursor {
+			start++
+		}
+		repoIDs = repoIDs[start:]
+	}
+
+	for _, repoID := range repoIDs {
+		logger := log.WithField("repo_id", repoID)
+		info, err := svn.readRepoInfo(repoID)
+		if err != nil {
+			logger.WithError(err).Warning("unable to read repository info, skipping repository")
+			continue
+		}
+		if query.ProjectID != "" && info.ProjectID != query.ProjectID {
+			continue
+		}
+		if query.Creator != "" && info.Creator != query.Creator {
+			continue
+		}
+		if query.Limit > 0 && len(list.Repos) == query.Limit {
+			// There is at least one more matching repository.
+			list.NextCursor = list.Repos[len(list.Repos)-1].RepoID
+			break
+		}
+
+		names, err := svn.GetUsernames(repoID)
+		if err != nil {
+			logger.WithError(err).Warning("unable to read usernames of repository")
+		}
+
+		list.Repos = append(list.Repos, RepoSummary{
+			RepoID:    repoID,
+			ProjectID: info.ProjectID,
+			Creator:   info.Creator,
+			CreatedOn: info.Creation,
+			UserCount: len(names),
+		})
+	}
+
+	return list, nil
+}
@@ -0,0 +1,102 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func repoIDsOf(list RepoList) []string {
+	repoIDs := []string{}
+	for _, repo := range list.Repos {
+		repoIDs = append(repoIDs, repo.RepoID)
+	}
+	return repoIDs
+}
+
+func (s *SVNManTestSuite) TestListRepos(t *check.C) {
+	list, err := s.svn.ListRepos(ListRepos{})
+	assert.Nil(t, err)
+	assert.Equal(t, RepoList{Repos: []RepoSummary{}}, list)
+
+	for _, repoID := range []string{"repo-c", "repo-a", "other-repo", "repo-b"} {
+		s.createTestRepo(t, repoID)
+	}
+	err = s.svn.CreateRepo(CreateRepo{
+		RepoID:    "repo-d",
+		ProjectID: "5a0ad83c5e6a4a0c3e3c0b1a",
+		Creator:   "someone else",
+	}, log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	err = s.svn.ModifyAccess("repo-a", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{
+			ModifyAccessGrantEntry{Username: "user-1", Password: "$2y$05$cWZN0CJN"},
+			ModifyAccessGrantEntry{Username: "user-2", Password: "$2y$05$cWZN0CJN"},
+		},
+	}, log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+
+	// Deleted repositories and junk should not be listed.
+	s.createTestRepo(t, "deleted-repo")
+	s.deleteTestRepo(t, "deleted-repo")
+	junk := filepath.Join(s.svn.repoRoot, "re", "junk")
+	if err := ioutil.WriteFile(junk, []byte("junk"), 0644); err != nil {
+		t.Fatalf("unable to write %s: %s", junk, err)
+	}
+
+	list, err = s.svn.ListRepos(ListRepos{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other-repo", "repo-a", "repo-b", "repo-c", "repo-d"}, repoIDsOf(list))
+	assert.Equal(t, "", list.NextCursor)
+
+	repo := list.Repos[1]
+	assert.Equal(t, "59eefa9cf488554678cae036", repo.ProjectID)
+	assert.Equal(t, "dr. Stüvel <sybren@blender.studio>", repo.Creator)
+	assert.False(t, repo.CreatedOn.IsZero())
+	assert.Equal(t, 2, repo.UserCount)
+	assert.Equal(t, 0, list.Repos[0].UserCount)
+
+	list, err = s.svn.ListRepos(ListRepos{ProjectID: "5a0ad83c5e6a4a0c3e3c0b1a"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"repo-d"}, repoIDsOf(list))
+
+	list, err = s.svn.ListRepos(ListRepos{Creator: "dr. Stüvel <sybren@blender.studio>"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other-repo", "repo-a", "repo-b", "repo-c"}, repoIDsOf(list))
+}
+
+func (s *SVNManTestSuite) TestListReposPagination(t *check.C) {
+	for _, repoID := range []string{"repo-c", "repo-a", "other-repo", "repo-b", "repo-d"} {
+		s.createTestRepo(t, repoID)
+	}
+
+	list, err := s.svn.ListRepos(ListRepos{Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other-repo", "repo-a"}, repoIDsOf(list))
+	assert.Equal(t, "repo-a", list.NextCursor)
+
+	list, err = s.svn.ListRepos(ListRepos{Limit: 2, Cursor: list.NextCursor})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"repo-b", "repo-c"}, repoIDsOf(list))
+	assert.Equal(t, "repo-c", list.NextCursor)
+
+	list, err = s.svn.ListRepos(ListRepos{Limit: 2, Cursor: list.NextCursor})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"repo-d"}, repoIDsOf(list))
+	assert.Equal(t, "", list.NextCursor)
+
+	// The cursor does not have to exist any more.
+	list, err = s.svn.ListRepos(ListRepos{Limit: 2, Cursor: "repo-bb"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"repo-c", "repo-d"}, repoIDsOf(list))
+	assert.Equal(t, "", list.NextCursor)
+
+	// Filters are applied before the limit.
+	list, err = s.svn.ListRepos(ListRepos{Limit: 4, ProjectID: "59eefa9cf488554678cae036"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other-repo", "repo-a", "repo-b", "repo-c"}, repoIDsOf(list))
+	assert.Equal(t, "repo-c", list.NextCursor)
+}
@@ -0,0 +1,114 @@
+package svnman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// Matches the lines in 'svnadmin load' output that report a loaded revision.
+var committedRevisionRegexp = regexp.MustCompile(`^------- Committed (?:revision|new rev) (\d+)`)
+
+// LoadProgressFunc is called by LoadRepo for every loaded revision.
+type LoadProgressFunc func(revision int)
+
+// LoadRepo creates a repository and loads the dump into it with 'svnadmin load'.
+// The dump is loaded while the repository is still being assembled, so the repository
+// only appears once it has been loaded completely.
+func (svn *SVNMan) LoadRepo(ctx context.Context, repoInfo CreateRepo, dump io.Reader,
+	progress LoadProgressFunc, logFields log.Fields) error {
+	err := svn.loadRepo(ctx, repoInfo, dump, progress, logFields)
+	svn.publishRepoEvent(events.RepoCreated, events.RepoEvent{
+		RepoID:    repoInfo.RepoID,
+		ProjectID: repoInfo.ProjectID,
+		Creator:   repoInfo.Creator,
+	}, err)
+	return err
+}
+
+func (svn *SVNMan) loadRepo(ctx context.Context, repoInfo CreateRepo, dump io.Reader,
+	progress LoadProgressFunc, logFields log.Fields) error {
+	logger := svn.createRepoLogger(repoInfo, logFields)
+
+	steps := append([]repoCreationStep{}, svn.repoCreationSteps()...)
+	steps = append(steps, repoCreationStep{"svnadmin load",
+		func(svn *SVNMan, stagingDir string, info repoinfo) error {
+			return svn.runLoad(ctx, stagingDir, dump, progress, logger)
+		}})
+	info, err := svn.createRepoDir(repoInfo, steps, logger)
+	switch {
+	case err == ErrAlreadyExists:
+		return err
+	case err != nil:
+		// Nothing was moved into place, so there is nothing to remove.
+		logger.WithError(err).Warning("unable to load repository from dump")
+	default:
+		err = svn.enableRepo(info, logger)
+		if err == nil {
+			logger.Info("repository loaded from dump")
+			return nil
+		}
+		logger.WithError(err).Warning("unable to enable loaded repository, removing it again")
+		svn.discardRepo(repoInfo.RepoID, logger)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrLoad
+}
+
+// runLoad runs 'svnadmin load', reporting the loaded revisions as they come in.
+func (svn *SVNMan) runLoad(ctx context.Context, repodir string, dump io.Reader,
+	progress LoadProgressFunc, logger *log.Entry) error {
+	cmd := exec.CommandContext(ctx, "svnadmin", "load", repodir)
+	cmd.Stdin = dump
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	logger.Info("loading dump into repository")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	loaded := 0
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		match := committedRevisionRegexp.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		revision, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		loaded++
+		logger.WithField("revision", revision).Debug("revision loaded")
+		if progress != nil {
+			progress(revision)
+		}
+	}
+	// Make sure svnadmin never blocks on a full pipe, even when the scanner gave up.
+	io.Copy(ioutil.Discard, stdout)
+
+	err = cmd.Wait()
+	logger = logger.WithField("revisions_loaded", loaded)
+	if err != nil {
+		logger.WithError(err).WithField("stderr", stderr.String()).Warning("error running svnadmin load")
+		return err
+	}
+	logger.Debug("'svnadmin load' successful")
+	return nil
+}
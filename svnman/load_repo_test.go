@@ -0,0 +1,140 @@
+package svnman
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+const testDump = `SVN-fs-dump-format-version: 2
+
+UUID: 0b0b0b0b-1111-2222-3333-444444444444
+
+Revision-number: 0
+
+Revision-number: 1
+
+Revision-number: 2
+
+`
+
+var testLoadRepo = CreateRepo{
+	RepoID:    "my-repo-id",
+	ProjectID: "59eefa9cf488554678cae036",
+	Creator:   "dr. Stüvel <sybren@blender.studio>",
+}
+
+func (s *SVNManTestSuite) TestLoadRepoHappy(t *check.C) {
+	revisions := []int{}
+	progress := func(revision int) {
+		revisions = append(revisions, revision)
+		_, err := os.Stat(s.svn.repoPath("my-repo-id"))
+		assert.True(t, os.IsNotExist(err), "repository should not appear before it is loaded")
+	}
+
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(testDump),
+		progress, log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, revisions)
+	assert.True(t, s.mb.reloadCalled, "a backend reload should have been queued")
+
+	head, err := s.svn.headRevision("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, head)
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err, "Apache config should have been created")
+	info, err := s.svn.readRepoInfo("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, "59eefa9cf488554678cae036", info.ProjectID)
+
+	published := s.mp.Events()
+	if len(published) != 1 {
+		t.Fatalf("expected 1 event, got %#v", published)
+	}
+	assert.Equal(t, events.RepoCreated, published[0].RoutingKey)
+	assert.True(t, s.repoEvent(t, published[0]).Success)
+}
+
+func (s *SVNManTestSuite) TestLoadRepoBrokenDump(t *check.C) {
+	revisions := []int{}
+	progress := func(revision int) { revisions = append(revisions, revision) }
+
+	dump := testDump + "BROKEN\n"
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(dump),
+		progress, log.Fields{"in": "unittest"})
+	assert.Equal(t, ErrLoad, err)
+	assert.Equal(t, []int{1, 2}, revisions)
+	assert.False(t, s.mb.reloadCalled, "backend should not be reloaded")
+
+	_, err = os.Stat(s.svn.repoPath("my-repo-id"))
+	assert.True(t, os.IsNotExist(err), "repository should have been removed")
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.True(t, os.IsNotExist(err), "Apache config should not exist")
+	s.assertNoRepoLeftovers(t, "my-repo-id")
+
+	published := s.mp.Events()
+	if len(published) != 1 {
+		t.Fatalf("expected 1 event, got %#v", published)
+	}
+	event := s.repoEvent(t, published[0])
+	assert.False(t, event.Success)
+	assert.Equal(t, ErrLoad.Error(), event.Error)
+}
+
+func (s *SVNManTestSuite) TestLoadRepoCancelled(t *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.svn.LoadRepo(ctx, testLoadRepo, strings.NewReader(testDump), nil, log.Fields{"in": "unittest"})
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = os.Stat(s.svn.repoPath("my-repo-id"))
+	assert.True(t, os.IsNotExist(err), "repository should have been removed")
+}
+
+func (s *SVNManTestSuite) TestLoadRepoAlreadyExists(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(testDump),
+		nil, log.Fields{"in": "unittest"})
+	assert.Equal(t, ErrAlreadyExists, err)
+
+	// The existing repository should not be touched.
+	head, err := s.svn.headRevision("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, head)
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err, "Apache config should still exist")
+}
+
+func (s *SVNManTestSuite) TestLoadRepoCreatedConcurrently(t *check.C) {
+	// Another request creates the repository while the dump is being loaded.
+	marker := filepath.Join(s.svn.repoPath("my-repo-id"), "format")
+	s.svn.creationSteps = append(s.svn.repoCreationSteps(), repoCreationStep{"concurrent create",
+		func(svn *SVNMan, stagingDir string, info repoinfo) error {
+			if err := os.MkdirAll(filepath.Dir(marker), 0750); err != nil {
+				return err
+			}
+			return ioutil.WriteFile(marker, []byte("5\n"), 0644)
+		}})
+	defer func() { s.svn.creationSteps = nil }()
+
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(testDump),
+		nil, log.Fields{"in": "unittest"})
+	assert.Equal(t, ErrAlreadyExists, err)
+	assert.False(t, s.mb.reloadCalled, "backend should not be reloaded")
+
+	// The other repository should not be touched.
+	_, err = os.Stat(marker)
+	assert.Nil(t, err, "concurrently created repository should still exist")
+	staged, err := ioutil.ReadDir(filepath.Join(s.svn.repoRoot, stagingDirName))
+	assert.Nil(t, err)
+	assert.Empty(t, staged, "staging directory should be empty")
+}
@@ -1,20 +1,111 @@
 package svnman
 
 import (
+	"sort"
+
+	"github.com/armadillica/svn-manager/events"
 	"github.com/foomo/htpasswd"
 	log "github.com/sirupsen/logrus"
 )
 
 // ModifyAccess grants or revoke usage access for users on a specific repository.
 func (svn *SVNMan) ModifyAccess(repoID string, mods ModifyAccess, logFields log.Fields) error {
-	filename := svn.htpasswd(repoID)
+	event := events.RepoEvent{
+		RepoID:  repoID,
+		Granted: make([]string, len(mods.Grant)),
+		Revoked: mods.Revoke,
+	}
+	for idx, grant := range mods.Grant {
+		event.Granted[idx] = grant.Username
+	}
+	if info, err := svn.readRepoInfo(repoID); err == nil {
+		event.ProjectID = info.ProjectID
+		event.Creator = info.Creator
+	}
+
+	err := svn.modifyAccess(repoID, mods, logFields)
+	svn.publishRepoEvent(events.RepoAccessChanged, event, err)
+	return err
+}
+
+func (svn *SVNMan) modifyAccess(repoID string, mods ModifyAccess, logFields log.Fields) error {
 	logger := log.WithFields(logFields).WithFields(log.Fields{
 		"grant_count":  len(mods.Grant),
 		"revoke_count": len(mods.Revoke),
-		"filename":     filename,
 	})
 
 	logger.Debug("modifying repository access")
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to read repository info")
+		return err
+	}
+	usernames, err := svn.GetUsernames(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to read users of repository")
+		return err
+	}
+
+	isUser := map[string]bool{}
+	for _, username := range usernames {
+		isUser[username] = true
+	}
+	readOnly := info.readOnlyUsers()
+	for _, grant := range mods.Grant {
+		switch {
+		case grant.Access == AccessRead:
+			readOnly[grant.Username] = true
+		case grant.Access == AccessWrite, !isUser[grant.Username]:
+			delete(readOnly, grant.Username)
+		}
+	}
+	for _, revoke := range mods.Revoke {
+		delete(readOnly, revoke)
+	}
+
+	info.ReadOnlyUsers = nil
+	for username := range readOnly {
+		info.ReadOnlyUsers = append(info.ReadOnlyUsers, username)
+	}
+	sort.Strings(info.ReadOnlyUsers)
+
+	if svn.userDir == "" {
+		err = svn.modifyHtpasswd(repoID, mods, logger)
+	} else {
+		err = svn.modifyMembers(repoID, mods, logger)
+	}
+	if err != nil {
+		return err
+	}
+	svn.indexRepoUsers(repoID)
+	if err := svn.writeRepoInfo(info); err != nil {
+		logger.WithError(err).Error("unable to write repository info")
+		return err
+	}
+
+	// The backend config lists the users, or at least those that can commit.
+	changed, err := svn.writeConfig(info)
+	if err != nil {
+		logger.WithError(err).Error("unable to update backend config")
+		return err
+	}
+	if changed {
+		svn.backend.QueueReload(repoID, svn.confPath(repoID))
+	}
+
+	logger.Info("repository access modified")
+	return nil
+}
+
+// modifyHtpasswd applies the changes in access to the repository's own htpasswd file.
+func (svn *SVNMan) modifyHtpasswd(repoID string, mods ModifyAccess, logger *log.Entry) error {
+	svn.userMutex.Lock()
+	defer svn.userMutex.Unlock()
+
+	filename := svn.htpasswd(repoID)
+	logger = logger.WithField("filename", filename)
+
 	passwds, err := htpasswd.ParseHtpasswdFile(filename)
 	if err != nil {
 		logger.WithError(err).Error("unable to parse htpasswd")
@@ -22,7 +113,12 @@ func (svn *SVNMan) ModifyAccess(repoID string, mods ModifyAccess, logFields log.
 	}
 
 	for _, grant := range mods.Grant {
-		passwds[grant.Username] = grant.Password
+		if grant.Password != "" {
+			passwds[grant.Username] = grant.Password
+		} else if _, known := passwds[grant.Username]; !known {
+			logger.WithField("username", grant.Username).Warning("no password given for new user")
+			return ErrNoPassword
+		}
 	}
 	for _, revoke := range mods.Revoke {
 		delete(passwds, revoke)
@@ -32,7 +128,14 @@ func (svn *SVNMan) ModifyAccess(repoID string, mods ModifyAccess, logFields log.
 		logger.WithError(err).Error("unable to save htpasswd")
 		return err
 	}
-
-	logger.Info("repository access modified")
 	return nil
 }
+
+// readOnlyUsers returns the set of users that can check out, but not commit.
+func (info repoinfo) readOnlyUsers() map[string]bool {
+	readOnly := map[string]bool{}
+	for _, username := range info.ReadOnlyUsers {
+		readOnly[username] = true
+	}
+	return readOnly
+}
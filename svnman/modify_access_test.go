@@ -1,9 +1,11 @@
 package svnman
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -37,7 +39,7 @@ func (s *SVNManTestSuite) TestModifyAccessHappy(t *check.C) {
 	// Grant access to one user.
 	if err := s.svn.ModifyAccess(repoInfo.RepoID, ModifyAccess{
 		Grant: []ModifyAccessGrantEntry{
-			ModifyAccessGrantEntry{"testkees", "$2y$05$cWVQLHS58K7fIKjz3tU52eBI2sxbE3KdAfZN0CJN"},
+			ModifyAccessGrantEntry{Username: "testkees", Password: "$2y$05$cWVQLHS58K7fIKjz3tU52eBI2sxbE3KdAfZN0CJN"},
 		},
 	}, logFields); err != nil {
 		t.Fatalf("Unable to modify access: %s", err)
@@ -52,8 +54,8 @@ func (s *SVNManTestSuite) TestModifyAccessHappy(t *check.C) {
 	// Modify password of one user, and grant access to a new one.
 	if err := s.svn.ModifyAccess(repoInfo.RepoID, ModifyAccess{
 		Grant: []ModifyAccessGrantEntry{
-			ModifyAccessGrantEntry{"testkees", "$2y$05$cWZN0CJN"},
-			ModifyAccessGrantEntry{"anotherone", "$2y$05$cW---ZN0CJN"},
+			ModifyAccessGrantEntry{Username: "testkees", Password: "$2y$05$cWZN0CJN"},
+			ModifyAccessGrantEntry{Username: "anotherone", Password: "$2y$05$cW---ZN0CJN"},
 		},
 	}, logFields); err != nil {
 		t.Fatalf("Unable to re-modify access: %s", err)
@@ -86,3 +88,99 @@ func (s *SVNManTestSuite) TestModifyAccessHappy(t *check.C) {
 	assert.Equal(t, "anotherone", oneline[0])
 	assert.Equal(t, "$2y$05$cW---ZN0CJN", oneline[1])
 }
+
+func (s *SVNManTestSuite) TestModifyAccessLevels(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+
+	grant := func(username, access string) ModifyAccessGrantEntry {
+		return ModifyAccessGrantEntry{Username: username, Password: "$2y$05$cWZN0CJN", Access: access}
+	}
+	users := func() []UserAccess {
+		details, err := s.svn.GetRepoDetails("1234")
+		if err != nil {
+			t.Fatalf("unable to get repository details: %s", err)
+		}
+		return details.Users
+	}
+
+	// New users can commit unless granted read access.
+	err := s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{grant("artist", ""), grant("lead", AccessWrite), grant("renderfarm", AccessRead)},
+	}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []UserAccess{
+		{"artist", AccessWrite},
+		{"lead", AccessWrite},
+		{"renderfarm", AccessRead},
+	}, users())
+
+	apa := s.readApacheConfig(t, "1234")
+	assert.Contains(t, apa, "<Limit GET PROPFIND OPTIONS REPORT>\n        Require valid-user\n")
+	assert.Contains(t, apa, "<LimitExcept GET PROPFIND OPTIONS REPORT>\n        Require user artist lead\n")
+
+	// Changing the password keeps the access level, unless given.
+	err = s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{grant("renderfarm", ""), grant("artist", AccessRead)},
+	}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []UserAccess{
+		{"artist", AccessRead},
+		{"lead", AccessWrite},
+		{"renderfarm", AccessRead},
+	}, users())
+
+	// Revoked users lose their access level too.
+	err = s.svn.ModifyAccess("1234", ModifyAccess{Revoke: []string{"renderfarm"}}, logFields)
+	assert.Nil(t, err)
+	err = s.svn.ModifyAccess("1234", ModifyAccess{Grant: []ModifyAccessGrantEntry{grant("renderfarm", "")}}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []UserAccess{
+		{"artist", AccessRead},
+		{"lead", AccessWrite},
+		{"renderfarm", AccessWrite},
+	}, users())
+
+	info, err := s.svn.readRepoInfo("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"artist"}, info.ReadOnlyUsers)
+
+	// Without read-only users, the config is back to normal.
+	err = s.svn.ModifyAccess("1234", ModifyAccess{Grant: []ModifyAccessGrantEntry{grant("artist", AccessWrite)}}, logFields)
+	assert.Nil(t, err)
+	apa = s.readApacheConfig(t, "1234")
+	assert.NotContains(t, apa, "LimitExcept")
+	assert.Contains(t, apa, "    Require valid-user\n")
+}
+
+func (s *SVNManTestSuite) TestModifyAccessConcurrently(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+
+	// Every change to the info file should survive, even when they happen at the same time.
+	wg := sync.WaitGroup{}
+	for idx := 0; idx < 10; idx++ {
+		wg.Add(1)
+		go func(username string) {
+			defer wg.Done()
+			err := s.svn.ModifyAccess("1234", ModifyAccess{
+				Grant: []ModifyAccessGrantEntry{{Username: username, Password: "$2y$05$cWZN0CJN", Access: AccessRead}},
+			}, logFields)
+			assert.Nil(t, err)
+		}(fmt.Sprintf("artist%d", idx))
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.Nil(t, s.svn.BlockRepo("1234", BlockRepo{ReadOnly: true, Reason: "testing"}, logFields))
+	}()
+	wg.Wait()
+
+	info, err := s.svn.readRepoInfo("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(info.ReadOnlyUsers))
+	assert.NotNil(t, info.Block)
+	usernames, err := s.svn.GetUsernames("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(usernames))
+}
@@ -0,0 +1,39 @@
+package svnman
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PackRepo packs the repository with 'svnadmin pack', which combines the revision files of
+// completed shards into one file per shard. This reduces the number of files on disk, and is
+// safe to do while the repository is in use.
+func (svn *SVNMan) PackRepo(ctx context.Context, repoID string, logFields log.Fields) error {
+	repodir := svn.repoPath(repoID)
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"repo_id":  repoID,
+		"repo_dir": repodir,
+	})
+
+	if _, err := os.Stat(repodir); os.IsNotExist(err) {
+		logger.Warning("nonexistent repository requested")
+		return ErrNotFound
+	}
+
+	logger.Info("packing repository")
+	startTime := time.Now()
+	if err := runSvnadmin(ctx, logger, "pack", repodir); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrPack
+	}
+
+	// Packing changes the disk size, but not the HEAD revision.
+	svn.statsCache.forget(repoID)
+	logger.WithField("duration", time.Since(startTime)).Info("repository packed")
+	return nil
+}
@@ -0,0 +1,19 @@
+package svnman
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestPackRepo(t *check.C) {
+	s.createTestRepo(t, "1234")
+
+	err := s.svn.PackRepo(context.Background(), "1234", log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+
+	err = s.svn.PackRepo(context.Background(), "4567", log.Fields{"in": "unittest"})
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,57 @@
+package svnman
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// PurgeAtticEntry permanently removes a deleted repository from the attic.
+// The timestamp is formatted as RFC3339fs, as it is in the attic.
+func (svn *SVNMan) PurgeAtticEntry(repoID, timestamp string, logFields log.Fields) error {
+	event := events.RepoEvent{RepoID: repoID}
+
+	// The info file is removed too, so read it while we still can.
+	infoPath := filepath.Join(svn.atticPathFs(repoID, timestamp), "info.yaml")
+	if info, err := readRepoInfoFile(infoPath); err == nil {
+		event.ProjectID = info.ProjectID
+		event.Creator = info.Creator
+	}
+
+	err := svn.purgeAtticEntry(repoID, timestamp, logFields)
+	svn.publishRepoEvent(events.RepoPurged, event, err)
+	return err
+}
+
+func (svn *SVNMan) purgeAtticEntry(repoID, timestamp string, logFields log.Fields) error {
+	confAtticPath := svn.confAtticPathFs(repoID, timestamp)
+	atticPath := svn.atticPathFs(repoID, timestamp)
+
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"conf_attic": confAtticPath,
+		"attic":      atticPath,
+	})
+	logger.Debug("purging repository from attic")
+
+	if _, err := os.Stat(atticPath); os.IsNotExist(err) {
+		logger.Warning("repository not found in attic")
+		return ErrNotInAttic
+	} else if err != nil {
+		logger.WithError(err).Error("unable to inspect attic")
+		return ErrPurge
+	}
+
+	if err := os.RemoveAll(atticPath); err != nil {
+		logger.WithError(err).Error("unable to remove repository from attic")
+		return ErrPurge
+	}
+	if err := os.Remove(confAtticPath); err != nil && !os.IsNotExist(err) {
+		// The repository itself is gone, so report success anyway.
+		logger.WithError(err).Warning("unable to remove backend config from attic")
+	}
+
+	logger.Info("repository purged from attic")
+	return nil
+}
@@ -0,0 +1,55 @@
+package svnman
+
+import (
+	"os"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestPurgeAtticEntryHappy(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	timestamp := s.deleteTestRepo(t, "my-repo-id")
+	s.mp.Reset()
+
+	err := s.svn.PurgeAtticEntry("my-repo-id", timestamp, logFields)
+	assert.Nil(t, err)
+
+	_, err = os.Stat(s.svn.atticPathFs("my-repo-id", timestamp))
+	assert.True(t, os.IsNotExist(err), "repository should have been removed from the attic")
+	_, err = os.Stat(s.svn.confAtticPathFs("my-repo-id", timestamp))
+	assert.True(t, os.IsNotExist(err), "Apache config should have been removed from the attic")
+
+	published := s.mp.Events()
+	if len(published) != 1 {
+		t.Fatalf("expected 1 event, got %#v", published)
+	}
+	assert.Equal(t, events.RepoPurged, published[0].RoutingKey)
+	event := s.repoEvent(t, published[0])
+	assert.Equal(t, "my-repo-id", event.RepoID)
+	assert.Equal(t, "59eefa9cf488554678cae036", event.ProjectID)
+	assert.True(t, event.Success)
+
+	// Purging twice should not work.
+	err = s.svn.PurgeAtticEntry("my-repo-id", timestamp, logFields)
+	assert.Equal(t, ErrNotInAttic, err)
+}
+
+func (s *SVNManTestSuite) TestPurgeAtticEntryLiveRepo(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	timestamp := s.deleteTestRepo(t, "my-repo-id")
+	s.createTestRepo(t, "my-repo-id")
+
+	err := s.svn.PurgeAtticEntry("my-repo-id", timestamp, logFields)
+	assert.Nil(t, err)
+
+	// Only the attic should be touched.
+	_, err = os.Stat(s.svn.repoPath("my-repo-id"))
+	assert.Nil(t, err, "live repository should still exist")
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err, "live Apache config should still exist")
+}
@@ -0,0 +1,177 @@
+package svnman
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// repoStats are the details of a repository that are expensive to determine, because they
+// require running svnlook or walking the entire repository.
+type repoStats struct {
+	revision int       // HEAD revision these stats are for.
+	modTime  time.Time // of the db/current file, which changes with every commit.
+
+	lastCommitAuthor string
+	lastCommitDate   *time.Time
+	diskSize         int64
+}
+
+// statsCache keeps the repoStats of every repository, so that they are only determined again
+// after a commit. SVNMan also forgets them when the repository changes in other ways.
+type statsCache struct {
+	mutex sync.Mutex
+	stats map[string]repoStats // repository ID to its stats.
+}
+
+// get returns the repository's stats, if they are still up to date.
+func (sc *statsCache) get(repoID string, revision int, modTime time.Time) (repoStats, bool) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	stats, ok := sc.stats[repoID]
+	if !ok || stats.revision != revision || !stats.modTime.Equal(modTime) {
+		return repoStats{}, false
+	}
+	return stats, true
+}
+
+func (sc *statsCache) set(repoID string, stats repoStats) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if sc.stats == nil {
+		sc.stats = map[string]repoStats{}
+	}
+	sc.stats[repoID] = stats
+}
+
+func (sc *statsCache) forget(repoID string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	delete(sc.stats, repoID)
+}
+
+// headRevision reads the youngest revision from the FSFS 'db/current' file.
+// This is much cheaper than running 'svnlook youngest'.
+func (svn *SVNMan) headRevision(repoID string) (int, error) {
+	return readHeadRevision(svn.repoPath(repoID))
+}
+
+// readHeadRevision reads the youngest revision of the repository in the given directory.
+func readHeadRevision(repodir string) (int, error) {
+	current, err := ioutil.ReadFile(filepath.Join(repodir, "db", "current"))
+	if err != nil {
+		return 0, err
+	}
+	// Older FSFS formats also store the next node & copy IDs, after the revision.
+	fields := strings.Fields(string(current))
+	if len(fields) == 0 {
+		return 0, errUnexpectedOutput
+	}
+	return strconv.Atoi(fields[0])
+}
+
+// userAccess returns the access level of every user of the repository, sorted by username.
+func (svn *SVNMan) userAccess(info repoinfo) ([]UserAccess, error) {
+	usernames, err := svn.GetUsernames(info.RepoID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(usernames)
+
+	readOnly := info.readOnlyUsers()
+	users := make([]UserAccess, len(usernames))
+	for idx, username := range usernames {
+		users[idx] = UserAccess{username, AccessWrite}
+		if readOnly[username] {
+			users[idx].Access = AccessRead
+		}
+	}
+	return users, nil
+}
+
+// GetRepoDetails returns information about the repository.
+func (svn *SVNMan) GetRepoDetails(repoID string) (RepoDetails, error) {
+	logger := log.WithField("repo_id", repoID)
+
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		return RepoDetails{}, err
+	}
+	details := RepoDetails{
+		ProjectID:  info.ProjectID,
+		Creator:    info.Creator,
+		CreatedOn:  info.Creation,
+		AppName:    info.AppName,
+		AppVersion: info.AppVer,
+	}
+	details.ApacheStatus, details.ApacheConfigError = svn.backend.ConfigStatus(repoID)
+	details.Users, err = svn.userAccess(info)
+	if err != nil {
+		logger.WithError(err).Error("unable to determine users of repository")
+		return RepoDetails{}, err
+	}
+	if info.Verify != nil {
+		verification := info.Verify.result()
+		details.LastVerification = &verification
+	}
+
+	details.HeadRevision, err = svn.headRevision(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to determine HEAD revision")
+		return RepoDetails{}, err
+	}
+
+	stats := svn.repoStats(repoID, details.HeadRevision, logger)
+	details.LastCommitAuthor = stats.lastCommitAuthor
+	details.LastCommitDate = stats.lastCommitDate
+	details.DiskSize = stats.diskSize
+	return details, nil
+}
+
+// repoStats returns the stats of the repository at its HEAD revision, from the cache when
+// possible. Failing to determine them shouldn't make the rest of the details unavailable,
+// so errors are only logged. Without a disk size, the stats are not cached.
+func (svn *SVNMan) repoStats(repoID string, revision int, logger *log.Entry) repoStats {
+	current, err := os.Stat(filepath.Join(svn.repoPath(repoID), "db", "current"))
+	if err != nil {
+		logger.WithError(err).Warning("unable to determine modification time of repository")
+		return repoStats{}
+	}
+	if stats, ok := svn.statsCache.get(repoID, revision, current.ModTime()); ok {
+		return stats
+	}
+
+	stats := repoStats{revision: revision, modTime: current.ModTime()}
+	if infoOut, err := svn.svnlook(logger, repoID, "info", "-r", strconv.Itoa(revision)); err == nil {
+		author, date, _, err := parseSvnlookInfo(infoOut)
+		if err != nil {
+			logger.WithError(err).WithField("output", infoOut).Warning("unable to parse svnlook output")
+		} else {
+			stats.lastCommitAuthor = author
+			stats.lastCommitDate = &date
+		}
+	}
+
+	stats.diskSize, err = dirSize(svn.repoPath(repoID))
+	if err != nil {
+		logger.WithError(err).Warning("unable to determine disk size of repository")
+		return stats
+	}
+	svn.statsCache.set(repoID, stats)
+	return stats
+}
+
+// WaitForConfig waits until the backend has been reloaded for the latest change to the repository's
+// config, or until the context is done. Returns the status of the config (see RepoDetails), and
+// why it failed.
+func (svn *SVNMan) WaitForConfig(ctx context.Context, repoID string) (status, reason string) {
+	return svn.backend.WaitForReload(ctx, repoID)
+}
@@ -0,0 +1,93 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/armadillica/svn-manager/backend"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestGetRepoDetails(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+
+	details, err := s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, "59eefa9cf488554678cae036", details.ProjectID)
+	assert.Equal(t, "dr. Stüvel <sybren@blender.studio>", details.Creator)
+	assert.False(t, details.CreatedOn.IsZero())
+	assert.Equal(t, "SVNMan unit test", details.AppName)
+	assert.Equal(t, "0.1.2.3-beta5-sub3", details.AppVersion)
+	assert.Equal(t, 0, details.HeadRevision)
+	assert.True(t, details.DiskSize > 0, "disk size should be positive")
+	assert.Equal(t, backend.StatusActive, details.ApacheStatus)
+	assert.Equal(t, "", details.ApacheConfigError)
+	assert.Equal(t, []UserAccess{}, details.Users)
+
+	s.mb.configStatus = backend.StatusFailed
+	s.mb.configReason = "Syntax error on line 3"
+	details, err = s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, backend.StatusFailed, details.ApacheStatus)
+	assert.Equal(t, "Syntax error on line 3", details.ApacheConfigError)
+
+	_, err = s.svn.GetRepoDetails("other-repo")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func (s *SVNManTestSuite) TestGetRepoDetailsCachesStats(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	details, err := s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	size := details.DiskSize
+
+	// Without a commit, the repository isn't walked again.
+	extra := filepath.Join(s.svn.repoPath("my-repo-id"), "db", "extra")
+	assert.Nil(t, ioutil.WriteFile(extra, make([]byte, 1000), 0644))
+	details, err = s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, size, details.DiskSize)
+
+	// A commit changes db/current.
+	current := filepath.Join(s.svn.repoPath("my-repo-id"), "db", "current")
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(current, later, later))
+	details, err = s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, size+1000, details.DiskSize)
+
+	// Deleting the repository forgets its stats.
+	assert.Nil(t, s.svn.DeleteRepo("my-repo-id", log.Fields{"in": "unittest"}))
+	_, ok := s.svn.statsCache.get("my-repo-id", 0, later)
+	assert.False(t, ok, "stats of deleted repository should be forgotten")
+}
+
+func (s *SVNManTestSuite) TestHeadRevision(t *check.C) {
+	s.createTestRepo(t, "my-repo-id")
+	current := filepath.Join(s.svn.repoPath("my-repo-id"), "db", "current")
+
+	write := func(content string) {
+		if err := ioutil.WriteFile(current, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %s: %s", current, err)
+		}
+	}
+
+	write("47\n")
+	rev, err := s.svn.headRevision("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, 47, rev)
+
+	// FSFS format 1 and 2.
+	write("12 3a 1\n")
+	rev, err = s.svn.headRevision("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, 12, rev)
+
+	write("")
+	_, err = s.svn.headRevision("my-repo-id")
+	assert.NotNil(t, err)
+}
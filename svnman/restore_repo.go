@@ -0,0 +1,91 @@
+package svnman
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/armadillica/svn-manager/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// RestoreRepo moves a repository that was deleted at the given time back out of the attic.
+// The timestamp is formatted as RFC3339fs, as it is in the attic.
+func (svn *SVNMan) RestoreRepo(repoID, timestamp string, logFields log.Fields) error {
+	event := events.RepoEvent{RepoID: repoID}
+	err := svn.restoreRepo(repoID, timestamp, logFields)
+	if err == nil {
+		if info, infoErr := svn.readRepoInfo(repoID); infoErr == nil {
+			event.ProjectID = info.ProjectID
+			event.Creator = info.Creator
+		}
+	}
+	svn.publishRepoEvent(events.RepoRestored, event, err)
+	return err
+}
+
+func (svn *SVNMan) restoreRepo(repoID, timestamp string, logFields log.Fields) error {
+	confPath := svn.confPath(repoID)
+	confAtticPath := svn.confAtticPathFs(repoID, timestamp)
+	repoPath := svn.repoPath(repoID)
+	atticPath := svn.atticPathFs(repoID, timestamp)
+
+	logger := log.WithFields(logFields).WithFields(log.Fields{
+		"conf":       confPath,
+		"conf_attic": confAtticPath,
+		"repo":       repoPath,
+		"attic":      atticPath,
+	})
+	logger.Debug("restoring repository from attic")
+
+	if _, err := os.Stat(repoPath); err == nil {
+		logger.Warning("repository already exists")
+		return ErrAlreadyExists
+	}
+	// Never replace the backend config of a live repository.
+	if _, err := os.Stat(confPath); err == nil {
+		logger.Warning("backend config already exists")
+		return ErrAlreadyExists
+	}
+	if _, err := os.Stat(atticPath); os.IsNotExist(err) {
+		logger.Warning("repository not found in attic")
+		return ErrNotInAttic
+	} else if err != nil {
+		logger.WithError(err).Error("unable to inspect attic")
+		return ErrRestore
+	}
+
+	if err := os.MkdirAll(filepath.Dir(repoPath), 0750); err != nil {
+		logger.WithError(err).Error("unable to create path for repo")
+		return ErrRestore
+	}
+	if err := os.MkdirAll(filepath.Dir(confPath), 0750); err != nil {
+		logger.WithError(err).Error("unable to create path for backend config")
+		return ErrRestore
+	}
+
+	// This is the reverse of DeleteRepo: first the repository, then the backend
+	// configuration that makes it accessible.
+	if err := os.Rename(atticPath, repoPath); err != nil {
+		logger.WithError(err).Error("unable to move repository out of attic")
+		return ErrRestore
+	}
+
+	err := os.Rename(confAtticPath, confPath)
+	if os.IsNotExist(err) {
+		// DeleteRepo accepts missing backend config files, so we can get here.
+		logger.Warning("backend config not found in attic, creating a new one")
+		_, err = svn.rewriteConfig(repoID)
+	}
+	if err != nil {
+		logger.WithError(err).Error("unable to restore backend config, moving repository back to attic")
+		if err := os.Rename(repoPath, atticPath); err != nil {
+			logger.WithError(err).Error("unable to move repository back to attic")
+		}
+		return ErrRestore
+	}
+
+	svn.indexRepoUsers(repoID)
+	svn.backend.QueueReload(repoID, confPath)
+	logger.Info("repository restored from attic")
+	return nil
+}
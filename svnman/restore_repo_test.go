@@ -0,0 +1,117 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+// deleteTestRepo deletes the repository, and returns its timestamp in the attic.
+func (s *SVNManTestSuite) deleteTestRepo(t *check.C, repoID string) string {
+	if err := s.svn.DeleteRepo(repoID, log.Fields{"in": "unittest"}); err != nil {
+		t.Fatalf("unable to delete repo: %s", err)
+	}
+
+	glob := s.svn.atticPathFs(repoID, "*")
+	found, err := filepath.Glob(glob)
+	if err != nil || len(found) == 0 {
+		t.Fatalf("repository not found in attic with %s: %s", glob, err)
+	}
+	// Multiple deletions in the same second share the same timestamp.
+	return strings.TrimPrefix(filepath.Base(found[len(found)-1]), repoID+"-")
+}
+
+func (s *SVNManTestSuite) TestRestoreRepoHappy(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	timestamp := s.deleteTestRepo(t, "my-repo-id")
+	s.mb = mockBackend{}
+
+	err := s.svn.RestoreRepo("my-repo-id", timestamp, logFields)
+	assert.Nil(t, err)
+	assert.True(t, s.mb.reloadCalled, "a backend reload should have been queued")
+
+	_, err = os.Stat(s.svn.repoPath("my-repo-id"))
+	assert.Nil(t, err, "repository should have been restored")
+	_, err = os.Stat(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err, "Apache config should have been restored")
+	_, err = os.Stat(s.svn.atticPathFs("my-repo-id", timestamp))
+	assert.True(t, os.IsNotExist(err), "repository should no longer be in the attic")
+	_, err = os.Stat(s.svn.confAtticPathFs("my-repo-id", timestamp))
+	assert.True(t, os.IsNotExist(err), "Apache config should no longer be in the attic")
+
+	info, err := s.svn.readRepoInfo("my-repo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, "59eefa9cf488554678cae036", info.ProjectID)
+
+	// Restoring twice should not work.
+	err = s.svn.RestoreRepo("my-repo-id", timestamp, logFields)
+	assert.Equal(t, ErrAlreadyExists, err)
+}
+
+func (s *SVNManTestSuite) TestRestoreRepoLiveRepoExists(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	timestamp := s.deleteTestRepo(t, "my-repo-id")
+	s.createTestRepo(t, "my-repo-id")
+	s.mb = mockBackend{}
+
+	err := s.svn.RestoreRepo("my-repo-id", timestamp, logFields)
+	assert.Equal(t, ErrAlreadyExists, err)
+	assert.False(t, s.mb.reloadCalled, "no backend reload should have been queued")
+
+	_, err = os.Stat(s.svn.atticPathFs("my-repo-id", timestamp))
+	assert.Nil(t, err, "repository should still be in the attic")
+}
+
+func (s *SVNManTestSuite) TestRestoreRepoLiveConfigExists(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	timestamp := s.deleteTestRepo(t, "my-repo-id")
+	if err := ioutil.WriteFile(s.svn.confPath("my-repo-id"), []byte("live config"), 0644); err != nil {
+		t.Fatalf("unable to write config: %s", err)
+	}
+	s.mb = mockBackend{}
+
+	err := s.svn.RestoreRepo("my-repo-id", timestamp, logFields)
+	assert.Equal(t, ErrAlreadyExists, err)
+	assert.False(t, s.mb.reloadCalled, "no backend reload should have been queued")
+
+	contents, err := ioutil.ReadFile(s.svn.confPath("my-repo-id"))
+	assert.Nil(t, err)
+	assert.Equal(t, "live config", string(contents), "live config should not have been replaced")
+	_, err = os.Stat(s.svn.atticPathFs("my-repo-id", timestamp))
+	assert.Nil(t, err, "repository should still be in the attic")
+	_, err = os.Stat(s.svn.confAtticPathFs("my-repo-id", timestamp))
+	assert.Nil(t, err, "config should still be in the attic")
+}
+
+func (s *SVNManTestSuite) TestRestoreRepoNotInAttic(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+
+	err := s.svn.RestoreRepo("my-repo-id", "2017-11-14T16-30-04Z-00", logFields)
+	assert.Equal(t, ErrNotInAttic, err)
+	assert.False(t, s.mb.reloadCalled, "no backend reload should have been queued")
+}
+
+func (s *SVNManTestSuite) TestRestoreRepoWithoutApacheConfig(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "my-repo-id")
+	timestamp := s.deleteTestRepo(t, "my-repo-id")
+
+	if err := os.Remove(s.svn.confAtticPathFs("my-repo-id", timestamp)); err != nil {
+		t.Fatalf("unable to remove Apache config from attic: %s", err)
+	}
+
+	err := s.svn.RestoreRepo("my-repo-id", timestamp, logFields)
+	assert.Nil(t, err)
+
+	apa := s.readApacheConfig(t, "my-repo-id")
+	assert.Contains(t, apa, "/repo/my-repo-id")
+	assert.Contains(t, apa, "59eefa9cf488554678cae036")
+}
@@ -1,14 +1,20 @@
 package svnman
 
 import (
+	"context"
 	"errors"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
-	"github.com/armadillica/svn-manager/apache"
+	"github.com/armadillica/svn-manager/backend"
+	"github.com/armadillica/svn-manager/events"
 	"github.com/foomo/htpasswd"
 	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 )
 
 var (
@@ -22,6 +28,36 @@ var (
 	ErrNotFound = errors.New("repository with this ID does not exist")
 	// ErrDeletion indicates that a repository deletion failed. Specifics are logged.
 	ErrDeletion = errors.New("unable to delete repository")
+	// ErrNotInAttic indicates that the requested repository does not exist in the attic.
+	ErrNotInAttic = errors.New("repository with this ID and timestamp does not exist in the attic")
+	// ErrRestore indicates that restoring a repository from the attic failed. Specifics are logged.
+	ErrRestore = errors.New("unable to restore repository")
+	// ErrPurge indicates that permanently removing a repository from the attic failed. Specifics are logged.
+	ErrPurge = errors.New("unable to purge repository from attic")
+	// ErrLoad indicates that loading a dump into a new repository failed. Specifics are logged.
+	ErrLoad = errors.New("unable to load repository from dump")
+	// ErrInvalidRevision indicates that the requested revision (range) does not exist in the repository.
+	ErrInvalidRevision = errors.New("invalid revision given")
+	// ErrUnknownHook indicates that the requested hook does not exist in the hook catalogue.
+	ErrUnknownHook = errors.New("hook with this name does not exist")
+	// ErrInvalidHookParam indicates that hook parameters were invalid. Specifics are logged.
+	ErrInvalidHookParam = errors.New("invalid hook parameter given")
+	// ErrConflictingHooks indicates that a hook was both installed and removed in one request.
+	ErrConflictingHooks = errors.New("hook cannot be both installed and removed")
+	// ErrInvalidAuthz indicates that authorization rules were invalid. Specifics are logged.
+	ErrInvalidAuthz = errors.New("invalid authorization rules given")
+	// ErrNoPassword indicates that access was granted to a new user without giving a password.
+	ErrNoPassword = errors.New("no password known for this user")
+	// ErrPasswordConflict indicates that access was granted to an existing user with another password.
+	ErrPasswordConflict = errors.New("user already exists with a different password")
+	// ErrBackupsDisabled is returned by backup operations when no backup root is configured.
+	ErrBackupsDisabled = errors.New("backups are not enabled")
+	// ErrNoBackup indicates that there is no backup of the requested repository.
+	ErrNoBackup = errors.New("no backup of this repository exists")
+	// ErrBackup indicates that backing up a repository failed. Specifics are logged.
+	ErrBackup = errors.New("unable to back up repository")
+	// ErrPack indicates that packing a repository failed. Specifics are logged.
+	ErrPack = errors.New("unable to pack repository")
 )
 
 // RFC3339fs is a filesystem-friendly version of RFC3339.
@@ -30,29 +66,86 @@ const RFC3339fs = "2006-01-02T15-04-05Z07-00"
 // Manager contains the interface of SVNMan, for testing/mocking purposes.
 type Manager interface {
 	CreateRepo(repoInfo CreateRepo, logFields log.Fields) error
+	LoadRepo(ctx context.Context, repoInfo CreateRepo, dump io.Reader, progress LoadProgressFunc, logFields log.Fields) error
 	ModifyAccess(repoID string, mods ModifyAccess, logFields log.Fields) error
+	SetPassword(username string, pw SetPassword, logFields log.Fields) ([]string, error)
+	GetUserRepos(username string) ([]UserRepo, error)
+	GetAuthz(repoID string) (Authz, error)
+	SetAuthz(repoID string, authz Authz, logFields log.Fields) error
 	GetUsernames(repoID string) ([]string, error)
+	GetRepoDetails(repoID string) (RepoDetails, error)
+	WaitForConfig(ctx context.Context, repoID string) (status, reason string)
+	DumpRepo(ctx context.Context, repoID string, options DumpRepo, w io.Writer, logFields log.Fields) error
+	VerifyRepo(ctx context.Context, repoID string, progress VerifyProgressFunc, logFields log.Fields) (VerifyResult, error)
+	PackRepo(ctx context.Context, repoID string, logFields log.Fields) error
+	ListRepos(query ListRepos) (RepoList, error)
 	DeleteRepo(repoID string, logFields log.Fields) error
+	BlockRepo(repoID string, block BlockRepo, logFields log.Fields) error
+	UnblockRepo(repoID string, logFields log.Fields) error
+	GetBlockState(repoID string) (BlockState, error)
+	ListAvailableHooks() ([]HookDescription, error)
+	GetRepoHooks(repoID string) ([]InstalledHook, error)
+	ModifyHooks(repoID string, mods ModifyHooks, logFields log.Fields) error
+	NotifyCommit(repoID string, revision int, logFields log.Fields) error
+	RestoreRepo(repoID, timestamp string, logFields log.Fields) error
+	ListAttic(repoID string) ([]AtticEntry, error)
+	PurgeAtticEntry(repoID, timestamp string, logFields log.Fields) error
+	BackupRepo(ctx context.Context, repoID string, logFields log.Fields) (BackupState, error)
+	GetBackupState(repoID string) (BackupState, error)
+	RestoreBackup(ctx context.Context, repoID string, logFields log.Fields) error
 }
 
 // SVNMan provides SVN management operations.
 type SVNMan struct {
-	restarter       apache.Restarter
-	repoRoot        string
-	apacheConfigDir string
+	backend       backend.Backend
+	publisher     events.Publisher
+	repoRoot      string
+	configDir     string // contains the backend config files.
+	backupRoot    string // backups are disabled when empty.
+	userDir       string // shared htpasswd file; every repository has its own when empty.
+	hookCatalogue string // found via filelocator when empty.
+	notifyURL     string // base URL of our API, for commit notifications from hooks.
+
+	// Replaces the steps of assembling a new repository when not nil, for testing.
+	creationSteps []repoCreationStep
 
 	// To store in the info.txt file.
 	appName    string
 	appVersion string
+
+	// Concurrent hotcopies to the same destination would conflict.
+	backupLocks repoLocks
+	// Protects the htpasswd files against concurrent password changes.
+	userMutex sync.Mutex
+	// Serialise read-modify-write cycles of the info files.
+	infoLocks repoLocks
+	// Serialise moving new repositories into place.
+	createLocks repoLocks
+	userIndex   userIndex
+	statsCache  statsCache
 }
 
 // Create returns a newly created SVNMan instance.
-func Create(restarter apache.Restarter, repoRoot, apacheConfigDir, appName, appVersion string) *SVNMan {
+func Create(serverBackend backend.Backend, publisher events.Publisher,
+	repoRoot, configDir, backupRoot, userDir, notifyURL, appName, appVersion string) *SVNMan {
 	log.WithFields(log.Fields{
-		"repo_root": repoRoot,
-		"apache":    apacheConfigDir,
+		"repo_root":   repoRoot,
+		"config_dir":  configDir,
+		"backup_root": backupRoot,
+		"user_dir":    userDir,
+		"notify_url":  notifyURL,
 	}).Info("creating SVN manager")
-	return &SVNMan{restarter, repoRoot, apacheConfigDir, appName, appVersion}
+	return &SVNMan{
+		backend:    serverBackend,
+		publisher:  publisher,
+		repoRoot:   repoRoot,
+		configDir:  configDir,
+		backupRoot: backupRoot,
+		userDir:    userDir,
+		notifyURL:  notifyURL,
+		appName:    appName,
+		appVersion: appVersion,
+	}
 }
 
 func (svn *SVNMan) repoPath(repoID string) string {
@@ -61,29 +154,144 @@ func (svn *SVNMan) repoPath(repoID string) string {
 }
 
 func (svn *SVNMan) atticPath(repoID string, timestamp time.Time) string {
+	return svn.atticPathFs(repoID, timestamp.Format(RFC3339fs))
+}
+
+// atticPathFs returns the attic path for a timestamp already formatted as RFC3339fs.
+func (svn *SVNMan) atticPathFs(repoID, timestamp string) string {
 	prefix := string([]rune(repoID)[:2])
-	fname := repoID + "-" + timestamp.Format(RFC3339fs)
+	fname := repoID + "-" + timestamp
 	return filepath.Join(svn.repoRoot, "attic", prefix, fname)
 }
 
-func (svn *SVNMan) apaConfPath(repoID string) string {
+func (svn *SVNMan) confPath(repoID string) string {
 	prefix := string([]rune(repoID)[:2])
-	fname := "svn-" + repoID + ".conf"
-	return filepath.Join(svn.apacheConfigDir, prefix, fname)
+	return filepath.Join(svn.configDir, prefix, svn.backend.ConfigFilename(repoID))
 }
 
-func (svn *SVNMan) apaAtticPath(repoID string, timestamp time.Time) string {
+func (svn *SVNMan) confAtticPath(repoID string, timestamp time.Time) string {
+	return svn.confAtticPathFs(repoID, timestamp.Format(RFC3339fs))
+}
+
+// confAtticPathFs returns the attic path for a timestamp already formatted as RFC3339fs.
+func (svn *SVNMan) confAtticPathFs(repoID, timestamp string) string {
 	prefix := string([]rune(repoID)[:2])
-	fname := "svn-" + repoID + ".conf-" + timestamp.Format(RFC3339fs)
-	return filepath.Join(svn.apacheConfigDir, "attic", prefix, fname)
+	fname := svn.backend.ConfigFilename(repoID) + "-" + timestamp
+	return filepath.Join(svn.configDir, "attic", prefix, fname)
+}
+
+func (svn *SVNMan) backupPath(repoID string) string {
+	prefix := string([]rune(repoID)[:2])
+	return filepath.Join(svn.backupRoot, prefix, repoID)
+}
+
+// backupInfoPath returns the path of the file describing the backup. It is stored next to
+// the backup, as 'svnadmin hotcopy' manages everything inside the backup directory.
+func (svn *SVNMan) backupInfoPath(repoID string) string {
+	return svn.backupPath(repoID) + ".yaml"
 }
 
 func (svn *SVNMan) htpasswd(repoID string) string {
 	return filepath.Join(svn.repoPath(repoID), "htpasswd")
 }
 
+func (svn *SVNMan) infoPath(repoID string) string {
+	return filepath.Join(svn.repoPath(repoID), "info.yaml")
+}
+
+// repoLocks provides a mutex per repository, created when first needed.
+type repoLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the repository's mutex, and returns it for unlocking.
+func (rl *repoLocks) lock(repoID string) *sync.Mutex {
+	rl.mutex.Lock()
+	if rl.locks == nil {
+		rl.locks = map[string]*sync.Mutex{}
+	}
+	repoMutex, found := rl.locks[repoID]
+	if !found {
+		repoMutex = &sync.Mutex{}
+		rl.locks[repoID] = repoMutex
+	}
+	rl.mutex.Unlock()
+
+	repoMutex.Lock()
+	return repoMutex
+}
+
+// readRepoInfo loads the info.yaml file of the repository. Callers that modify and write the
+// info should hold svn.infoLocks for the repository.
+func (svn *SVNMan) readRepoInfo(repoID string) (repoinfo, error) {
+	return readRepoInfoFile(svn.infoPath(repoID))
+}
+
+// readRepoInfoFile loads an info.yaml file, which may also be in the attic.
+func readRepoInfoFile(filename string) (repoinfo, error) {
+	info := repoinfo{}
+	infobytes, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return info, ErrNotFound
+	} else if err != nil {
+		return info, err
+	}
+	if err := yaml.Unmarshal(infobytes, &info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// writeRepoInfo saves the info.yaml file of the repository.
+func (svn *SVNMan) writeRepoInfo(info repoinfo) error {
+	return writeRepoInfoFile(svn.infoPath(info.RepoID), info)
+}
+
+// writeRepoInfoFile saves an info.yaml file, which may also be in a staging directory.
+func writeRepoInfoFile(filename string, info repoinfo) error {
+	infobytes, err := yaml.Marshal(&info)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(filename, infobytes, 0644)
+}
+
+// dirSize returns the total size of the files in the directory, in bytes.
+func dirSize(dirname string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dirname, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// publishRepoEvent publishes the outcome of an operation on a repository.
+func (svn *SVNMan) publishRepoEvent(routingKey string, event events.RepoEvent, err error) {
+	event.Success = err == nil
+	if err != nil {
+		event.Error = err.Error()
+	}
+	event.Timestamp = time.Now().UTC()
+	svn.publisher.Publish(routingKey, event)
+}
+
 // GetUsernames returns the list of usernames that have access to the given repository.
 func (svn *SVNMan) GetUsernames(repoID string) ([]string, error) {
+	if svn.userDir != "" {
+		return svn.readMembers(repoID)
+	}
+	return svn.htpasswdUsernames(repoID)
+}
+
+// htpasswdUsernames returns the usernames in the repository's own htpasswd file.
+func (svn *SVNMan) htpasswdUsernames(repoID string) ([]string, error) {
 	filename := svn.htpasswd(repoID)
 	passwds, err := htpasswd.ParseHtpasswdFile(filename)
 	if os.IsNotExist(err) {
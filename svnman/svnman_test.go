@@ -1,31 +1,58 @@
 package svnman
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"text/template"
 
+	"github.com/armadillica/svn-manager/apache"
+	"github.com/armadillica/svn-manager/backend"
+	"github.com/armadillica/svn-manager/events"
 	check "gopkg.in/check.v1"
 )
 
-type mockRestarter struct {
-	restartCalled        bool
-	flushCalled          bool
-	performRestartCalled bool
+// Config files are rendered with the Apache template shipped with SVN Manager.
+var testApacheTemplate = template.Must(apache.LoadTemplate("../apache_templates/location.conf.tmpl"))
+
+// mockBackend renders Apache config files, but doesn't reload anything.
+type mockBackend struct {
+	reloadCalled        bool
+	flushCalled         bool
+	performReloadCalled bool
+	configStatus        string // status of every config; active when empty.
+	configReason        string
 }
 
-func (mr *mockRestarter) QueueRestart() {
-	mr.restartCalled = true
+func (mb *mockBackend) QueueReload(repoID, configFile string) {
+	mb.reloadCalled = true
+}
+func (mb *mockBackend) Flush() {
+	mb.flushCalled = true
 }
-func (mr *mockRestarter) Flush() {
-	mr.flushCalled = true
+func (mb *mockBackend) PerformReload() {
+	mb.performReloadCalled = true
+}
+func (mb *mockBackend) ConfigStatus(repoID string) (string, string) {
+	if mb.configStatus == "" {
+		return backend.StatusActive, ""
+	}
+	return mb.configStatus, mb.configReason
 }
-func (mr *mockRestarter) PerformRestart() {
-	mr.performRestartCalled = true
+func (mb *mockBackend) WaitForReload(ctx context.Context, repoID string) (string, string) {
+	return mb.ConfigStatus(repoID)
+}
+func (mb *mockBackend) ConfigFilename(repoID string) string {
+	return apache.ConfigFilename(repoID)
+}
+func (mb *mockBackend) RenderConfig(repo backend.Repo) ([]byte, error) {
+	return apache.RenderConfig(testApacheTemplate, repo)
 }
 
 type SVNManTestSuite struct {
 	svn *SVNMan
-	mr  mockRestarter
+	mb  mockBackend
+	mp  events.MemoryPublisher
 }
 
 var _ = check.Suite(&SVNManTestSuite{})
@@ -39,13 +66,17 @@ func mustTempDir(dir, prefix string) string {
 }
 
 func (s *SVNManTestSuite) SetUpTest(t *check.C) {
-	s.mr = mockRestarter{}
+	s.mb = mockBackend{}
+	s.mp.Reset()
 	s.svn = &SVNMan{
-		restarter:       &s.mr,
-		repoRoot:        mustTempDir("", "reporoot"),
-		apacheConfigDir: mustTempDir("", "apache"),
-		appName:         "SVNMan unit test",
-		appVersion:      "0.1.2.3-beta5-sub3",
+		backend:    &s.mb,
+		publisher:  &s.mp,
+		repoRoot:   mustTempDir("", "reporoot"),
+		configDir:  mustTempDir("", "config"),
+		backupRoot: mustTempDir("", "backups"),
+		notifyURL:  "http://localhost:8085/api",
+		appName:    "SVNMan unit test",
+		appVersion: "0.1.2.3-beta5-sub3",
 	}
 }
 
@@ -53,7 +84,10 @@ func (s *SVNManTestSuite) TearDownTest(t *check.C) {
 	if err := os.RemoveAll(s.svn.repoRoot); err != nil {
 		t.Fatal("unable to remove repoRoot", s.svn.repoRoot, err)
 	}
-	if err := os.RemoveAll(s.svn.apacheConfigDir); err != nil {
-		t.Fatal("unable to remove apacheConfigDir", s.svn.apacheConfigDir, err)
+	if err := os.RemoveAll(s.svn.configDir); err != nil {
+		t.Fatal("unable to remove configDir", s.svn.configDir, err)
+	}
+	if err := os.RemoveAll(s.svn.backupRoot); err != nil {
+		t.Fatal("unable to remove backupRoot", s.svn.backupRoot, err)
 	}
 }
@@ -0,0 +1,143 @@
+package svnman
+
+import (
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// userIndex keeps track of which repositories users have access to, so that finding them
+// doesn't require reading every htpasswd file. It is built on first use, and updated by
+// SVNMan whenever the users of a repository change.
+type userIndex struct {
+	mutex     sync.Mutex
+	built     bool
+	repoUsers map[string][]string        // repository ID to usernames.
+	userRepos map[string]map[string]bool // username to set of repository IDs.
+}
+
+// setRepoUsers replaces the users of the repository.
+func (ui *userIndex) setRepoUsers(repoID string, usernames []string) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	if !ui.built {
+		return
+	}
+	ui.removeRepoLocked(repoID)
+	ui.addRepoLocked(repoID, usernames)
+}
+
+// removeRepo forgets about the repository.
+func (ui *userIndex) removeRepo(repoID string) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	if !ui.built {
+		return
+	}
+	ui.removeRepoLocked(repoID)
+}
+
+func (ui *userIndex) addRepoLocked(repoID string, usernames []string) {
+	ui.repoUsers[repoID] = usernames
+	for _, username := range usernames {
+		if ui.userRepos[username] == nil {
+			ui.userRepos[username] = map[string]bool{}
+		}
+		ui.userRepos[username][repoID] = true
+	}
+}
+
+func (ui *userIndex) removeRepoLocked(repoID string) {
+	for _, username := range ui.repoUsers[repoID] {
+		delete(ui.userRepos[username], repoID)
+		if len(ui.userRepos[username]) == 0 {
+			delete(ui.userRepos, username)
+		}
+	}
+	delete(ui.repoUsers, repoID)
+}
+
+// reposOfUser returns the sorted IDs of the repositories the user has access to. The index
+// is built with the given function when this is the first lookup; when that fails, the next
+// lookup tries again.
+func (ui *userIndex) reposOfUser(username string, build func() (map[string][]string, error)) ([]string, error) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	if !ui.built {
+		repoUsers, err := build()
+		if err != nil {
+			return nil, err
+		}
+		ui.repoUsers = map[string][]string{}
+		ui.userRepos = map[string]map[string]bool{}
+		for repoID, usernames := range repoUsers {
+			ui.addRepoLocked(repoID, usernames)
+		}
+		ui.built = true
+	}
+
+	repoIDs := []string{}
+	for repoID := range ui.userRepos[username] {
+		repoIDs = append(repoIDs, repoID)
+	}
+	sort.Strings(repoIDs)
+	return repoIDs, nil
+}
+
+// scanRepoUsers reads the users of every repository, for building the user index.
+func (svn *SVNMan) scanRepoUsers() (map[string][]string, error) {
+	repoIDs, err := svn.repoIDs()
+	if err != nil {
+		log.WithError(err).Error("unable to list repositories for user index")
+		return nil, err
+	}
+	repoUsers := map[string][]string{}
+	for _, repoID := range repoIDs {
+		usernames, err := svn.GetUsernames(repoID)
+		if err != nil {
+			log.WithError(err).WithField("repo_id", repoID).Error("unable to read users of repository for user index")
+			return nil, err
+		}
+		repoUsers[repoID] = usernames
+	}
+	log.WithField("repo_count", len(repoUsers)).Info("user index built")
+	return repoUsers, nil
+}
+
+// indexRepoUsers updates the user index after the users of the repository changed.
+func (svn *SVNMan) indexRepoUsers(repoID string) {
+	usernames, err := svn.GetUsernames(repoID)
+	if err != nil {
+		log.WithError(err).WithField("repo_id", repoID).Warning("unable to read users of repository for user index")
+		svn.userIndex.removeRepo(repoID)
+		return
+	}
+	svn.userIndex.setRepoUsers(repoID, usernames)
+}
+
+// repoIDsOfUser returns the IDs of the repositories the user has access to.
+func (svn *SVNMan) repoIDsOfUser(username string) ([]string, error) {
+	return svn.userIndex.reposOfUser(username, svn.scanRepoUsers)
+}
+
+// GetUserRepos returns the repositories the user has access to, sorted by repository ID.
+func (svn *SVNMan) GetUserRepos(username string) ([]UserRepo, error) {
+	repoIDs, err := svn.repoIDsOfUser(username)
+	if err != nil {
+		return nil, err
+	}
+	repos := []UserRepo{}
+	for _, repoID := range repoIDs {
+		info, err := svn.readRepoInfo(repoID)
+		if err == ErrNotFound {
+			// Deleted since it was indexed.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		repos = append(repos, UserRepo{RepoID: repoID, ProjectID: info.ProjectID})
+	}
+	return repos, nil
+}
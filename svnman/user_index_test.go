@@ -0,0 +1,109 @@
+package svnman
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) reposOfUser(t *check.C, username string) []string {
+	repoIDs, err := s.svn.repoIDsOfUser(username)
+	if err != nil {
+		t.Fatalf("unable to find repositories of %s: %s", username, err)
+	}
+	return repoIDs
+}
+
+func (s *SVNManTestSuite) TestGetUserRepos(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+	s.createTestRepo(t, "5678")
+	grant := func(repoID, username string) {
+		err := s.svn.ModifyAccess(repoID, ModifyAccess{
+			Grant: []ModifyAccessGrantEntry{{Username: username, Password: "$2y$05$cWZN0CJN"}},
+		}, logFields)
+		assert.Nil(t, err)
+	}
+	grant("1234", "alice@example.com")
+	grant("5678", "alice@example.com")
+	grant("5678", "bob")
+
+	// The first lookup scans the htpasswd files.
+	repos, err := s.svn.GetUserRepos("alice@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []UserRepo{
+		{RepoID: "1234", ProjectID: "59eefa9cf488554678cae036"},
+		{RepoID: "5678", ProjectID: "59eefa9cf488554678cae036"},
+	}, repos)
+
+	repos, err = s.svn.GetUserRepos("nobody")
+	assert.Nil(t, err)
+	assert.Equal(t, []UserRepo{}, repos)
+
+	// Later changes update the index.
+	grant("1234", "bob")
+	err = s.svn.ModifyAccess("5678", ModifyAccess{Revoke: []string{"alice@example.com"}}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1234"}, s.reposOfUser(t, "alice@example.com"))
+	assert.Equal(t, []string{"1234", "5678"}, s.reposOfUser(t, "bob"))
+
+	assert.Nil(t, s.svn.DeleteRepo("1234", logFields))
+	assert.Equal(t, []string{}, s.reposOfUser(t, "alice@example.com"))
+	assert.Equal(t, []string{"5678"}, s.reposOfUser(t, "bob"))
+
+	s.createTestRepo(t, "9abc")
+	grant("9abc", "bob")
+	assert.Equal(t, []string{"5678", "9abc"}, s.reposOfUser(t, "bob"))
+}
+
+func (s *SVNManTestSuite) TestUserIndexRestoreRepo(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+	assert.Nil(t, s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$cWZN0CJN"}},
+	}, logFields))
+	assert.Equal(t, []string{"1234"}, s.reposOfUser(t, "alice"))
+
+	assert.Nil(t, s.svn.DeleteRepo("1234", logFields))
+	assert.Equal(t, []string{}, s.reposOfUser(t, "alice"))
+
+	attic, err := s.svn.ListAttic("1234")
+	assert.Nil(t, err)
+	if len(attic) != 1 {
+		t.Fatalf("expected one attic entry, got %#v", attic)
+	}
+	assert.Nil(t, s.svn.RestoreRepo("1234", attic[0].Timestamp, logFields))
+	assert.Equal(t, []string{"1234"}, s.reposOfUser(t, "alice"))
+}
+
+func (s *SVNManTestSuite) TestUserIndexBuildFailure(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+	assert.Nil(t, s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$cWZN0CJN"}},
+	}, logFields))
+
+	// An unreadable htpasswd file fails the lookup, rather than giving an incomplete answer.
+	htpasswdFile := s.svn.htpasswd("1234")
+	if err := os.Rename(htpasswdFile, htpasswdFile+".moved"); err != nil {
+		t.Fatalf("unable to move htpasswd: %s", err)
+	}
+	if err := os.Mkdir(htpasswdFile, 0750); err != nil {
+		t.Fatalf("unable to create directory: %s", err)
+	}
+	_, err := s.svn.GetUserRepos("alice")
+	assert.NotNil(t, err)
+
+	// The failed index isn't kept.
+	if err := os.Remove(htpasswdFile); err != nil {
+		t.Fatalf("unable to remove directory: %s", err)
+	}
+	if err := os.Rename(htpasswdFile+".moved", htpasswdFile); err != nil {
+		t.Fatalf("unable to move htpasswd back: %s", err)
+	}
+	repos, err := s.svn.GetUserRepos("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []UserRepo{{RepoID: "1234", ProjectID: "59eefa9cf488554678cae036"}}, repos)
+}
@@ -0,0 +1,298 @@
+package svnman
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/foomo/htpasswd"
+	log "github.com/sirupsen/logrus"
+)
+
+// Returns the path of the htpasswd file in the user directory, shared by all repositories.
+func (svn *SVNMan) userHtpasswd() string {
+	return filepath.Join(svn.userDir, "htpasswd")
+}
+
+// Returns the path of the repository's member list. This is an Apache AuthGroupFile with
+// one group, named after the repository.
+func (svn *SVNMan) membersPath(repoID string) string {
+	return filepath.Join(svn.repoPath(repoID), "members")
+}
+
+// writeFileAtomically writes the file via a temporary file, so that readers like Apache
+// never see a half-written file.
+func writeFileAtomically(filename string, data []byte, perm os.FileMode) error {
+	tempfile := filename + ".new"
+	if err := ioutil.WriteFile(tempfile, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tempfile, filename)
+}
+
+// readUserPasswords returns the passwords from the user directory; there are none when it
+// doesn't exist yet.
+func (svn *SVNMan) readUserPasswords() (htpasswd.HashedPasswords, error) {
+	passwds, err := htpasswd.ParseHtpasswdFile(svn.userHtpasswd())
+	if os.IsNotExist(err) {
+		return htpasswd.HashedPasswords{}, nil
+	}
+	return passwds, err
+}
+
+// updateUserPasswords stores the given passwords in the user directory. When overwrite is
+// false, users that already have a password keep it, and the sorted usernames whose given
+// password was different are returned.
+func (svn *SVNMan) updateUserPasswords(passwords map[string]string, overwrite bool) ([]string, error) {
+	svn.userMutex.Lock()
+	defer svn.userMutex.Unlock()
+
+	passwds, err := svn.readUserPasswords()
+	if err != nil {
+		return nil, err
+	}
+	conflicts := []string{}
+	for username, password := range passwords {
+		if existing, exists := passwds[username]; exists && !overwrite {
+			if existing != password {
+				conflicts = append(conflicts, username)
+			}
+			continue
+		}
+		passwds[username] = password
+	}
+	sort.Strings(conflicts)
+
+	if err := os.MkdirAll(svn.userDir, 0750); err != nil {
+		return nil, err
+	}
+	return conflicts, writeFileAtomically(svn.userHtpasswd(), passwds.Bytes(), 0640)
+}
+
+// SetPassword sets the password of a user for all repositories the user has access to, and
+// returns the IDs of those repositories. With a user directory, the password is stored there.
+// Otherwise it is changed in the htpasswd file of every repository that contains the user.
+func (svn *SVNMan) SetPassword(username string, pw SetPassword, logFields log.Fields) ([]string, error) {
+	logger := log.WithFields(logFields).WithField("username", username)
+	if svn.userDir == "" {
+		return svn.setHtpasswdPasswords(username, pw.Password, logger)
+	}
+
+	if _, err := svn.updateUserPasswords(map[string]string{username: pw.Password}, true); err != nil {
+		logger.WithError(err).Error("unable to save password in user directory")
+		return nil, err
+	}
+	repoIDs, err := svn.repoIDsOfUser(username)
+	if err != nil {
+		logger.WithError(err).Error("unable to find repositories of user")
+		return nil, err
+	}
+	logger.WithField("repo_count", len(repoIDs)).Info("password set in user directory")
+	return repoIDs, nil
+}
+
+// setHtpasswdPasswords changes the password of the user in the htpasswd file of every
+// repository that contains the user. Either all files are changed, or none are.
+func (svn *SVNMan) setHtpasswdPasswords(username, password string, logger *log.Entry) ([]string, error) {
+	svn.userMutex.Lock()
+	defer svn.userMutex.Unlock()
+
+	allRepoIDs, err := svn.repoIDs()
+	if err != nil {
+		logger.WithError(err).Error("unable to list repositories")
+		return nil, err
+	}
+
+	// Write all new files next to the current ones first, so that failures leave everything
+	// as it was.
+	type change struct {
+		repoID   string
+		filename string
+		previous []byte
+	}
+	changes := []change{}
+	discard := func(changes []change) {
+		for _, ch := range changes {
+			os.Remove(ch.filename + ".new")
+		}
+	}
+	for _, repoID := range allRepoIDs {
+		filename := svn.htpasswd(repoID)
+		repoLogger := logger.WithFields(log.Fields{"repo_id": repoID, "filename": filename})
+		previous, err := ioutil.ReadFile(filename)
+		if err != nil {
+			repoLogger.WithError(err).Error("unable to read htpasswd")
+			discard(changes)
+			return nil, err
+		}
+		passwds, err := htpasswd.ParseHtpasswd(previous)
+		if err != nil {
+			repoLogger.WithError(err).Error("unable to parse htpasswd")
+			discard(changes)
+			return nil, err
+		}
+		if _, found := passwds[username]; !found {
+			continue
+		}
+		passwds[username] = password
+		if err := ioutil.WriteFile(filename+".new", passwds.Bytes(), 0640); err != nil {
+			repoLogger.WithError(err).Error("unable to write htpasswd")
+			discard(changes)
+			return nil, err
+		}
+		changes = append(changes, change{repoID, filename, previous})
+	}
+
+	repoIDs := make([]string, len(changes))
+	for idx, ch := range changes {
+		if err := os.Rename(ch.filename+".new", ch.filename); err != nil {
+			logger.WithError(err).WithField("repo_id", ch.repoID).Error("unable to replace htpasswd, rolling back")
+			for _, done := range changes[:idx] {
+				if err := writeFileAtomically(done.filename, done.previous, 0640); err != nil {
+					logger.WithError(err).WithField("repo_id", done.repoID).Error("unable to roll back htpasswd")
+				}
+			}
+			discard(changes[idx:])
+			return nil, err
+		}
+		repoIDs[idx] = ch.repoID
+	}
+
+	logger.WithField("repo_count", len(repoIDs)).Info("password changed in htpasswd files")
+	return repoIDs, nil
+}
+
+// readMembers returns the users with access to the repository, from its member list.
+// Repositories not migrated to the user directory yet only have an htpasswd file.
+func (svn *SVNMan) readMembers(repoID string) ([]string, error) {
+	contents, err := ioutil.ReadFile(svn.membersPath(repoID))
+	if os.IsNotExist(err) {
+		return svn.htpasswdUsernames(repoID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(string(contents), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("unexpected contents in %s", svn.membersPath(repoID))
+	}
+	return strings.Fields(parts[1]), nil
+}
+
+// writeMembers saves the member list of the repository.
+func (svn *SVNMan) writeMembers(repoID string, usernames []string) error {
+	sorted := append([]string{}, usernames...)
+	sort.Strings(sorted)
+	contents := fmt.Sprintf("%s: %s\n", repoID, strings.Join(sorted, " "))
+	return writeFileAtomically(svn.membersPath(repoID), []byte(contents), 0640)
+}
+
+// needsUserMigration returns whether the repository still uses its own htpasswd file,
+// while the user directory is enabled.
+func (svn *SVNMan) needsUserMigration(repoID string) bool {
+	if svn.userDir == "" {
+		return false
+	}
+	_, err := os.Stat(svn.membersPath(repoID))
+	return os.IsNotExist(err)
+}
+
+// migrateUsers moves the users of a repository into the user directory. Users that are
+// already in the user directory keep their password there; when that differs from their
+// password in this repository, they have to use the other one from now on. The repository's
+// htpasswd file is left alone, but no longer used.
+func (svn *SVNMan) migrateUsers(repoID string, logger *log.Entry) error {
+	if !svn.needsUserMigration(repoID) {
+		return nil
+	}
+
+	passwds, err := htpasswd.ParseHtpasswdFile(svn.htpasswd(repoID))
+	if os.IsNotExist(err) {
+		passwds = htpasswd.HashedPasswords{}
+	} else if err != nil {
+		return err
+	}
+	conflicts, err := svn.updateUserPasswords(passwds, false)
+	if err != nil {
+		return err
+	}
+	for _, username := range conflicts {
+		logger.WithField("username", username).Warning("user already in the user directory with another password, keeping that one")
+	}
+
+	usernames := []string{}
+	for username := range passwds {
+		usernames = append(usernames, username)
+	}
+	if err := svn.writeMembers(repoID, usernames); err != nil {
+		return err
+	}
+	logger.WithFields(log.Fields{
+		"user_count":     len(usernames),
+		"conflict_count": len(conflicts),
+	}).Info("users moved into the user directory")
+	return nil
+}
+
+// modifyMembers applies the changes in access to the member list of the repository, and
+// stores the passwords of new users in the user directory.
+func (svn *SVNMan) modifyMembers(repoID string, mods ModifyAccess, logger *log.Entry) error {
+	if err := svn.migrateUsers(repoID, logger); err != nil {
+		logger.WithError(err).Error("unable to move users into the user directory")
+		return err
+	}
+	usernames, err := svn.readMembers(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to read member list")
+		return err
+	}
+	passwds, err := svn.readUserPasswords()
+	if err != nil {
+		logger.WithError(err).Error("unable to read user directory")
+		return err
+	}
+
+	members := map[string]bool{}
+	for _, username := range usernames {
+		members[username] = true
+	}
+	// Other repositories may rely on existing passwords, so those can only be changed
+	// with SetPassword.
+	newPasswords := map[string]string{}
+	for _, grant := range mods.Grant {
+		_, known := passwds[grant.Username]
+		switch {
+		case known && grant.Password != "" && grant.Password != passwds[grant.Username]:
+			logger.WithField("username", grant.Username).Warning("refusing to change password of existing user")
+			return ErrPasswordConflict
+		case !known && grant.Password != "":
+			newPasswords[grant.Username] = grant.Password
+		case !known:
+			logger.WithField("username", grant.Username).Warning("no password given for unknown user")
+			return ErrNoPassword
+		}
+		members[grant.Username] = true
+	}
+	for _, revoke := range mods.Revoke {
+		delete(members, revoke)
+	}
+
+	if len(newPasswords) > 0 {
+		if _, err := svn.updateUserPasswords(newPasswords, false); err != nil {
+			logger.WithError(err).Error("unable to save passwords in user directory")
+			return err
+		}
+	}
+	usernames = []string{}
+	for username := range members {
+		usernames = append(usernames, username)
+	}
+	if err := svn.writeMembers(repoID, usernames); err != nil {
+		logger.WithError(err).Error("unable to save member list")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package svnman
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/foomo/htpasswd"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) loadUserHtpasswd(t *check.C) htpasswd.HashedPasswords {
+	passwds, err := htpasswd.ParseHtpasswdFile(s.svn.userHtpasswd())
+	if err != nil {
+		t.Fatalf("unable to parse %s: %s", s.svn.userHtpasswd(), err)
+	}
+	return passwds
+}
+
+func (s *SVNManTestSuite) readMembersFile(t *check.C, repoID string) string {
+	contents, err := ioutil.ReadFile(s.svn.membersPath(repoID))
+	if err != nil {
+		t.Fatalf("unable to read member list: %s", err)
+	}
+	return string(contents)
+}
+
+func (s *SVNManTestSuite) TestUserDirectory(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.svn.userDir = mustTempDir("", "users")
+	defer os.RemoveAll(s.svn.userDir)
+	s.createTestRepo(t, "1234")
+	s.createTestRepo(t, "5678")
+
+	err := s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{
+			{Username: "alice", Password: "$2y$05$alice"},
+			{Username: "bob", Password: "$2y$05$bob"},
+		},
+	}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, "1234: alice bob\n", s.readMembersFile(t, "1234"))
+
+	// The other repository can use the passwords without knowing them.
+	err = s.svn.ModifyAccess("5678", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "bob"}},
+	}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, "5678: bob\n", s.readMembersFile(t, "5678"))
+	assert.Equal(t, htpasswd.HashedPasswords{"alice": "$2y$05$alice", "bob": "$2y$05$bob"},
+		s.loadUserHtpasswd(t))
+
+	usernames, err := s.svn.GetUsernames("5678")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bob"}, usernames)
+
+	err = s.svn.ModifyAccess("5678", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "carol"}},
+	}, logFields)
+	assert.Equal(t, ErrNoPassword, err)
+
+	// Setting a password once affects all repositories.
+	repoIDs, err := s.svn.SetPassword("bob", SetPassword{"$2y$05$bob-new"}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1234", "5678"}, repoIDs)
+	assert.Equal(t, "$2y$05$bob-new", s.loadUserHtpasswd(t)["bob"])
+
+	// Revoking access doesn't remove the user from the user directory.
+	err = s.svn.ModifyAccess("1234", ModifyAccess{Revoke: []string{"alice"}}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, "1234: bob\n", s.readMembersFile(t, "1234"))
+	assert.Equal(t, "$2y$05$alice", s.loadUserHtpasswd(t)["alice"])
+
+	apa := s.readApacheConfig(t, "1234")
+	assert.Contains(t, apa, "    AuthUserFile "+s.svn.userHtpasswd()+"\n")
+	assert.Contains(t, apa, "    AuthGroupFile "+s.svn.membersPath("1234")+"\n")
+	assert.Contains(t, apa, "    Require group 1234\n")
+	assert.NotContains(t, apa, "valid-user")
+}
+
+func (s *SVNManTestSuite) TestUserDirectoryGrantKeepsPassword(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.svn.userDir = mustTempDir("", "users")
+	defer os.RemoveAll(s.svn.userDir)
+	s.createTestRepo(t, "1234")
+	s.createTestRepo(t, "5678")
+
+	assert.Nil(t, s.svn.ModifyAccess("5678", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "bob", Password: "$2y$05$bob"}},
+	}, logFields))
+
+	// Granting access to another repository must not change the password bob uses for 5678.
+	err := s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "bob", Password: "$2y$05$evil"}},
+	}, logFields)
+	assert.Equal(t, ErrPasswordConflict, err)
+	usernames, err := s.svn.GetUsernames("1234")
+	assert.Nil(t, err)
+	assert.Empty(t, usernames)
+	assert.Equal(t, htpasswd.HashedPasswords{"bob": "$2y$05$bob"}, s.loadUserHtpasswd(t))
+
+	// Giving the password bob already has is fine.
+	assert.Nil(t, s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "bob", Password: "$2y$05$bob"}},
+	}, logFields))
+	assert.Equal(t, "1234: bob\n", s.readMembersFile(t, "1234"))
+}
+
+func (s *SVNManTestSuite) TestUserDirectoryMigration(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+	s.createTestRepo(t, "5678")
+	assert.Nil(t, s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{
+			{Username: "alice", Password: "$2y$05$alice-1234"},
+			{Username: "bob", Password: "$2y$05$bob"},
+		},
+	}, logFields))
+	assert.Nil(t, s.svn.ModifyAccess("5678", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$alice-5678"}},
+	}, logFields))
+
+	s.svn.userDir = mustTempDir("", "users")
+	defer os.RemoveAll(s.svn.userDir)
+
+	// Until migrated, the users are taken from the repository's htpasswd file.
+	usernames, err := s.svn.GetUsernames("5678")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice"}, usernames)
+
+	s.mb = mockBackend{}
+	assert.Nil(t, s.svn.WriteMissingConfigs(logFields))
+	assert.True(t, s.mb.reloadCalled, "backend reload not requested")
+
+	assert.Equal(t, "1234: alice bob\n", s.readMembersFile(t, "1234"))
+	assert.Equal(t, "5678: alice\n", s.readMembersFile(t, "5678"))
+	assert.Contains(t, s.readApacheConfig(t, "5678"), "    Require group 5678\n")
+
+	// The first password found wins.
+	assert.Equal(t, htpasswd.HashedPasswords{"alice": "$2y$05$alice-1234", "bob": "$2y$05$bob"},
+		s.loadUserHtpasswd(t))
+
+	// Migrated repositories are left alone.
+	s.mb = mockBackend{}
+	assert.Nil(t, s.svn.WriteMissingConfigs(logFields))
+	assert.False(t, s.mb.reloadCalled, "backend reload should not be requested")
+}
+
+func (s *SVNManTestSuite) TestSetPasswordInHtpasswdFiles(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	for _, repoID := range []string{"1234", "5678", "9abc"} {
+		s.createTestRepo(t, repoID)
+		assert.Nil(t, s.svn.ModifyAccess(repoID, ModifyAccess{
+			Grant: []ModifyAccessGrantEntry{{Username: "bob", Password: "$2y$05$bob-" + repoID}},
+		}, logFields))
+	}
+	for _, repoID := range []string{"1234", "9abc"} {
+		assert.Nil(t, s.svn.ModifyAccess(repoID, ModifyAccess{
+			Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$alice-" + repoID}},
+		}, logFields))
+	}
+
+	repoIDs, err := s.svn.SetPassword("alice", SetPassword{"$2y$05$alice-new"}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1234", "9abc"}, repoIDs)
+
+	assert.Equal(t, []string{"alice:$2y$05$alice-new", "bob:$2y$05$bob-1234"}, s.loadHtpasswd(t, "1234"))
+	assert.Equal(t, []string{"bob:$2y$05$bob-5678"}, s.loadHtpasswd(t, "5678"))
+	assert.Equal(t, []string{"alice:$2y$05$alice-new", "bob:$2y$05$bob-9abc"}, s.loadHtpasswd(t, "9abc"))
+
+	repoIDs, err = s.svn.SetPassword("nobody", SetPassword{"$2y$05$nobody"}, logFields)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, repoIDs)
+}
+
+func (s *SVNManTestSuite) TestSetPasswordInHtpasswdFilesFailure(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	for _, repoID := range []string{"1234", "5678"} {
+		s.createTestRepo(t, repoID)
+		assert.Nil(t, s.svn.ModifyAccess(repoID, ModifyAccess{
+			Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$alice-" + repoID}},
+		}, logFields))
+	}
+
+	// An unreadable htpasswd file means none of them are changed.
+	unreadable := s.svn.htpasswd("5678")
+	if err := os.Rename(unreadable, unreadable+".moved"); err != nil {
+		t.Fatalf("unable to move htpasswd: %s", err)
+	}
+	if err := os.Mkdir(unreadable, 0750); err != nil {
+		t.Fatalf("unable to create directory: %s", err)
+	}
+
+	_, err := s.svn.SetPassword("alice", SetPassword{"$2y$05$alice-new"}, logFields)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"alice:$2y$05$alice-1234"}, s.loadHtpasswd(t, "1234"))
+	_, err = os.Stat(s.svn.htpasswd("1234") + ".new")
+	assert.True(t, os.IsNotExist(err), "temporary file should have been removed")
+}
+
+func (s *SVNManTestSuite) TestModifyAccessNoPassword(t *check.C) {
+	logFields := log.Fields{"in": "unittest"}
+	s.createTestRepo(t, "1234")
+
+	err := s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Access: AccessRead}},
+	}, logFields)
+	assert.Equal(t, ErrNoPassword, err)
+
+	// Existing users keep their password.
+	assert.Nil(t, s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Password: "$2y$05$alice"}},
+	}, logFields))
+	assert.Nil(t, s.svn.ModifyAccess("1234", ModifyAccess{
+		Grant: []ModifyAccessGrantEntry{{Username: "alice", Access: AccessRead}},
+	}, logFields))
+	assert.Equal(t, []string{"alice:$2y$05$alice"}, s.loadHtpasswd(t, "1234"))
+}
+
+func (s *SVNManTestSuite) TestUpdateUserPasswordsConflicts(t *check.C) {
+	s.svn.userDir = mustTempDir("", "users")
+	defer os.RemoveAll(s.svn.userDir)
+
+	conflicts, err := s.svn.updateUserPasswords(map[string]string{"alice": "$2y$05$alice", "bob": "$2y$05$bob"}, false)
+	assert.Nil(t, err)
+	assert.Empty(t, conflicts)
+
+	conflicts, err = s.svn.updateUserPasswords(map[string]string{
+		"alice": "$2y$05$alice",
+		"bob":   "$2y$05$bob-other",
+		"carol": "$2y$05$carol",
+	}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bob"}, conflicts)
+	assert.Equal(t, htpasswd.HashedPasswords{
+		"alice": "$2y$05$alice",
+		"bob":   "$2y$05$bob",
+		"carol": "$2y$05$carol",
+	}, s.loadUserHtpasswd(t))
+}
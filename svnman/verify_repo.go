@@ -0,0 +1,183 @@
+package svnman
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Stored in repoinfo after the repository has been verified.
+type verifyinfo struct {
+	VerifiedOn   time.Time       `yaml:"verified_on"`
+	Success      bool            `yaml:"success"`
+	HeadRevision int             `yaml:"head_revision"`
+	Failures     []verifyfailure `yaml:"failures,omitempty"`
+	Errors       []string        `yaml:"errors,omitempty"`
+}
+
+type verifyfailure struct {
+	Revision int      `yaml:"revision"`
+	Errors   []string `yaml:"errors"`
+}
+
+func (vi *verifyinfo) result() VerifyResult {
+	result := VerifyResult{
+		VerifiedOn:   vi.VerifiedOn,
+		Success:      vi.Success,
+		HeadRevision: vi.HeadRevision,
+		Errors:       vi.Errors,
+	}
+	for _, failure := range vi.Failures {
+		result.Failures = append(result.Failures, VerifyFailure{failure.Revision, failure.Errors})
+	}
+	return result
+}
+
+func verifyinfoFromResult(result VerifyResult) *verifyinfo {
+	vi := &verifyinfo{
+		VerifiedOn:   result.VerifiedOn,
+		Success:      result.Success,
+		HeadRevision: result.HeadRevision,
+		Errors:       result.Errors,
+	}
+	for _, failure := range result.Failures {
+		vi.Failures = append(vi.Failures, verifyfailure{failure.Revision, failure.Errors})
+	}
+	return vi
+}
+
+var (
+	verifiedRevisionRegexp = regexp.MustCompile(`^\* Verified revision (\d+)\.`)
+	verifyErrorRegexp      = regexp.MustCompile(`^\* Error verifying revision (\d+)\.`)
+)
+
+// VerifyProgressFunc is called by VerifyRepo for every verified revision.
+type VerifyProgressFunc func(revision, headRevision int)
+
+// VerifyRepo checks the integrity of the repository with 'svnadmin verify'. Corruption is
+// reported in the result and recorded in the repository info; an error is only returned
+// when the verification itself could not be performed.
+func (svn *SVNMan) VerifyRepo(ctx context.Context, repoID string, progress VerifyProgressFunc,
+	logFields log.Fields) (VerifyResult, error) {
+	repodir := svn.repoPath(repoID)
+	logger := log.WithFields(logFields).WithField("repo_id", repoID)
+
+	if _, err := os.Stat(repodir); os.IsNotExist(err) {
+		logger.Warning("nonexistent repository requested")
+		return VerifyResult{}, ErrNotFound
+	}
+	head, err := svn.headRevision(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to determine HEAD revision")
+		return VerifyResult{}, err
+	}
+
+	// svnadmin reports verification progress on stderr, and we want to see everything in order.
+	cmd := exec.CommandContext(ctx, "svnadmin", "verify", "--keep-going", "--revision", "0:"+strconv.Itoa(head), repodir)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	cmd.Stderr = cmd.Stdout
+
+	logger.WithField("head_revision", head).Info("verifying repository")
+	if err := cmd.Start(); err != nil {
+		logger.WithError(err).Error("unable to run svnadmin verify")
+		return VerifyResult{}, err
+	}
+	result := parseSvnadminVerify(output, head, progress)
+	io.Copy(ioutil.Discard, output)
+	err = cmd.Wait()
+
+	if ctx.Err() != nil {
+		logger.WithError(ctx.Err()).Warning("repository verification cancelled")
+		return VerifyResult{}, ctx.Err()
+	}
+	result.VerifiedOn = time.Now().UTC()
+	result.HeadRevision = head
+	result.Success = err == nil && len(result.Failures) == 0 && len(result.Errors) == 0
+	if err != nil && len(result.Failures) == 0 && len(result.Errors) == 0 {
+		// Make sure the failure is visible, even when svnadmin didn't say anything useful.
+		result.Errors = []string{err.Error()}
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"success":         result.Success,
+		"failed_revision": len(result.Failures),
+	})
+	if result.Success {
+		logger.Info("repository verified")
+	} else {
+		logger.WithField("errors", result.Errors).Error("repository failed verification")
+	}
+
+	// Re-read the info, as it may have changed while verifying.
+	defer svn.infoLocks.lock(repoID).Unlock()
+	info, err := svn.readRepoInfo(repoID)
+	if err != nil {
+		logger.WithError(err).Error("unable to read repository info")
+		return result, err
+	}
+	info.Verify = verifyinfoFromResult(result)
+	if err := svn.writeRepoInfo(info); err != nil {
+		logger.WithError(err).Error("unable to save repository info")
+		return result, err
+	}
+	return result, nil
+}
+
+// parseSvnadminVerify parses the combined stdout and stderr of 'svnadmin verify --keep-going'.
+// Errors are attributed to the revision that was reported to fail last.
+func parseSvnadminVerify(output io.Reader, head int, progress VerifyProgressFunc) VerifyResult {
+	result := VerifyResult{}
+	var failure *VerifyFailure
+
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := verifiedRevisionRegexp.FindStringSubmatch(line); match != nil {
+			failure = nil
+			if revision, err := strconv.Atoi(match[1]); err == nil && progress != nil {
+				progress(revision, head)
+			}
+			continue
+		}
+		if match := verifyErrorRegexp.FindStringSubmatch(line); match != nil {
+			revision, _ := strconv.Atoi(match[1])
+			result.Failures = append(result.Failures, VerifyFailure{Revision: revision, Errors: []string{}})
+			failure = &result.Failures[len(result.Failures)-1]
+			continue
+		}
+		if strings.HasPrefix(line, "-----Summary") {
+			// The summary repeats the errors we've already seen.
+			failure = nil
+			for scanner.Scan() {
+				if strings.HasPrefix(scanner.Text(), "svnadmin: ") {
+					line = scanner.Text()
+					break
+				}
+			}
+		}
+		if !strings.HasPrefix(line, "svnadmin: ") {
+			continue
+		}
+
+		message := strings.TrimPrefix(line, "svnadmin: ")
+		if failure != nil {
+			failure.Errors = append(failure.Errors, message)
+		} else {
+			result.Errors = append(result.Errors, message)
+		}
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package svnman
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+func (s *SVNManTestSuite) TestVerifyRepoHappy(t *check.C) {
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(testDump),
+		nil, log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+
+	revisions := []int{}
+	progress := func(revision, head int) {
+		assert.Equal(t, 2, head)
+		revisions = append(revisions, revision)
+	}
+	result, err := s.svn.VerifyRepo(context.Background(), "my-repo-id", progress, log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 2, result.HeadRevision)
+	assert.Empty(t, result.Failures)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []int{0, 1, 2}, revisions)
+
+	details, err := s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	if assert.NotNil(t, details.LastVerification) {
+		assert.True(t, details.LastVerification.Success)
+		assert.True(t, result.VerifiedOn.Equal(details.LastVerification.VerifiedOn))
+	}
+	// The rest of the info should have been kept.
+	assert.Equal(t, "59eefa9cf488554678cae036", details.ProjectID)
+}
+
+func (s *SVNManTestSuite) TestVerifyRepoCorrupt(t *check.C) {
+	err := s.svn.LoadRepo(context.Background(), testLoadRepo, strings.NewReader(testDump),
+		nil, log.Fields{"in": "unittest"})
+	assert.Nil(t, err)
+	// Our fake svnadmin reports this revision as corrupt.
+	err = ioutil.WriteFile(filepath.Join(s.svn.repoPath("my-repo-id"), "CORRUPT"), []byte("1"), 0644)
+	assert.Nil(t, err)
+
+	result, err := s.svn.VerifyRepo(context.Background(), "my-repo-id", nil, log.Fields{"in": "unittest"})
+	assert.Nil(t, err, "corruption should be reported in the result")
+	assert.False(t, result.Success)
+	if assert.Len(t, result.Failures, 1) {
+		assert.Equal(t, 1, result.Failures[0].Revision)
+		assert.Equal(t, []string{"E160004: Corrupt node-revision '0.0.r1/17'"}, result.Failures[0].Errors)
+	}
+	if assert.Len(t, result.Errors, 1) {
+		assert.Contains(t, result.Errors[0], "failed to verify")
+	}
+
+	details, err := s.svn.GetRepoDetails("my-repo-id")
+	assert.Nil(t, err)
+	if assert.NotNil(t, details.LastVerification) {
+		assert.False(t, details.LastVerification.Success)
+		assert.Equal(t, result.Failures, details.LastVerification.Failures)
+	}
+}
+
+func (s *SVNManTestSuite) TestVerifyRepoNotFound(t *check.C) {
+	_, err := s.svn.VerifyRepo(context.Background(), "my-repo-id", nil, log.Fields{})
+	assert.Equal(t, ErrNotFound, err)
+}
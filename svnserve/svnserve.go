@@ -0,0 +1,344 @@
+// Package svnserve serves the repositories via svnserve, so that they can be accessed on
+// svn:// URLs.
+package svnserve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armadillica/svn-manager/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// How long to wait before restarting svnserve after it exited unexpectedly.
+const respawnDelay = 5 * time.Second
+
+const configTemplate = `# Generated by SVN Manager; do not edit.
+[general]
+anon-access = none
+auth-access = write
+authz-db = %s
+realm = %s
+
+[sasl]
+use-sasl = true
+`
+
+// Permissions in authz files, ordered from least to most access.
+var permissions = []string{"", "r", "rw"}
+
+// The most permissive permission per access level.
+var maxPermissions = map[string]int{
+	backend.AccessReadWrite: 2,
+	backend.AccessReadOnly:  1,
+	backend.AccessNone:      0,
+}
+
+// Svnserve is the backend that serves the repositories via a single svnserve daemon.
+// Every repository has its own authz file, which are combined into one authz file for
+// the daemon.
+type Svnserve struct {
+	*backend.DelayedReloader
+
+	configDir string
+	repoRoot  string
+	listen    string // host:port
+	realm     string // authentication realm, also used by SASL to find the users.
+	validate  func(authzFile string) (string, error)
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+var _ backend.Backend = (*Svnserve)(nil)
+
+func svnauthzValidate(authzFile string) (string, error) {
+	deadline := time.Now().Add(10 * time.Second)
+	ctx, cancelFunc := context.WithDeadline(context.Background(), deadline)
+	defer cancelFunc()
+
+	cmd := exec.CommandContext(ctx, "svnauthz", "validate", authzFile)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// checkSASLConfig checks that the SASL configuration of svnserve can authenticate the users
+// managed by SVN Manager. Their passwords are only known as bcrypt hashes, which SASL's own
+// password database cannot use, so the passwords have to be checked by saslauthd. That can
+// check them against the htpasswd file in the user directory, for example via PAM with
+// pam_pwdfile. saslauthd only supports the PLAIN mechanism.
+func checkSASLConfig(filename string) error {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	options := map[string][]string{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid line in %s: %q", filename, line)
+		}
+		options[strings.TrimSpace(parts[0])] = strings.Fields(parts[1])
+	}
+
+	contains := func(values []string, wanted string) bool {
+		for _, value := range values {
+			if strings.EqualFold(value, wanted) {
+				return true
+			}
+		}
+		return false
+	}
+	if !contains(options["pwcheck_method"], "saslauthd") {
+		return fmt.Errorf("%s does not set pwcheck_method to saslauthd, so the bcrypted passwords cannot be checked", filename)
+	}
+	if mechs, ok := options["mech_list"]; ok && !contains(mechs, "PLAIN") {
+		return fmt.Errorf("%s does not allow the PLAIN mechanism, which saslauthd needs", filename)
+	}
+	return nil
+}
+
+// Create creates a new svnserve backend, and writes the svnserve configuration. Call Go()
+// to start the daemon. The combined authz file is validated and replaced reloadDelay after
+// an authz file in configDir changed. saslConfig is the SASL configuration file for
+// svnserve, which has to be able to authenticate the users in the user directory.
+func Create(reloadDelay time.Duration, configDir, repoRoot, listen, realm, saslConfig string) *Svnserve {
+	for _, executable := range []string{"svnserve", "svnauthz"} {
+		if _, err := exec.LookPath(executable); err != nil {
+			log.WithError(err).Fatalf("unable to find %s", executable)
+		}
+	}
+	if _, _, err := net.SplitHostPort(listen); err != nil {
+		log.WithField("listen", listen).WithError(err).Fatal("invalid svnserve listen address")
+	}
+	if realm == "" || strings.ContainsAny(realm, "\r\n") {
+		log.WithField("realm", realm).Fatal("invalid svnserve realm")
+	}
+	if err := checkSASLConfig(saslConfig); err != nil {
+		log.WithField("sasl_config", saslConfig).WithError(err).Fatal("svnserve cannot authenticate the users")
+	}
+
+	ss := newSvnserve(reloadDelay, configDir, repoRoot, listen, realm, svnauthzValidate)
+	if err := ss.writeConfig(); err != nil {
+		log.WithField("config_dir", configDir).WithError(err).Fatal("unable to write svnserve configuration")
+	}
+	if output, err := ss.testAuthz(); err != nil {
+		log.WithField("output", strings.TrimSpace(output)).WithError(err).Fatal("svnserve authz configuration is invalid")
+	}
+	if _, err := ss.installAuthz(); err != nil {
+		log.WithField("config_dir", configDir).WithError(err).Fatal("unable to install svnserve authz file")
+	}
+	return ss
+}
+
+func newSvnserve(reloadDelay time.Duration, configDir, repoRoot, listen, realm string,
+	validate func(authzFile string) (string, error)) *Svnserve {
+	ss := &Svnserve{
+		configDir: configDir,
+		repoRoot:  repoRoot,
+		listen:    listen,
+		realm:     realm,
+		validate:  validate,
+		done:      make(chan struct{}),
+	}
+	ss.DelayedReloader = backend.CreateDelayedReloader("svnserve", reloadDelay, configDir,
+		ss.testAuthz, ss.installAuthz)
+	return ss
+}
+
+func (ss *Svnserve) configFile() string {
+	return filepath.Join(ss.configDir, "svnserve.conf")
+}
+
+func (ss *Svnserve) authzFile() string {
+	return filepath.Join(ss.configDir, "authz")
+}
+
+func (ss *Svnserve) candidateAuthzFile() string {
+	return ss.authzFile() + ".new"
+}
+
+// ConfigFilename returns the filename of the repository's authz file.
+func (ss *Svnserve) ConfigFilename(repoID string) string {
+	return fmt.Sprintf("svn-%s.authz", repoID)
+}
+
+// RenderConfig returns the authz rules for the repository. svnserve uses the name of the
+// repository directory, which is the repository ID, to find them. Groups cannot be used, as
+// the [groups] section is shared by all repositories, so the expanded rules are used instead.
+func (ss *Svnserve) RenderConfig(repo backend.Repo) ([]byte, error) {
+	maxPerm, ok := maxPermissions[repo.Access]
+	if !ok {
+		return nil, fmt.Errorf("unknown access level %q", repo.Access)
+	}
+
+	var authz bytes.Buffer
+	fmt.Fprintf(&authz, "# Access rules for project %q\n", repo.ProjectID)
+	for idx, rule := range repo.AuthzRules {
+		if idx > 0 {
+			authz.WriteString("\n")
+		}
+		fmt.Fprintf(&authz, "[%s:%s]\n", repo.RepoID, rule.Path)
+		if rule.Path == "/" {
+			// Users of other repositories can authenticate too.
+			authz.WriteString("* =\n")
+		}
+
+		usernames := make([]string, 0, len(rule.Access))
+		for username := range rule.Access {
+			usernames = append(usernames, username)
+		}
+		sort.Strings(usernames)
+		for _, username := range usernames {
+			level := 0
+			for idx, perm := range permissions {
+				if perm == rule.Access[username] {
+					level = idx
+				}
+			}
+			if level > maxPerm {
+				level = maxPerm
+			}
+			fmt.Fprintf(&authz, "%s = %s\n", username, permissions[level])
+		}
+	}
+	return authz.Bytes(), nil
+}
+
+// writeConfig writes the svnserve.conf file shared by all repositories.
+func (ss *Svnserve) writeConfig() error {
+	if err := os.MkdirAll(ss.configDir, 0750); err != nil {
+		return err
+	}
+	conf := fmt.Sprintf(configTemplate, ss.authzFile(), ss.realm)
+	return ioutil.WriteFile(ss.configFile(), []byte(conf), 0640)
+}
+
+// testAuthz combines the authz files of all repositories into the candidate authz file,
+// and validates it.
+func (ss *Svnserve) testAuthz() (string, error) {
+	// Rejected and deleted authz files live one directory deeper, so they are not matched.
+	found, err := filepath.Glob(filepath.Join(ss.configDir, "*", ss.ConfigFilename("*")))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(found)
+
+	var authz bytes.Buffer
+	authz.WriteString("# Generated by SVN Manager from the per-repository authz files; do not edit.\n")
+	for _, filename := range found {
+		contents, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		authz.WriteString("\n")
+		authz.Write(contents)
+	}
+
+	candidate := ss.candidateAuthzFile()
+	if err := ioutil.WriteFile(candidate, authz.Bytes(), 0640); err != nil {
+		return "unable to write " + candidate, err
+	}
+	return ss.validate(candidate)
+}
+
+// installAuthz replaces the authz file with the candidate written by testAuthz.
+// svnserve reads the authz file for every connection, so it doesn't need to be restarted.
+func (ss *Svnserve) installAuthz() (string, error) {
+	if err := os.Rename(ss.candidateAuthzFile(), ss.authzFile()); err != nil {
+		return "unable to install " + ss.authzFile(), err
+	}
+	return "", nil
+}
+
+// Go starts the svnserve daemon, and restarts it whenever it exits.
+func (ss *Svnserve) Go() {
+	ss.wg.Add(1)
+	go func() {
+		defer ss.wg.Done()
+		for {
+			err := ss.run()
+			select {
+			case <-ss.done:
+				log.Debug("svnserve stopped")
+				return
+			default:
+			}
+			log.WithError(err).WithField("respawn_delay", respawnDelay).Error("svnserve exited unexpectedly")
+
+			select {
+			case <-ss.done:
+				return
+			case <-time.After(respawnDelay):
+			}
+		}
+	}()
+}
+
+// Close stops the svnserve daemon.
+func (ss *Svnserve) Close() {
+	close(ss.done)
+	ss.wg.Wait()
+}
+
+// run runs svnserve until it exits, or until Close() is called.
+func (ss *Svnserve) run() error {
+	host, port, err := net.SplitHostPort(ss.listen)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"--daemon", "--foreground",
+		"--root", ss.repoRoot,
+		"--config-file", ss.configFile(),
+		"--listen-port", port,
+	}
+	if host != "" {
+		args = append(args, "--listen-host", host)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "svnserve", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	log.WithField("listen", ss.listen).Info("starting svnserve")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err = <-exited:
+	case <-ss.done:
+		cancel()
+		err = <-exited
+	}
+	if err == nil {
+		return errors.New("svnserve exited")
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%s: %s", err, msg)
+	}
+	return err
+}
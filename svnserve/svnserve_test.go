@@ -0,0 +1,172 @@
+package svnserve
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armadillica/svn-manager/backend"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type SvnserveTestSuite struct {
+	configDir string
+	validated []string // contents of the validated authz files.
+	ss        *Svnserve
+}
+
+var _ = check.Suite(&SvnserveTestSuite{})
+
+func (s *SvnserveTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.configDir, err = ioutil.TempDir("", "svnserve")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	s.validated = []string{}
+	s.ss = newSvnserve(time.Hour, s.configDir, "/svn", ":3690", "Test SVN", s.fakeValidate)
+}
+
+func (s *SvnserveTestSuite) TearDownTest(c *check.C) {
+	os.RemoveAll(s.configDir)
+}
+
+// fakeValidate refuses the authz file when it contains "BROKEN".
+func (s *SvnserveTestSuite) fakeValidate(authzFile string) (string, error) {
+	contents, err := ioutil.ReadFile(authzFile)
+	if err != nil {
+		return "", err
+	}
+	s.validated = append(s.validated, string(contents))
+	if strings.Contains(string(contents), "BROKEN") {
+		return "svnauthz: E220003: Invalid authz configuration", errors.New("exit status 1")
+	}
+	return "", nil
+}
+
+func (s *SvnserveTestSuite) writeAuthz(c *check.C, repoID, contents string) string {
+	filename := filepath.Join(s.configDir, repoID[:2], s.ss.ConfigFilename(repoID))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		c.Fatalf("unable to create directory: %s", err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		c.Fatalf("unable to write %s: %s", filename, err)
+	}
+	return filename
+}
+
+func (s *SvnserveTestSuite) TestRenderConfig(c *check.C) {
+	repo := backend.Repo{
+		RepoID:    "1234",
+		ProjectID: "59eefa9cf488554678cae036",
+		Usernames: []string{"alice", "bob"},
+		Access:    backend.AccessReadWrite,
+		AuthzRules: []backend.AuthzRule{
+			{Path: "/", Access: map[string]string{"bob": "rw", "alice": "rw"}},
+			{Path: "/trunk/release", Access: map[string]string{"alice": "rw", "bob": "r"}},
+		},
+	}
+	authz, err := s.ss.RenderConfig(repo)
+	assert.Nil(c, err)
+	assert.Equal(c, `# Access rules for project "59eefa9cf488554678cae036"
+[1234:/]
+* =
+alice = rw
+bob = rw
+
+[1234:/trunk/release]
+alice = rw
+bob = r
+`, string(authz))
+
+	repo.Access = backend.AccessReadOnly
+	authz, err = s.ss.RenderConfig(repo)
+	assert.Nil(c, err)
+	assert.Contains(c, string(authz), "[1234:/]\n* =\nalice = r\nbob = r\n")
+	assert.Contains(c, string(authz), "[1234:/trunk/release]\nalice = r\nbob = r\n")
+
+	repo.Access = backend.AccessNone
+	authz, err = s.ss.RenderConfig(repo)
+	assert.Nil(c, err)
+	assert.NotContains(c, string(authz), "= r")
+
+	repo.Access = "write-only"
+	_, err = s.ss.RenderConfig(repo)
+	assert.NotNil(c, err)
+}
+
+func (s *SvnserveTestSuite) TestWriteConfig(c *check.C) {
+	assert.Nil(c, s.ss.writeConfig())
+	conf, err := ioutil.ReadFile(filepath.Join(s.configDir, "svnserve.conf"))
+	assert.Nil(c, err)
+	assert.Contains(c, string(conf), "anon-access = none\n")
+	assert.Contains(c, string(conf), "authz-db = "+filepath.Join(s.configDir, "authz")+"\n")
+	assert.Contains(c, string(conf), "use-sasl = true\n")
+	assert.Contains(c, string(conf), "realm = Test SVN\n")
+}
+
+func (s *SvnserveTestSuite) TestReloadCombinesAuthz(c *check.C) {
+	s.ss.QueueReload("1234", s.writeAuthz(c, "1234", "[1234:/]\nalice = rw\n"))
+	s.ss.QueueReload("5678", s.writeAuthz(c, "5678", "[5678:/]\nbob = r\n"))
+	s.ss.Flush()
+
+	authz, err := ioutil.ReadFile(filepath.Join(s.configDir, "authz"))
+	assert.Nil(c, err)
+	assert.Contains(c, string(authz), "[1234:/]\nalice = rw\n")
+	assert.Contains(c, string(authz), "[5678:/]\nbob = r\n")
+	_, err = os.Stat(filepath.Join(s.configDir, "authz.new"))
+	assert.True(c, os.IsNotExist(err), "candidate authz file should have been installed")
+
+	status, _ := s.ss.ConfigStatus("1234")
+	assert.Equal(c, backend.StatusActive, status)
+}
+
+func (s *SvnserveTestSuite) TestReloadRollsBackBrokenAuthz(c *check.C) {
+	s.writeAuthz(c, "1234", "[1234:/]\nalice = rw\n")
+	s.ss.QueueReload("1234", "")
+	s.ss.Flush()
+
+	brokenFile := s.writeAuthz(c, "5678", "BROKEN")
+	s.ss.QueueReload("5678", brokenFile)
+	s.ss.Flush()
+
+	status, reason := s.ss.ConfigStatus("5678")
+	assert.Equal(c, backend.StatusFailed, status)
+	assert.Contains(c, reason, "Invalid authz configuration")
+	_, err := os.Stat(brokenFile)
+	assert.True(c, os.IsNotExist(err), "broken authz file should have been removed")
+
+	// The rejected file must not end up in the combined authz file.
+	authz, err := ioutil.ReadFile(filepath.Join(s.configDir, "authz"))
+	assert.Nil(c, err)
+	assert.Contains(c, string(authz), "[1234:/]\nalice = rw\n")
+	assert.NotContains(c, string(authz), "BROKEN")
+	status, _ = s.ss.ConfigStatus("1234")
+	assert.Equal(c, backend.StatusActive, status)
+}
+
+func (s *SvnserveTestSuite) TestCheckSASLConfig(c *check.C) {
+	filename := filepath.Join(s.configDir, "svn.conf")
+	checkContents := func(contents string) error {
+		if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+			c.Fatalf("unable to write %s: %s", filename, err)
+		}
+		return checkSASLConfig(filename)
+	}
+
+	assert.Nil(c, checkContents("# Checked against the user directory.\npwcheck_method: saslauthd\nmech_list: PLAIN\n"))
+	assert.Nil(c, checkContents("pwcheck_method: saslauthd\n"))
+
+	// SASL's own password database would need the plain-text passwords.
+	assert.NotNil(c, checkContents("pwcheck_method: auxprop\nauxprop_plugin: sasldb\n"))
+	assert.NotNil(c, checkContents("mech_list: PLAIN\n"))
+	assert.NotNil(c, checkContents("pwcheck_method: saslauthd\nmech_list: DIGEST-MD5 CRAM-MD5\n"))
+	assert.NotNil(c, checkContents("pwcheck_method saslauthd\n"))
+
+	os.Remove(filename)
+	assert.NotNil(c, checkSASLConfig(filename))
+}
@@ -0,0 +1,147 @@
+// Package verifier periodically verifies the integrity of all repositories, so that
+// corruption is caught before users notice.
+package verifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	log "github.com/sirupsen/logrus"
+)
+
+// JobType is the type of the jobs that verify a repository.
+const JobType = "verify"
+
+// JobFunc returns a job function that verifies the repository, and stores the
+// svnman.VerifyResult as the job result. Corruption does not make the job fail;
+// only being unable to verify does. The job ID is added to logFields.
+func JobFunc(svn svnman.Manager, repoID string, logFields log.Fields) jobs.Func {
+	return func(ctx context.Context, run *jobs.Run) error {
+		logFields["job_id"] = run.ID
+		progress := func(revision, headRevision int) {
+			run.Progress(int64(revision+1), int64(headRevision+1), "revisions")
+		}
+		result, err := svn.VerifyRepo(ctx, repoID, progress, logFields)
+		if err != nil {
+			return err
+		}
+		return run.SetResult(result)
+	}
+}
+
+// Verifier submits a verification job for one repository at a time, cycling through all of them.
+type Verifier struct {
+	svn      svnman.Manager
+	jobs     *jobs.Manager
+	interval time.Duration
+
+	cursor    string // ID of the last verified repository.
+	lastJobID string
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Create returns a Verifier that starts verifying the next repository every interval.
+// Call Go() to actually start it.
+func Create(svn svnman.Manager, jobManager *jobs.Manager, interval time.Duration) *Verifier {
+	log.WithField("interval", interval).Info("creating repository verifier")
+
+	return &Verifier{
+		svn:      svn,
+		jobs:     jobManager,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Go starts the verifier in a background goroutine.
+func (v *Verifier) Go() {
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+
+		ticker := time.NewTicker(v.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-v.done:
+				log.Info("repository verifier stopped")
+				return
+			case <-ticker.C:
+				v.verifyNext()
+			}
+		}
+	}()
+}
+
+// Close stops the verifier. Verification jobs that were already submitted are
+// handled by the job manager.
+func (v *Verifier) Close() {
+	log.Info("shutting down repository verifier")
+	close(v.done)
+	v.wg.Wait()
+}
+
+// nextRepoID returns the ID of the repository after the cursor, wrapping around to the first
+// repository at the end. Returns an empty string when there are no repositories.
+func (v *Verifier) nextRepoID() (string, error) {
+	list, err := v.svn.ListRepos(svnman.ListRepos{Cursor: v.cursor, Limit: 1})
+	if err != nil {
+		return "", err
+	}
+	if len(list.Repos) == 0 && v.cursor != "" {
+		list, err = v.svn.ListRepos(svnman.ListRepos{Limit: 1})
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(list.Repos) == 0 {
+		return "", nil
+	}
+	return list.Repos[0].RepoID, nil
+}
+
+// verifyNext submits a verification job for the next repository. Nothing is submitted
+// while the previous job is still unfinished, so that slow verifications don't pile up.
+func (v *Verifier) verifyNext() {
+	if v.lastJobID != "" {
+		job, err := v.jobs.Get(v.lastJobID)
+		if err == nil && !job.Status.Finished() {
+			log.WithFields(log.Fields{
+				"job_id":  v.lastJobID,
+				"repo_id": job.RepoID,
+			}).Debug("repository verifier still waiting for previous verification")
+			return
+		}
+	}
+
+	repoID, err := v.nextRepoID()
+	if err != nil {
+		log.WithError(err).Error("repository verifier unable to list repositories")
+		return
+	}
+	if repoID == "" {
+		log.Debug("repository verifier found no repositories")
+		return
+	}
+
+	logFields := log.Fields{
+		"repo_id": repoID,
+		"in":      "verifier",
+	}
+	logger := log.WithFields(logFields)
+
+	job, err := v.jobs.Submit(JobType, repoID, "", JobFunc(v.svn, repoID, logFields))
+	if err != nil {
+		logger.WithError(err).Warning("repository verifier unable to submit job")
+		return
+	}
+	logger.WithField("job_id", job.ID).Debug("repository verifier submitted job")
+	v.cursor = repoID
+	v.lastJobID = job.ID
+}
@@ -0,0 +1,143 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/armadillica/svn-manager/jobs"
+	"github.com/armadillica/svn-manager/svnman"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	check "gopkg.in/check.v1"
+)
+
+type VerifierTestSuite struct {
+	jobsDir  string
+	jobs     *jobs.Manager
+	mockCtrl *gomock.Controller
+	mockSVN  *svnman.MockManager
+	verifier *Verifier
+}
+
+var _ = check.Suite(&VerifierTestSuite{})
+
+func (s *VerifierTestSuite) SetUpTest(c *check.C) {
+	var err error
+	s.jobsDir, err = ioutil.TempDir("", "verifier-jobs")
+	if err != nil {
+		c.Fatalf("unable to create temporary directory: %s", err)
+	}
+	s.jobs, err = jobs.Create(s.jobsDir, 1)
+	if err != nil {
+		c.Fatalf("unable to create job manager: %s", err)
+	}
+	s.mockCtrl = gomock.NewController(c)
+	s.mockSVN = svnman.NewMockManager(s.mockCtrl)
+	s.verifier = Create(s.mockSVN, s.jobs, time.Hour)
+}
+
+func (s *VerifierTestSuite) TearDownTest(c *check.C) {
+	s.jobs.Close()
+	s.mockCtrl.Finish()
+	os.RemoveAll(s.jobsDir)
+}
+
+// waitForLastJob waits until the last submitted job has finished, and returns its final state.
+func (s *VerifierTestSuite) waitForLastJob(c *check.C) jobs.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := s.jobs.Get(s.verifier.lastJobID)
+		if err != nil {
+			c.Fatalf("unable to get job %s: %s", s.verifier.lastJobID, err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("job %s did not finish in time", s.verifier.lastJobID)
+	return jobs.Job{}
+}
+
+func (s *VerifierTestSuite) expectList(cursor string, repoIDs ...string) {
+	list := svnman.RepoList{}
+	for _, repoID := range repoIDs {
+		list.Repos = append(list.Repos, svnman.RepoSummary{RepoID: repoID})
+	}
+	s.mockSVN.EXPECT().ListRepos(svnman.ListRepos{Cursor: cursor, Limit: 1}).Times(1).Return(list, nil)
+}
+
+func (s *VerifierTestSuite) expectVerify(repoID string, result svnman.VerifyResult) {
+	s.mockSVN.EXPECT().
+		VerifyRepo(gomock.Any(), repoID, gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, repoID string, progress svnman.VerifyProgressFunc,
+			logFields log.Fields) (svnman.VerifyResult, error) {
+			progress(0, 1)
+			progress(1, 1)
+			return result, nil
+		})
+}
+
+func (s *VerifierTestSuite) TestCycleThroughRepos(c *check.C) {
+	s.expectList("", "repo-a")
+	s.expectVerify("repo-a", svnman.VerifyResult{Success: true, HeadRevision: 1})
+	s.verifier.verifyNext()
+	job := s.waitForLastJob(c)
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+	assert.Equal(c, "repo-a", job.RepoID)
+	assert.Equal(c, JobType, job.Type)
+	assert.Equal(c, jobs.Progress{Done: 2, Total: 2, Unit: "revisions"}, job.Progress)
+
+	// Corruption is reported in the result, and doesn't fail the job.
+	s.expectList("repo-a", "repo-b")
+	failures := []svnman.VerifyFailure{{Revision: 1, Errors: []string{"E160004: Corrupt node-revision"}}}
+	s.expectVerify("repo-b", svnman.VerifyResult{HeadRevision: 1, Failures: failures})
+	s.verifier.verifyNext()
+	job = s.waitForLastJob(c)
+	assert.Equal(c, jobs.StatusCompleted, job.Status)
+	result := svnman.VerifyResult{}
+	assert.Nil(c, json.Unmarshal(job.Result, &result))
+	assert.False(c, result.Success)
+	assert.Equal(c, failures, result.Failures)
+
+	// At the end of the list, we should start at the beginning again.
+	s.expectList("repo-b")
+	s.expectList("", "repo-a")
+	s.expectVerify("repo-a", svnman.VerifyResult{Success: true, HeadRevision: 1})
+	s.verifier.verifyNext()
+	job = s.waitForLastJob(c)
+	assert.Equal(c, "repo-a", job.RepoID)
+}
+
+func (s *VerifierTestSuite) TestNoRepos(c *check.C) {
+	s.expectList("")
+	s.verifier.verifyNext()
+	assert.Equal(c, "", s.verifier.lastJobID)
+}
+
+func (s *VerifierTestSuite) TestWaitForPreviousJob(c *check.C) {
+	block := make(chan struct{})
+	s.expectList("", "repo-a")
+	s.mockSVN.EXPECT().
+		VerifyRepo(gomock.Any(), "repo-a", gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, repoID string, progress svnman.VerifyProgressFunc,
+			logFields log.Fields) (svnman.VerifyResult, error) {
+			<-block
+			return svnman.VerifyResult{Success: true}, nil
+		})
+	s.verifier.verifyNext()
+	firstJobID := s.verifier.lastJobID
+
+	// Should not list or submit anything while the first job is unfinished.
+	s.verifier.verifyNext()
+	assert.Equal(c, firstJobID, s.verifier.lastJobID)
+
+	close(block)
+	s.waitForLastJob(c)
+}